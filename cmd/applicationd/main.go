@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/apns"
 	"github.com/danikarik/okpock/pkg/env"
 	"github.com/danikarik/okpock/pkg/filestore"
 	"github.com/danikarik/okpock/pkg/filestore/awsstore"
 	"github.com/danikarik/okpock/pkg/mail"
 	"github.com/danikarik/okpock/pkg/mail/awsmail"
+	"github.com/danikarik/okpock/pkg/outbox"
 	"github.com/danikarik/okpock/pkg/pkpass"
 	"github.com/danikarik/okpock/pkg/service"
 	"github.com/danikarik/okpock/pkg/store/sequel"
@@ -24,6 +26,11 @@ import (
 var Version string
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verifyCommand(os.Args[2:])
+		return
+	}
+
 	var (
 		ctx = context.Background()
 		err error
@@ -89,37 +96,97 @@ func main() {
 		}
 	}
 
-	var couponCert *filestore.Object
+	var certs = make(map[api.PassType]*filestore.Object)
 	{
-		couponCert, err = s3.GetFile(ctx, cfg.Certificates.Bucket, cfg.Certificates.Coupon.Path)
-		if err != nil {
-			errorExit("get coupon certificate: %v", err)
+		if missing := cfg.Certificates.Missing(); len(missing) > 0 {
+			errorExit("missing certificates: %v", missing)
+		}
+
+		for _, passType := range api.PassTypes() {
+			certs[passType], err = s3.GetFile(ctx, cfg.Certificates.Bucket, cfg.Certificates.Certificate(passType).Path)
+			if err != nil {
+				errorExit("get %s certificate: %v", passType, err)
+			}
 		}
 	}
 
-	var couponSigner pkpass.Signer
+	var signers = pkpass.NewRegistry()
 	{
-		couponSigner, err = pkpass.NewSigner(rootCert.Body, couponCert.Body, cfg.Certificates.Coupon.Pass)
+		for _, passType := range api.PassTypes() {
+			signer, err := pkpass.NewSigner(rootCert.Body, certs[passType].Body, cfg.Certificates.Certificate(passType).Pass)
+			if err != nil {
+				errorExit("%s signer: %v", passType, err)
+			}
+			signers.Set(cfg.PassTypeID(passType), signer)
+		}
+	}
+
+	var verifier pkpass.Verifier
+	{
+		verifier, err = pkpass.NewVerifier(rootCert.Body)
 		if err != nil {
-			errorExit("coupon signer: %v", err)
+			errorExit("verifier: %v", err)
 		}
 	}
 
-	var couponNotificator apns.Notificator
+	var authKey *filestore.Object
 	{
-		couponNotificator, err = apns.New(couponCert.Body, cfg.Certificates.Coupon.Pass, cfg.IsProduction())
+		err = cfg.APNS.IsValid()
 		if err != nil {
-			errorExit("new notificator: %v", err)
+			errorExit("apns config: %v", err)
 		}
+
+		if cfg.APNS.UseToken() {
+			authKey, err = s3.GetFile(ctx, cfg.Certificates.Bucket, cfg.APNS.Key)
+			if err != nil {
+				errorExit("get apns auth key: %v", err)
+			}
+		}
+	}
+
+	var notificators = apns.NewRegistry()
+	{
+		for _, passType := range api.PassTypes() {
+			var (
+				passTypeID  = cfg.PassTypeID(passType)
+				notificator apns.Notificator
+			)
+
+			if cfg.APNS.UseToken() {
+				notificator, err = apns.NewWithToken(authKey.Body, cfg.APNS.KeyID, cfg.Certificates.Team, passTypeID, cfg.IsProduction())
+			} else {
+				notificator, err = apns.New(certs[passType].Body, cfg.Certificates.Certificate(passType).Pass, passTypeID, cfg.IsProduction())
+			}
+			if err != nil {
+				errorExit("%s notificator: %v", passType, err)
+			}
+
+			notificators.Set(passTypeID, notificator)
+		}
+	}
+
+	var db = sequel.New(conn)
+
+	var dispatcher *outbox.Dispatcher
+	{
+		dispatcher = outbox.New(db, notificators, logger, outbox.Options{
+			Workers:     cfg.APNS.Workers,
+			MaxAttempts: cfg.APNS.MaxAttempts,
+			Rate:        cfg.APNS.PushRate,
+		})
 	}
+	go dispatcher.Run(ctx)
 
 	var srv *service.Service
 	{
-		db := sequel.New(conn)
-		env := env.New(cfg, db, db, db, s3, mailer, couponSigner, couponNotificator)
+		env := env.New(cfg, db, db, db, s3, mailer, signers, verifier, notificators)
 
 		srv = service.New(Version, env, logger)
 	}
+	go srv.RunJobs(ctx)
+	go srv.RunPurger(ctx)
+	go srv.RunScheduler(ctx)
+	go srv.RunExpirySweeper(ctx)
 
 	logger.Info("server", zap.String("http_address", cfg.Addr()))
 	errorExit("server: %v", http.ListenAndServe(cfg.Addr(), srv))
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/danikarik/okpock/pkg/pkpass"
+)
+
+// verifyCommand inspects local .pkpass file and prints verification report.
+func verifyCommand(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	rootPath := flags.String("root", "", "path to Apple WWDR certificate in PEM format")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: applicationd verify [-root wwdr.pem] file%s\n", pkpass.Extension)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var root []byte
+	if *rootPath != "" {
+		data, err := ioutil.ReadFile(*rootPath)
+		if err != nil {
+			errorExit("read root certificate: %v", err)
+		}
+		root = data
+	}
+
+	verifier, err := pkpass.NewVerifier(root)
+	if err != nil {
+		errorExit("verifier: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		errorExit("read pass: %v", err)
+	}
+
+	report, err := verifier.Verify(data)
+	if err != nil {
+		errorExit("verify: %v", err)
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		errorExit("report: %v", err)
+	}
+	fmt.Println(string(out))
+
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
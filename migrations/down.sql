@@ -2,6 +2,8 @@ DROP TABLE IF EXISTS `passes`;
 
 DROP TABLE IF EXISTS `registrations`;
 
+DROP TABLE IF EXISTS `devices`;
+
 DROP TABLE IF EXISTS `logs`;
 
 DROP TABLE IF EXISTS `users`;
@@ -17,3 +19,13 @@ DROP TABLE IF EXISTS `uploads`;
 DROP TABLE IF EXISTS `project_pass_cards`;
 
 DROP TABLE IF EXISTS `pass_cards`;
+
+DROP TABLE IF EXISTS `pushes`;
+
+DROP TABLE IF EXISTS `jobs`;
+
+DROP TABLE IF EXISTS `job_results`;
+
+DROP TABLE IF EXISTS `pass_card_versions`;
+
+DROP TABLE IF EXISTS `scheduled_updates`;
@@ -8,14 +8,24 @@ CREATE TABLE IF NOT EXISTS `passes` (
     UNIQUE KEY `passes_serial_number_unique_idx` (`serial_number`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
-CREATE TABLE IF NOT EXISTS `registrations` (
+CREATE TABLE IF NOT EXISTS `devices` (
     `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
     `device_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
     `push_token` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `devices_device_id_unique_idx` (`device_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `registrations` (
+    `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
+    `device_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
     `serial_number` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
     `pass_type_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW(),
     PRIMARY KEY (`id`),
-    UNIQUE KEY `registrations_serial_number_unique_idx` (`serial_number`)
+    UNIQUE KEY `registrations_device_and_serial_number_unique_idx` (`device_id`, `serial_number`),
+    KEY `registrations_serial_number_idx` (`serial_number`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 CREATE TABLE IF NOT EXISTS `logs` (
@@ -75,6 +85,12 @@ CREATE TABLE IF NOT EXISTS `projects` (
     `strip_image` VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "",
     `strip_image_2x` VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "",
     `strip_image_3x` VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "",
+    `thumbnail_image` VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "",
+    `thumbnail_image_2x` VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "",
+    `thumbnail_image_3x` VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "",
+    `localizations` TEXT DEFAULT NULL,
+    `template` TEXT DEFAULT NULL,
+    `expire_after_days` INT(10) unsigned NOT NULL DEFAULT 0,
     `created_at` TIMESTAMP NULL DEFAULT NOW(),
     `updated_at` TIMESTAMP NULL DEFAULT NOW(),
     PRIMARY KEY (`id`),
@@ -117,7 +133,230 @@ CREATE TABLE IF NOT EXISTS `project_pass_cards` (
 CREATE TABLE IF NOT EXISTS `pass_cards` (
     `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
     `raw_data` TEXT DEFAULT NULL,
+    `localizations` TEXT DEFAULT NULL,
+    `version` INT(10) unsigned NOT NULL DEFAULT 1,
     `created_at` TIMESTAMP NULL DEFAULT NOW(),
     `updated_at` TIMESTAMP NULL DEFAULT NOW(),
-    PRIMARY KEY (`id`)
+    `archived_at` TIMESTAMP NULL DEFAULT NULL,
+    `expires_at` TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `pass_cards_archived_at_idx` (`archived_at`),
+    KEY `pass_cards_expires_at_idx` (`expires_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `pushes` (
+    `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
+    `serial_number` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `pass_type_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `push_token` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `status` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "pending",
+    `attempts` INT(10) unsigned NOT NULL DEFAULT 0,
+    `last_error` TEXT NOT NULL,
+    `next_attempt_at` TIMESTAMP NULL DEFAULT NOW(),
+    `created_at` TIMESTAMP NULL DEFAULT NOW(),
+    `updated_at` TIMESTAMP NULL DEFAULT NOW(),
+    PRIMARY KEY (`id`),
+    KEY `pushes_serial_number_idx` (`serial_number`),
+    KEY `pushes_status_and_next_attempt_at_idx` (`status`, `next_attempt_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `jobs` (
+    `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
+    `project_id` INT(10) unsigned NOT NULL,
+    `kind` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `params` TEXT DEFAULT NULL,
+    `cursor` BIGINT NOT NULL DEFAULT 0,
+    `status` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "pending",
+    `total` INT(10) unsigned NOT NULL DEFAULT 0,
+    `processed` INT(10) unsigned NOT NULL DEFAULT 0,
+    `failed` INT(10) unsigned NOT NULL DEFAULT 0,
+    `last_error` TEXT NOT NULL,
+    `attempts` INT(10) unsigned NOT NULL DEFAULT 0,
+    `lease_until` TIMESTAMP NULL DEFAULT NULL,
+    `user_id` INT(10) unsigned NOT NULL DEFAULT 0,
+    `request_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "",
+    `created_at` TIMESTAMP NULL DEFAULT NOW(),
+    `updated_at` TIMESTAMP NULL DEFAULT NOW(),
+    `finished_at` TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `jobs_project_id_idx` (`project_id`),
+    KEY `jobs_status_and_lease_until_idx` (`status`, `lease_until`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `job_results` (
+    `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
+    `job_id` INT(10) unsigned NOT NULL,
+    `row` INT(10) unsigned NOT NULL,
+    `pass_card_id` INT(10) unsigned NOT NULL DEFAULT 0,
+    `serial_number` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "",
+    `error` TEXT NOT NULL,
+    `created_at` TIMESTAMP NULL DEFAULT NOW(),
+    PRIMARY KEY (`id`),
+    KEY `job_results_job_id_idx` (`job_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `pass_card_versions` (
+    `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
+    `pass_card_id` INT(10) unsigned NOT NULL,
+    `version` INT(10) unsigned NOT NULL,
+    `raw_data` TEXT DEFAULT NULL,
+    `user_id` INT(10) unsigned NOT NULL DEFAULT 0,
+    `request_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "",
+    `created_at` TIMESTAMP NULL DEFAULT NOW(),
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `pass_card_versions_pass_card_and_version_unique_idx` (`pass_card_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `scheduled_updates` (
+    `id` INT(10) unsigned NOT NULL AUTO_INCREMENT,
+    `project_id` INT(10) unsigned NOT NULL,
+    `pass_card_id` INT(10) unsigned NOT NULL DEFAULT 0,
+    `filter` TEXT DEFAULT NULL,
+    `patch` TEXT NOT NULL,
+    `status` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "pending",
+    `effective_at` TIMESTAMP NULL DEFAULT NULL,
+    `job_id` INT(10) unsigned NOT NULL DEFAULT 0,
+    `last_error` TEXT NOT NULL,
+    `claimed_at` TIMESTAMP NULL DEFAULT NULL,
+    `user_id` INT(10) unsigned NOT NULL DEFAULT 0,
+    `request_id` VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "",
+    `created_at` TIMESTAMP NULL DEFAULT NOW(),
+    `updated_at` TIMESTAMP NULL DEFAULT NOW(),
+    PRIMARY KEY (`id`),
+    KEY `scheduled_updates_project_id_idx` (`project_id`),
+    KEY `scheduled_updates_status_and_effective_at_idx` (`status`, `effective_at`),
+    KEY `scheduled_updates_status_and_claimed_at_idx` (`status`, `claimed_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Upgrade of databases created before columns and indexes above were added.
+-- Every statement is skipped when it is applied already, so file can be run
+-- again safely.
+
+DROP PROCEDURE IF EXISTS `okpock_add_column`;
+DROP PROCEDURE IF EXISTS `okpock_add_index`;
+DROP PROCEDURE IF EXISTS `okpock_drop_index`;
+DROP PROCEDURE IF EXISTS `okpock_move_push_tokens`;
+
+DELIMITER //
+
+CREATE PROCEDURE `okpock_add_column`(IN tbl VARCHAR(64), IN col VARCHAR(64), IN def TEXT)
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.COLUMNS
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND COLUMN_NAME = col
+    ) THEN
+        SET @ddl = CONCAT('ALTER TABLE `', tbl, '` ADD COLUMN `', col, '` ', def);
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+CREATE PROCEDURE `okpock_add_index`(IN tbl VARCHAR(64), IN idx VARCHAR(64), IN def TEXT)
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.STATISTICS
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND INDEX_NAME = idx
+    ) THEN
+        SET @ddl = CONCAT('ALTER TABLE `', tbl, '` ADD ', def);
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+CREATE PROCEDURE `okpock_drop_index`(IN tbl VARCHAR(64), IN idx VARCHAR(64))
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.STATISTICS
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND INDEX_NAME = idx
+    ) THEN
+        SET @ddl = CONCAT('ALTER TABLE `', tbl, '` DROP INDEX `', idx, '`');
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+-- Push tokens were kept in registrations before devices table was added.
+CREATE PROCEDURE `okpock_move_push_tokens`()
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.COLUMNS
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'registrations' AND COLUMN_NAME = 'push_token'
+    ) THEN
+        INSERT IGNORE INTO `devices` (`device_id`, `push_token`)
+            SELECT `device_id`, `push_token` FROM `registrations`;
+        ALTER TABLE `registrations` DROP COLUMN `push_token`;
+    END IF;
+END //
+
+DELIMITER ;
+
+CALL okpock_move_push_tokens();
+CALL okpock_drop_index('registrations', 'registrations_serial_number_unique_idx');
+CALL okpock_add_index('registrations', 'registrations_device_and_serial_number_unique_idx', 'UNIQUE KEY `registrations_device_and_serial_number_unique_idx` (`device_id`, `serial_number`)');
+CALL okpock_add_index('registrations', 'registrations_serial_number_idx', 'KEY `registrations_serial_number_idx` (`serial_number`)');
+CALL okpock_add_column('registrations', 'updated_at', 'TIMESTAMP NOT NULL DEFAULT NOW() AFTER `pass_type_id`');
+
+CALL okpock_add_column('projects', 'thumbnail_image', 'VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "" AFTER `strip_image_3x`');
+CALL okpock_add_column('projects', 'thumbnail_image_2x', 'VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "" AFTER `thumbnail_image`');
+CALL okpock_add_column('projects', 'thumbnail_image_3x', 'VARCHAR(191) COLLATE utf8mb4_unicode_ci DEFAULT "" AFTER `thumbnail_image_2x`');
+CALL okpock_add_column('projects', 'localizations', 'TEXT DEFAULT NULL AFTER `thumbnail_image_3x`');
+CALL okpock_add_column('projects', 'template', 'TEXT DEFAULT NULL AFTER `localizations`');
+CALL okpock_add_column('projects', 'expire_after_days', 'INT(10) unsigned NOT NULL DEFAULT 0 AFTER `template`');
+
+CALL okpock_add_column('pass_cards', 'localizations', 'TEXT DEFAULT NULL AFTER `raw_data`');
+CALL okpock_add_column('pass_cards', 'version', 'INT(10) unsigned NOT NULL DEFAULT 1 AFTER `localizations`');
+CALL okpock_add_column('pass_cards', 'archived_at', 'TIMESTAMP NULL DEFAULT NULL AFTER `updated_at`');
+CALL okpock_add_column('pass_cards', 'expires_at', 'TIMESTAMP NULL DEFAULT NULL AFTER `archived_at`');
+CALL okpock_add_index('pass_cards', 'pass_cards_archived_at_idx', 'KEY `pass_cards_archived_at_idx` (`archived_at`)');
+CALL okpock_add_index('pass_cards', 'pass_cards_expires_at_idx', 'KEY `pass_cards_expires_at_idx` (`expires_at`)');
+
+CALL okpock_add_column('jobs', 'params', 'TEXT DEFAULT NULL AFTER `kind`');
+CALL okpock_add_column('jobs', 'cursor', 'BIGINT NOT NULL DEFAULT 0 AFTER `params`');
+CALL okpock_add_column('jobs', 'attempts', 'INT(10) unsigned NOT NULL DEFAULT 0 AFTER `last_error`');
+CALL okpock_add_column('jobs', 'lease_until', 'TIMESTAMP NULL DEFAULT NULL AFTER `attempts`');
+CALL okpock_add_column('jobs', 'user_id', 'INT(10) unsigned NOT NULL DEFAULT 0 AFTER `lease_until`');
+CALL okpock_add_column('jobs', 'request_id', 'VARCHAR(191) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT "" AFTER `user_id`');
+CALL okpock_add_index('jobs', 'jobs_status_and_lease_until_idx', 'KEY `jobs_status_and_lease_until_idx` (`status`, `lease_until`)');
+
+-- Jobs started before worker was added have no params to be resumed with.
+UPDATE `jobs`
+SET `status` = 'failed', `last_error` = 'job: interrupted by restart', `finished_at` = NOW()
+WHERE `status` IN ('pending', 'running') AND `params` IS NULL AND `user_id` = 0;
+
+CALL okpock_add_column('scheduled_updates', 'claimed_at', 'TIMESTAMP NULL DEFAULT NULL AFTER `last_error`');
+CALL okpock_add_index('scheduled_updates', 'scheduled_updates_status_and_claimed_at_idx', 'KEY `scheduled_updates_status_and_claimed_at_idx` (`status`, `claimed_at`)');
+
+-- Updates claimed before claims expired are reclaimed by scheduler.
+UPDATE `scheduled_updates`
+SET `claimed_at` = `updated_at`
+WHERE `status` = 'running' AND `claimed_at` IS NULL;
+
+-- Backfill of derived columns. `expirationDate` is W3C date with either `Z`
+-- or numeric offset, timestamps are written in UTC.
+SET time_zone = '+00:00';
+
+UPDATE `pass_cards`
+SET `expires_at` = CONVERT_TZ(
+    STR_TO_DATE(LEFT(`raw_data`->>'$.expirationDate', 19), '%Y-%m-%dT%H:%i:%s'),
+    IF(RIGHT(`raw_data`->>'$.expirationDate', 1) = 'Z', '+00:00', RIGHT(`raw_data`->>'$.expirationDate', 6)),
+    '+00:00'
+)
+WHERE `expires_at` IS NULL
+    AND `raw_data`->>'$.expirationDate' REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})$';
+
+-- Pass cards created before history was kept get their current version
+-- as the first entry of history.
+INSERT INTO `pass_card_versions` (`pass_card_id`, `version`, `raw_data`, `user_id`, `request_id`, `created_at`)
+SELECT pc.`id`, pc.`version`, pc.`raw_data`, 0, '', pc.`updated_at`
+FROM `pass_cards` pc
+WHERE NOT EXISTS (
+    SELECT 1 FROM `pass_card_versions` pcv WHERE pcv.`pass_card_id` = pc.`id`
+);
+
+DROP PROCEDURE IF EXISTS `okpock_add_column`;
+DROP PROCEDURE IF EXISTS `okpock_add_index`;
+DROP PROCEDURE IF EXISTS `okpock_drop_index`;
+DROP PROCEDURE IF EXISTS `okpock_move_push_tokens`;
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobStatus is an alias for background job status.
+type JobStatus string
+
+const (
+	// JobPending waits for worker.
+	JobPending = JobStatus("pending")
+	// JobRunning is being processed.
+	JobRunning = JobStatus("running")
+	// JobFinished is processed. Failures of single items are counted in `Failed`.
+	JobFinished = JobStatus("finished")
+	// JobFailed is given up because of unrecoverable error.
+	JobFailed = JobStatus("failed")
+)
+
+// JobKind is an alias for background job type.
+type JobKind string
+
+const (
+	// PublishJob rebuilds and pushes every pass card of project.
+	PublishJob = JobKind("publish")
+	// RenderJob applies project template to every pass card of project.
+	RenderJob = JobKind("render")
+	// IssueJob creates pass cards from uploaded rows.
+	IssueJob = JobKind("issue")
+	// PatchJob applies patch to pass cards matching filter.
+	PatchJob = JobKind("patch")
+	// ArchiveJob voids and archives pass cards matching filter.
+	ArchiveJob = JobKind("archive")
+)
+
+// NewJob returns a new instance of `Job`.
+func NewJob(kind JobKind, projectID int64, author Author) *Job {
+	now := time.Now()
+	return &Job{
+		ProjectID: projectID,
+		Kind:      kind,
+		Status:    JobPending,
+		UserID:    author.UserID,
+		RequestID: author.RequestID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Job holds progress of background project operation. Job is processed by
+// worker holding lease on it. `Cursor` points to the next item, so job of
+// worker whose lease has expired is resumed by another one.
+type Job struct {
+	ID        int64   `json:"id" db:"id"`
+	ProjectID int64   `json:"projectId" db:"project_id"`
+	Kind      JobKind `json:"kind" db:"kind"`
+	Params    RawJSON `json:"-" db:"params"`
+	Cursor    int64   `json:"-" db:"cursor"`
+
+	Status    JobStatus `json:"status" db:"status"`
+	Total     int       `json:"total" db:"total"`
+	Processed int       `json:"processed" db:"processed"`
+	Failed    int       `json:"failed" db:"failed"`
+	LastError string    `json:"lastError,omitempty" db:"last_error"`
+
+	Attempts   int        `json:"attempts" db:"attempts"`
+	LeaseUntil *time.Time `json:"-" db:"lease_until"`
+
+	UserID    int64  `json:"userId,omitempty" db:"user_id"`
+	RequestID string `json:"requestId,omitempty" db:"request_id"`
+
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+}
+
+// Done reports whether job is no longer processed.
+func (j *Job) Done() bool {
+	return j.Status == JobFinished || j.Status == JobFailed
+}
+
+// Author returns author of job.
+func (j *Job) Author() Author {
+	return Author{UserID: j.UserID, RequestID: j.RequestID}
+}
+
+// Finish marks job with final status.
+func (j *Job) Finish(status JobStatus) {
+	now := time.Now()
+	j.Status = status
+	j.FinishedAt = &now
+}
+
+// String returns string representation of struct.
+func (j *Job) String() string {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Jobs holds next page token and items.
+type Jobs struct {
+	Opts *PagingOptions
+	Data []*Job
+}
+
+// NewJobResult returns a new instance of `JobResult`.
+func NewJobResult(row int) *JobResult {
+	return &JobResult{
+		Row:       row,
+		CreatedAt: time.Now(),
+	}
+}
+
+// JobResult holds outcome of single job item.
+type JobResult struct {
+	ID    int64 `json:"id" db:"id"`
+	JobID int64 `json:"jobId" db:"job_id"`
+	Row   int   `json:"row" db:"row"`
+
+	PassCardID   int64  `json:"passCardId,omitempty" db:"pass_card_id"`
+	SerialNumber string `json:"serialNumber,omitempty" db:"serial_number"`
+	Error        string `json:"error,omitempty" db:"error"`
+	URL          string `json:"url,omitempty" db:"-"`
+
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Succeeded reports whether item is processed without error.
+func (r *JobResult) Succeeded() bool { return r.Error == "" }
+
+// JobResults holds next page token and items.
+type JobResults struct {
+	Opts *PagingOptions
+	Data []*JobResult
+}
@@ -38,3 +38,43 @@ func (j JSONMap) Scan(src interface{}) error {
 	}
 	return json.Unmarshal(source, &j)
 }
+
+// RawJSON is an alias for raw json value kept as is.
+type RawJSON []byte
+
+// MarshalJSON returns raw value, `null` if empty.
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON keeps copy of raw value.
+func (j *RawJSON) UnmarshalJSON(data []byte) error {
+	if j == nil {
+		return errors.New("RawJSON: UnmarshalJSON on nil pointer")
+	}
+	*j = append((*j)[0:0], data...)
+	return nil
+}
+
+// Value is a value that drivers must be able to handle.
+func (j RawJSON) Value() (driver.Value, error) {
+	return driver.Value(string(j)), nil
+}
+
+// Scan value from database.
+func (j *RawJSON) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		*j = RawJSON(v)
+	case []byte:
+		*j = append(RawJSON{}, v...)
+	case nil:
+		*j = nil
+	default:
+		return errors.New("invalid data type for RawJSON")
+	}
+	return nil
+}
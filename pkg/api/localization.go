@@ -0,0 +1,109 @@
+package api
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var languageRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+// Localization holds translated strings and images of a single language.
+type Localization struct {
+	// Strings maps field values and labels to translations.
+	Strings map[string]string `json:"strings,omitempty"`
+	// Images maps image file names like `logo@2x.png` to upload keys.
+	Images map[string]string `json:"images,omitempty"`
+}
+
+// Localizations holds translations keyed by language, e.g. `en` or `zh-Hans`.
+type Localizations map[string]*Localization
+
+// IsValid checks whether input is valid or not.
+func (l Localizations) IsValid() error {
+	for lang, loc := range l {
+		if !languageRegexp.MatchString(lang) {
+			return fmt.Errorf("localization: language %q is invalid", lang)
+		}
+		if loc == nil {
+			return fmt.Errorf("localization: %s is empty", lang)
+		}
+		for key := range loc.Strings {
+			if key == "" {
+				return fmt.Errorf("localization: %s has empty string key", lang)
+			}
+		}
+		for name, key := range loc.Images {
+			if name == "" || strings.Contains(name, "/") {
+				return fmt.Errorf("localization: %s has invalid image name %q", lang, name)
+			}
+			if key == "" {
+				return fmt.Errorf("localization: %s image %q is empty", lang, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Merge returns translations where values of other override receiver.
+func (l Localizations) Merge(other Localizations) Localizations {
+	merged := Localizations{}
+	for _, src := range []Localizations{l, other} {
+		for lang, loc := range src {
+			if loc == nil {
+				continue
+			}
+			dst, ok := merged[lang]
+			if !ok {
+				dst = &Localization{
+					Strings: map[string]string{},
+					Images:  map[string]string{},
+				}
+				merged[lang] = dst
+			}
+			for key, value := range loc.Strings {
+				dst.Strings[key] = value
+			}
+			for name, key := range loc.Images {
+				dst.Images[name] = key
+			}
+		}
+	}
+	return merged
+}
+
+// Value is a value that drivers must be able to handle.
+func (l Localizations) Value() (driver.Value, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return driver.Value(""), err
+	}
+	return driver.Value(string(data)), nil
+}
+
+// Scan value from database.
+func (l *Localizations) Scan(src interface{}) error {
+	var source []byte
+	switch v := src.(type) {
+	case nil:
+		source = []byte("")
+	case string:
+		source = []byte(v)
+	case []byte:
+		source = v
+	case sql.NullString:
+		source = []byte(v.String)
+	default:
+		return errors.New("invalid data type for Localizations")
+	}
+
+	if len(source) == 0 || string(source) == "null" {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(source, l)
+}
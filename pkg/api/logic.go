@@ -1,6 +1,9 @@
 package api
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ProjectStore implements project related methods.
 type ProjectStore interface {
@@ -33,6 +36,18 @@ type ProjectStore interface {
 
 	// SetStripImage ...
 	SetStripImage(ctx context.Context, size ImageSize, key string, project *Project) error
+
+	// SetThumbnailImage ...
+	SetThumbnailImage(ctx context.Context, size ImageSize, key string, project *Project) error
+
+	// SetProjectLocalizations ...
+	SetProjectLocalizations(ctx context.Context, localizations Localizations, project *Project) error
+
+	// SetProjectTemplate ...
+	SetProjectTemplate(ctx context.Context, template *Template, project *Project) error
+
+	// SetProjectExpiration ...
+	SetProjectExpiration(ctx context.Context, expireAfterDays int, project *Project) error
 }
 
 // UploadStore implements user upload related methods.
@@ -61,8 +76,71 @@ type PassCardStore interface {
 	LoadPassCards(ctx context.Context, project *Project, opts *PagingOptions) (*PassCardInfoList, error)
 	// LoadPassCardsByBarcodeMessage ...
 	LoadPassCardsByBarcodeMessage(ctx context.Context, project *Project, message string, opts *PagingOptions) (*PassCardInfoList, error)
-	// UpdatePassCard ...
+	// LoadPassCardsByGroupingIdentifier ...
+	LoadPassCardsByGroupingIdentifier(ctx context.Context, project *Project, groupingID string, opts *PagingOptions) (*PassCardInfoList, error)
+	// LoadPassCardsByUserInfo ...
+	LoadPassCardsByUserInfo(ctx context.Context, project *Project, key, value string, opts *PagingOptions) (*PassCardInfoList, error)
+	// UpdatePassCard stores new version of pass card. Returns
+	// `store.ErrVersionMismatch` when pass card has been changed since it
+	// was loaded and `store.ErrNotFound` when it is archived.
 	UpdatePassCard(ctx context.Context, data *PassCard, passcard *PassCardInfo) error
+	// SetPassCardLocalizations ...
+	SetPassCardLocalizations(ctx context.Context, localizations Localizations, passcard *PassCardInfo) error
+	// ArchivePassCard ...
+	ArchivePassCard(ctx context.Context, passcard *PassCardInfo) error
+	// LoadArchivedPassCards ...
+	LoadArchivedPassCards(ctx context.Context, before time.Time, opts *PagingOptions) (*PassCardInfoList, error)
+	// DeletePassCard ...
+	DeletePassCard(ctx context.Context, passcard *PassCardInfo) error
+	// LoadExpiredPassCards ...
+	LoadExpiredPassCards(ctx context.Context, before time.Time, opts *PagingOptions) (*PassCardInfoList, error)
+	// LoadPassCardProject ...
+	LoadPassCardProject(ctx context.Context, passcard *PassCardInfo) (*Project, error)
+	// LoadPassCardVersion ...
+	LoadPassCardVersion(ctx context.Context, passcard *PassCardInfo, version int64) (*PassCardVersion, error)
+	// LoadPassCardVersions ...
+	LoadPassCardVersions(ctx context.Context, passcard *PassCardInfo, opts *PagingOptions) (*PassCardVersions, error)
+}
+
+// JobStore implements background job related methods.
+type JobStore interface {
+	// SaveNewJob ...
+	SaveNewJob(ctx context.Context, project *Project, job *Job) error
+	// LoadJob ...
+	LoadJob(ctx context.Context, project *Project, id int64) (*Job, error)
+	// LoadJobs ...
+	LoadJobs(ctx context.Context, project *Project, opts *PagingOptions) (*Jobs, error)
+	// LoadPendingJobs returns pending jobs and running jobs whose lease
+	// has expired by given time.
+	LoadPendingJobs(ctx context.Context, now time.Time, limit uint64) ([]*Job, error)
+	// ClaimJob ...
+	ClaimJob(ctx context.Context, lease time.Duration, job *Job) (bool, error)
+	// UpdateJob stores progress of claimed job. Returns `store.ErrVersionMismatch`
+	// when job was claimed by another worker after lease expired.
+	UpdateJob(ctx context.Context, job *Job) error
+	// SaveJobResult ...
+	SaveJobResult(ctx context.Context, job *Job, result *JobResult) error
+	// LoadJobResults ...
+	LoadJobResults(ctx context.Context, job *Job, opts *PagingOptions) (*JobResults, error)
+}
+
+// ScheduleStore implements scheduled update related methods.
+type ScheduleStore interface {
+	// SaveNewScheduledUpdate ...
+	SaveNewScheduledUpdate(ctx context.Context, project *Project, update *ScheduledUpdate) error
+	// LoadScheduledUpdate ...
+	LoadScheduledUpdate(ctx context.Context, project *Project, id int64) (*ScheduledUpdate, error)
+	// LoadScheduledUpdates ...
+	LoadScheduledUpdates(ctx context.Context, project *Project, opts *PagingOptions) (*ScheduledUpdates, error)
+	// LoadDueScheduledUpdates returns pending updates effective by given
+	// time and running updates claimed more than lease ago.
+	LoadDueScheduledUpdates(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]*ScheduledUpdate, error)
+	// ClaimScheduledUpdate marks update as running and renews claim time
+	// unless it is claimed by another scheduler in the meantime.
+	ClaimScheduledUpdate(ctx context.Context, update *ScheduledUpdate) (bool, error)
+	// UpdateScheduledUpdate stores changes only if scheduled update is
+	// still in given status.
+	UpdateScheduledUpdate(ctx context.Context, update *ScheduledUpdate, status ScheduleStatus) (bool, error)
 }
 
 // Logic implements method for business logic.
@@ -70,4 +148,6 @@ type Logic interface {
 	ProjectStore
 	UploadStore
 	PassCardStore
+	JobStore
+	ScheduleStore
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/danikarik/okpock/pkg/secure"
@@ -95,7 +96,7 @@ const (
 	// PrimaryFieldsType refers to `PrimaryFields` fields.
 	PrimaryFieldsType = FieldType("primaryFields")
 	// SecondaryFieldsType refers to `SecondaryFields` fields.
-	SecondaryFieldsType = FieldType("primaryFields")
+	SecondaryFieldsType = FieldType("secondaryFields")
 )
 
 const w3cDate string = time.RFC3339
@@ -162,10 +163,26 @@ type PassStructure struct {
 	TransitType     string   `json:"transitType,omitempty"`
 }
 
+type fieldSection struct {
+	prefix string
+	fields []*Field
+}
+
+func (s *PassStructure) sections() []fieldSection {
+	return []fieldSection{
+		{"auxiliary fields", s.AuxiliaryFields},
+		{"back fields", s.BackFields},
+		{"header fields", s.HeaderFields},
+		{"primary fields", s.PrimaryFields},
+		{"secondary fields", s.SecondaryFields},
+	}
+}
+
 // NewPassCardInfo returns a new instance of `PassCardInfo`.
 func NewPassCardInfo(data *PassCard) *PassCardInfo {
 	return &PassCardInfo{
 		Data:      data,
+		Version:   1,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -173,15 +190,40 @@ func NewPassCardInfo(data *PassCard) *PassCardInfo {
 
 // PassCardInfo is a wrapper around `PassCard` with extra fields.
 type PassCardInfo struct {
-	ID        int64     `json:"id" db:"id"`
-	Data      *PassCard `json:"data" db:"raw_data"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	ID            int64         `json:"id" db:"id"`
+	Data          *PassCard     `json:"data" db:"raw_data"`
+	Localizations Localizations `json:"localizations,omitempty" db:"localizations"`
+	Version       int64         `json:"version" db:"version"`
+	CreatedAt     time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updatedAt" db:"updated_at"`
+	ArchivedAt    *time.Time    `json:"archivedAt,omitempty" db:"archived_at"`
+	ExpiresAt     *time.Time    `json:"expiresAt,omitempty" db:"expires_at"`
+}
+
+// IsArchived returns true if pass card is deleted by user.
+func (p *PassCardInfo) IsArchived() bool { return p.ArchivedAt != nil }
+
+// IsExpired returns true if pass card is not voided yet, but its
+// `expirationDate` or project expiration rule has passed by given time.
+func (p *PassCardInfo) IsExpired(project *Project, now time.Time) bool {
+	if p.IsArchived() || p.Data == nil || p.Data.Voided {
+		return false
+	}
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(now) {
+		return true
+	}
+	if project != nil && project.ExpireAfterDays > 0 {
+		return !p.CreatedAt.AddDate(0, 0, project.ExpireAfterDays).After(now)
+	}
+	return false
 }
 
 // IsValid checks whether input is valid or not.
 func (p *PassCardInfo) IsValid() error {
-	return p.Data.IsValid()
+	if err := p.Data.IsValid(); err != nil {
+		return err
+	}
+	return p.Localizations.IsValid()
 }
 
 // String returns string representation of struct.
@@ -396,6 +438,18 @@ func (p *PassCard) Scan(src interface{}) error {
 	return json.Unmarshal(source, &p)
 }
 
+// Expiration returns parsed `expirationDate`, nil if it is not set.
+func (p *PassCard) Expiration() *time.Time {
+	if p.ExpirationDate == "" {
+		return nil
+	}
+	t, err := time.Parse(w3cDate, p.ExpirationDate)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
 // CopyFrom copies required fields.
 func (p *PassCard) CopyFrom(src *PassCard) {
 	p.SerialNumber = src.SerialNumber
@@ -404,6 +458,25 @@ func (p *PassCard) CopyFrom(src *PassCard) {
 	p.AuthenticationToken = src.AuthenticationToken
 }
 
+// Style returns pass type and structure of the only style key set.
+func (p *PassCard) Style() (PassType, *PassStructure, error) {
+	if !hasOneStyle(p.BoardingPass, p.Coupon, p.EventTicket, p.Generic, p.StoreCard) {
+		return "", nil, errors.New("pass structure: only one style allowed")
+	}
+	switch {
+	case p.BoardingPass != nil:
+		return BoardingPass, p.BoardingPass, nil
+	case p.Coupon != nil:
+		return Coupon, p.Coupon, nil
+	case p.EventTicket != nil:
+		return EventTicket, p.EventTicket, nil
+	case p.Generic != nil:
+		return Generic, p.Generic, nil
+	default:
+		return StoreCard, p.StoreCard, nil
+	}
+}
+
 func hasOneStyle(styles ...*PassStructure) bool {
 	styleCnt := 0
 	for _, style := range styles {
@@ -416,29 +489,12 @@ func hasOneStyle(styles ...*PassStructure) bool {
 
 func hasValidFields(styles ...*PassStructure) error {
 	for _, style := range styles {
-		if style != nil {
-			for _, field := range style.AuxiliaryFields {
-				if err := validField("auxiliary fields", field); err != nil {
-					return err
-				}
-			}
-			for _, field := range style.BackFields {
-				if err := validField("back fields", field); err != nil {
-					return err
-				}
-			}
-			for _, field := range style.HeaderFields {
-				if err := validField("header fields", field); err != nil {
-					return err
-				}
-			}
-			for _, field := range style.PrimaryFields {
-				if err := validField("primary fields", field); err != nil {
-					return err
-				}
-			}
-			for _, field := range style.SecondaryFields {
-				if err := validField("secondary fields", field); err != nil {
+		if style == nil {
+			continue
+		}
+		for _, section := range style.sections() {
+			for _, field := range section.fields {
+				if err := validField(section.prefix, field); err != nil {
 					return err
 				}
 			}
@@ -456,3 +512,38 @@ func validField(prefix string, field *Field) error {
 	}
 	return nil
 }
+
+// CheckChangeMessages checks whether `changeMessage` of fields added or
+// changed since prev contains `%@`. Fields stored before with other messages
+// are left as is, prev is nil for new pass card.
+func (p *PassCard) CheckChangeMessages(prev *PassCard) error {
+	_, structure, err := p.Style()
+	if err != nil {
+		return err
+	}
+
+	messages := map[string]string{}
+	if prev != nil {
+		if _, prevStructure, err := prev.Style(); err == nil {
+			for _, section := range prevStructure.sections() {
+				for _, field := range section.fields {
+					messages[field.Key] = field.ChangeMessage
+				}
+			}
+		}
+	}
+
+	for _, section := range structure.sections() {
+		for _, field := range section.fields {
+			if field.ChangeMessage == "" || strings.Contains(field.ChangeMessage, "%@") {
+				continue
+			}
+			if prevMessage, ok := messages[field.Key]; ok && prevMessage == field.ChangeMessage {
+				continue
+			}
+			return fmt.Errorf("%s: change message must contain %%@", section.prefix)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+type authorContextKey struct{}
+
+// Author holds who made a change.
+type Author struct {
+	UserID    int64
+	RequestID string
+}
+
+// WithAuthor returns context holding author of following changes.
+func WithAuthor(ctx context.Context, author Author) context.Context {
+	return context.WithValue(ctx, authorContextKey{}, author)
+}
+
+// AuthorFromContext returns author of changes, zero value if not set.
+func AuthorFromContext(ctx context.Context) Author {
+	if ctx == nil {
+		return Author{}
+	}
+	if author, ok := ctx.Value(authorContextKey{}).(Author); ok {
+		return author
+	}
+	return Author{}
+}
+
+// NewPassCardVersion returns a new instance of `PassCardVersion`.
+func NewPassCardVersion(passcard *PassCardInfo, author Author) *PassCardVersion {
+	return &PassCardVersion{
+		PassCardID: passcard.ID,
+		Version:    passcard.Version,
+		Data:       passcard.Data,
+		UserID:     author.UserID,
+		RequestID:  author.RequestID,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// PassCardVersion holds pass card data as it was after change.
+type PassCardVersion struct {
+	ID         int64     `json:"id" db:"id"`
+	PassCardID int64     `json:"passCardId" db:"pass_card_id"`
+	Version    int64     `json:"version" db:"version"`
+	Data       *PassCard `json:"data" db:"raw_data"`
+	UserID     int64     `json:"userId,omitempty" db:"user_id"`
+	RequestID  string    `json:"requestId,omitempty" db:"request_id"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+}
+
+// PassCardVersions holds next page token and items.
+type PassCardVersions struct {
+	Opts *PagingOptions
+	Data []*PassCardVersion
+}
@@ -13,6 +13,10 @@ type PassKit interface {
 	// UpdatePass ...
 	UpdatePass(ctx context.Context, serialNumber string) error
 
+	// UpdatePassWithPushes bumps pass update time and stores pending push
+	// for every device registered for pass in one transaction.
+	UpdatePassWithPushes(ctx context.Context, serialNumber, passTypeID string) error
+
 	// FindPass ...
 	FindPass(ctx context.Context, serialNumber, authToken, passTypeID string) (bool, error)
 
@@ -22,8 +26,11 @@ type PassKit interface {
 	// FindRegistrationBySerialNumber ...
 	FindRegistrationBySerialNumber(ctx context.Context, serialNumber string) (bool, error)
 
-	// FindPushToken
-	FindPushToken(ctx context.Context, serialNumber string) (string, error)
+	// FindPushTokens ...
+	FindPushTokens(ctx context.Context, serialNumber string) ([]string, error)
+
+	// FindDeviceIDs ...
+	FindDeviceIDs(ctx context.Context, pushToken string) ([]string, error)
 
 	// FindSerialNumbers ...
 	FindSerialNumbers(ctx context.Context, deviceID, passTypeID, tag string) ([]string, error)
@@ -37,6 +44,29 @@ type PassKit interface {
 	// DeleteRegistration ...
 	DeleteRegistration(ctx context.Context, deviceID, serialNumber, passTypeID string) (bool, error)
 
+	// DeleteStaleRegistration removes registration only if device was not
+	// registered for pass again after given time.
+	DeleteStaleRegistration(ctx context.Context, deviceID, serialNumber, passTypeID string, before time.Time) (bool, error)
+
+	// DeletePass ...
+	DeletePass(ctx context.Context, serialNumber string) error
+
 	// InsertLog ...
 	InsertLog(ctx context.Context, remoteAddr, requestID, message string) error
+
+	// InsertPush ...
+	InsertPush(ctx context.Context, push *Push) error
+
+	// LoadPushes ...
+	LoadPushes(ctx context.Context, serialNumber string) ([]*Push, error)
+
+	// LoadPendingPushes ...
+	LoadPendingPushes(ctx context.Context, limit uint64) ([]*Push, error)
+
+	// ClaimPush ...
+	ClaimPush(ctx context.Context, lease time.Duration, push *Push) (bool, error)
+
+	// UpdatePush stores delivery result of claimed push. Returns false
+	// when push was reclaimed by another worker after lease expired.
+	UpdatePush(ctx context.Context, push *Push) (bool, error)
 }
@@ -22,6 +22,17 @@ const (
 	StoreCard = PassType("storeCard")
 )
 
+// PassTypes returns list of all supported pass types.
+func PassTypes() []PassType {
+	return []PassType{
+		BoardingPass,
+		Coupon,
+		EventTicket,
+		Generic,
+		StoreCard,
+	}
+}
+
 // ImageSize is an alias for image size.
 type ImageSize string
 
@@ -75,6 +86,15 @@ type Project struct {
 	StripImage2x string `json:"stripImage2x" db:"strip_image_2x"`
 	StripImage3x string `json:"stripImage3x" db:"strip_image_3x"`
 
+	ThumbnailImage   string `json:"thumbnailImage" db:"thumbnail_image"`
+	ThumbnailImage2x string `json:"thumbnailImage2x" db:"thumbnail_image_2x"`
+	ThumbnailImage3x string `json:"thumbnailImage3x" db:"thumbnail_image_3x"`
+
+	Localizations Localizations `json:"localizations,omitempty" db:"localizations"`
+	Template      *Template     `json:"template,omitempty" db:"template"`
+
+	ExpireAfterDays int `json:"expireAfterDays,omitempty" db:"expire_after_days"`
+
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
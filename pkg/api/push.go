@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PushStatus is an alias for push delivery status.
+type PushStatus string
+
+const (
+	// PushPending waits for delivery or next retry.
+	PushPending = PushStatus("pending")
+	// PushProcessing is claimed by worker.
+	PushProcessing = PushStatus("processing")
+	// PushDelivered is accepted by APNs.
+	PushDelivered = PushStatus("delivered")
+	// PushDead is given up after too many attempts or unrecoverable error.
+	PushDead = PushStatus("dead")
+)
+
+// NewPush returns a new instance of `Push`.
+func NewPush(serialNumber, passTypeID, pushToken string) *Push {
+	now := time.Now()
+	return &Push{
+		SerialNumber:  serialNumber,
+		PassTypeID:    passTypeID,
+		PushToken:     pushToken,
+		Status:        PushPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Push holds update notification waiting in outbox.
+type Push struct {
+	ID int64 `json:"id" db:"id"`
+
+	SerialNumber string `json:"serialNumber" db:"serial_number"`
+	PassTypeID   string `json:"passTypeIdentifier" db:"pass_type_id"`
+	PushToken    string `json:"pushToken" db:"push_token"`
+
+	Status        PushStatus `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"lastError,omitempty" db:"last_error"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt" db:"next_attempt_at"`
+
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// String returns string representation of struct.
+func (p *Push) String() string {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
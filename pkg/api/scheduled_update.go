@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ScheduleStatus is an alias for scheduled update status.
+type ScheduleStatus string
+
+const (
+	// SchedulePending waits for effective time.
+	SchedulePending = ScheduleStatus("pending")
+	// ScheduleRunning is claimed by scheduler and applied by patch job.
+	ScheduleRunning = ScheduleStatus("running")
+	// ScheduleApplied is applied. Failures of single pass cards are kept in job.
+	ScheduleApplied = ScheduleStatus("applied")
+	// ScheduleFailed is given up because nothing could be applied.
+	ScheduleFailed = ScheduleStatus("failed")
+	// ScheduleCanceled is canceled before effective time.
+	ScheduleCanceled = ScheduleStatus("canceled")
+)
+
+// NewScheduledUpdate returns a new instance of `ScheduledUpdate`.
+func NewScheduledUpdate(projectID int64, effectiveAt time.Time, author Author) *ScheduledUpdate {
+	now := time.Now()
+	return &ScheduledUpdate{
+		ProjectID:   projectID,
+		Status:      SchedulePending,
+		EffectiveAt: effectiveAt,
+		UserID:      author.UserID,
+		RequestID:   author.RequestID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// ScheduledUpdate holds patch applied to single pass card or pass cards
+// matching filter at effective time.
+type ScheduledUpdate struct {
+	ID         int64   `json:"id" db:"id"`
+	ProjectID  int64   `json:"projectId" db:"project_id"`
+	PassCardID int64   `json:"passCardId,omitempty" db:"pass_card_id"`
+	Filter     RawJSON `json:"filter,omitempty" db:"filter"`
+	Patch      RawJSON `json:"patch" db:"patch"`
+
+	Status      ScheduleStatus `json:"status" db:"status"`
+	EffectiveAt time.Time      `json:"effectiveAt" db:"effective_at"`
+	JobID       int64          `json:"jobId,omitempty" db:"job_id"`
+	LastError   string         `json:"lastError,omitempty" db:"last_error"`
+	ClaimedAt   *time.Time     `json:"-" db:"claimed_at"`
+
+	UserID    int64  `json:"userId,omitempty" db:"user_id"`
+	RequestID string `json:"requestId,omitempty" db:"request_id"`
+
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Author returns author of scheduled changes.
+func (u *ScheduledUpdate) Author() Author {
+	return Author{UserID: u.UserID, RequestID: u.RequestID}
+}
+
+// String returns string representation of struct.
+func (u *ScheduledUpdate) String() string {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ScheduledUpdates holds next page token and items.
+type ScheduledUpdates struct {
+	Opts *PagingOptions
+	Data []*ScheduledUpdate
+}
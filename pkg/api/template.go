@@ -0,0 +1,202 @@
+package api
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TemplateField describes pass field whose value is supplied by pass card.
+// `Value` of embedded field is used as default.
+type TemplateField struct {
+	Field
+	Required bool `json:"required,omitempty"`
+}
+
+// Template holds project pass structure with field keys, labels and styles.
+type Template struct {
+	AuxiliaryFields []*TemplateField `json:"auxiliaryFields,omitempty"`
+	BackFields      []*TemplateField `json:"backFields,omitempty"`
+	HeaderFields    []*TemplateField `json:"headerFields,omitempty"`
+	PrimaryFields   []*TemplateField `json:"primaryFields,omitempty"`
+	SecondaryFields []*TemplateField `json:"secondaryFields,omitempty"`
+	TransitType     string           `json:"transitType,omitempty"`
+}
+
+type templateSection struct {
+	fieldType FieldType
+	fields    []*TemplateField
+}
+
+func (t *Template) sections() []templateSection {
+	return []templateSection{
+		{HeaderFieldsType, t.HeaderFields},
+		{PrimaryFieldsType, t.PrimaryFields},
+		{SecondaryFieldsType, t.SecondaryFields},
+		{AuxiliaryFieldsType, t.AuxiliaryFields},
+		{BackFieldsType, t.BackFields},
+	}
+}
+
+// IsValid checks whether input is valid or not.
+func (t *Template) IsValid() error {
+	keys := map[string]bool{}
+	for _, section := range t.sections() {
+		for _, field := range section.fields {
+			if field == nil || field.Key == "" {
+				return fmt.Errorf("template: %s: key is empty", section.fieldType)
+			}
+			if keys[field.Key] {
+				return fmt.Errorf("template: key %q is duplicated", field.Key)
+			}
+			keys[field.Key] = true
+		}
+	}
+	return nil
+}
+
+// Keys returns keys of every template field.
+func (t *Template) Keys() []string {
+	keys := []string{}
+	for _, section := range t.sections() {
+		for _, field := range section.fields {
+			keys = append(keys, field.Key)
+		}
+	}
+	return keys
+}
+
+// Render returns pass structure with values merged into template fields.
+// Missing values fall back to defaults, optional fields without value
+// are omitted.
+func (t *Template) Render(values map[string]interface{}) (*PassStructure, error) {
+	known := map[string]bool{}
+	for _, key := range t.Keys() {
+		known[key] = true
+	}
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("template: unknown key %q", key)
+		}
+	}
+
+	render := func(fields []*TemplateField) ([]*Field, error) {
+		var rendered []*Field
+		for _, tf := range fields {
+			field := tf.Field
+			if value, ok := values[tf.Key]; ok && !isEmptyValue(value) {
+				field.Value = value
+			}
+			if isEmptyValue(field.Value) {
+				if tf.Required {
+					return nil, fmt.Errorf("template: key %q is required", tf.Key)
+				}
+				continue
+			}
+			rendered = append(rendered, &field)
+		}
+		return rendered, nil
+	}
+
+	var (
+		structure = &PassStructure{TransitType: t.TransitType}
+		err       error
+	)
+
+	structure.HeaderFields, err = render(t.HeaderFields)
+	if err != nil {
+		return nil, err
+	}
+	structure.PrimaryFields, err = render(t.PrimaryFields)
+	if err != nil {
+		return nil, err
+	}
+	structure.SecondaryFields, err = render(t.SecondaryFields)
+	if err != nil {
+		return nil, err
+	}
+	structure.AuxiliaryFields, err = render(t.AuxiliaryFields)
+	if err != nil {
+		return nil, err
+	}
+	structure.BackFields, err = render(t.BackFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return structure, nil
+}
+
+// Values returns values of structure fields which are defined by template.
+func (t *Template) Values(structure *PassStructure) map[string]interface{} {
+	values := map[string]interface{}{}
+	if structure == nil {
+		return values
+	}
+
+	known := map[string]bool{}
+	for _, key := range t.Keys() {
+		known[key] = true
+	}
+
+	for _, fields := range [][]*Field{
+		structure.HeaderFields,
+		structure.PrimaryFields,
+		structure.SecondaryFields,
+		structure.AuxiliaryFields,
+		structure.BackFields,
+	} {
+		for _, field := range fields {
+			if known[field.Key] {
+				values[field.Key] = field.Value
+			}
+		}
+	}
+
+	return values
+}
+
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+// Value is a value that drivers must be able to handle.
+func (t *Template) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return driver.Value(""), err
+	}
+	return driver.Value(string(data)), nil
+}
+
+// Scan value from database.
+func (t *Template) Scan(src interface{}) error {
+	var source []byte
+	switch v := src.(type) {
+	case nil:
+		source = []byte("")
+	case string:
+		source = []byte(v)
+	case []byte:
+		source = v
+	case sql.NullString:
+		source = []byte(v.String)
+	default:
+		return errors.New("invalid data type for Template")
+	}
+
+	if len(source) == 0 || string(source) == "null" {
+		*t = Template{}
+		return nil
+	}
+	return json.Unmarshal(source, t)
+}
@@ -4,41 +4,97 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/sideshow/apns2"
 	"github.com/sideshow/apns2/certificate"
 )
 
+// APNs rejection reasons which mean that device is no longer
+// interested in pass updates.
+const (
+	ReasonBadDeviceToken = apns2.ReasonBadDeviceToken
+	ReasonUnregistered   = apns2.ReasonUnregistered
+)
+
 // New creates a new instance of `Notificator`.
-func New(data []byte, pass string, production bool) (Notificator, error) {
+// Topic must be equal to pass type identifier of certificate.
+func New(data []byte, pass, topic string, production bool) (Notificator, error) {
 	cert, err := certificate.FromP12Bytes(data, pass)
 	if err != nil {
 		return nil, err
 	}
-	client := apns2.NewClient(cert).Production()
-	return &notificator{client}, nil
+	client := apns2.NewClient(cert)
+	if production {
+		client = client.Production()
+	} else {
+		client = client.Development()
+	}
+	return &notificator{client, topic}, nil
 }
 
 // Notificator sends notifictions for iOS devices.
+// Error is returned only when APNs could not be reached,
+// rejected notifications are reported by `Result`.
 type Notificator interface {
-	Push(ctx context.Context, token string) error
+	Push(ctx context.Context, token string) (*Result, error)
+}
+
+// Result holds APNs response for a single notification.
+type Result struct {
+	// StatusCode is HTTP status code returned by APNs.
+	StatusCode int
+	// ID is a unique notification identifier assigned by APNs.
+	ID string
+	// Reason describes why notification was rejected.
+	Reason string
+	// Timestamp is the last time APNs confirmed that
+	// device token was no longer valid for the topic.
+	Timestamp time.Time
+}
+
+// Sent checks whether notification was accepted by APNs.
+func (r *Result) Sent() bool {
+	return r.StatusCode == http.StatusOK
+}
+
+// Unregistered checks whether device token should be forgotten.
+func (r *Result) Unregistered() bool {
+	return r.Reason == ReasonUnregistered || r.Reason == ReasonBadDeviceToken
+}
+
+// Retryable checks whether notification may be accepted later.
+func (r *Result) Retryable() bool {
+	return r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= http.StatusInternalServerError
+}
+
+// String returns string representation of result.
+func (r *Result) String() string {
+	if r.Timestamp.IsZero() {
+		return fmt.Sprintf("%d %s", r.StatusCode, r.Reason)
+	}
+	return fmt.Sprintf("%d %s at %s", r.StatusCode, r.Reason, r.Timestamp.Format(time.RFC3339))
 }
 
 type notificator struct {
 	client *apns2.Client
+	topic  string
 }
 
-func (n *notificator) Push(ctx context.Context, token string) error {
-	result, err := n.client.PushWithContext(ctx, &apns2.Notification{
+func (n *notificator) Push(ctx context.Context, token string) (*Result, error) {
+	resp, err := n.client.PushWithContext(ctx, &apns2.Notification{
 		DeviceToken: token,
+		Topic:       n.topic,
 		Payload:     []byte(`{}`),
 		Priority:    apns2.PriorityHigh,
 	})
 	if err != nil {
-		return err
-	}
-	if result.StatusCode != http.StatusOK {
-		return fmt.Errorf("wrong status code: %s", result.Reason)
+		return nil, err
 	}
-	return nil
+	return &Result{
+		StatusCode: resp.StatusCode,
+		ID:         resp.ApnsID,
+		Reason:     resp.Reason,
+		Timestamp:  resp.Timestamp.Time,
+	}, nil
 }
@@ -0,0 +1,66 @@
+package apns_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/apns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult(t *testing.T) {
+	testCases := []struct {
+		Name         string
+		Result       apns.Result
+		Sent         bool
+		Unregistered bool
+		Retryable    bool
+		String       string
+	}{
+		{
+			Name:   "Sent",
+			Result: apns.Result{StatusCode: http.StatusOK},
+			Sent:   true,
+			String: "200 ",
+		},
+		{
+			Name:         "BadDeviceToken",
+			Result:       apns.Result{StatusCode: http.StatusBadRequest, Reason: apns.ReasonBadDeviceToken},
+			Unregistered: true,
+			String:       "400 BadDeviceToken",
+		},
+		{
+			Name: "Unregistered",
+			Result: apns.Result{
+				StatusCode: http.StatusGone,
+				Reason:     apns.ReasonUnregistered,
+				Timestamp:  time.Date(2019, 6, 8, 13, 20, 0, 0, time.UTC),
+			},
+			Unregistered: true,
+			String:       "410 Unregistered at 2019-06-08T13:20:00Z",
+		},
+		{
+			Name:      "TooManyRequests",
+			Result:    apns.Result{StatusCode: http.StatusTooManyRequests, Reason: "TooManyRequests"},
+			Retryable: true,
+			String:    "429 TooManyRequests",
+		},
+		{
+			Name:      "ServiceUnavailable",
+			Result:    apns.Result{StatusCode: http.StatusServiceUnavailable, Reason: "ServiceUnavailable"},
+			Retryable: true,
+			String:    "503 ServiceUnavailable",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.Sent, tc.Result.Sent())
+			assert.Equal(tc.Unregistered, tc.Result.Unregistered())
+			assert.Equal(tc.Retryable, tc.Result.Retryable())
+			assert.Equal(tc.String, tc.Result.String())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package apns
 import (
 	"context"
 	"fmt"
+	"net/http"
 )
 
 // NewMock returns mock of `Notificator`.
@@ -12,7 +13,7 @@ func NewMock() Notificator {
 
 type mock struct{}
 
-func (m *mock) Push(ctx context.Context, token string) error {
+func (m *mock) Push(ctx context.Context, token string) (*Result, error) {
 	fmt.Println("sending update notification to", token)
-	return nil
+	return &Result{StatusCode: http.StatusOK}, nil
 }
@@ -0,0 +1,38 @@
+package apns
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrUnsupportedNotificator returned when there is no notificator for pass type identifier.
+var ErrUnsupportedNotificator = errors.New("apns: unsupported notificator")
+
+// NewRegistry returns a new empty instance of `Registry`.
+func NewRegistry() *Registry {
+	return &Registry{notificators: map[string]Notificator{}}
+}
+
+// Registry holds notificators keyed by pass type identifier.
+type Registry struct {
+	mu           sync.Mutex
+	notificators map[string]Notificator
+}
+
+// Set registers notificator for pass type identifier.
+func (r *Registry) Set(passTypeID string, notificator Notificator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notificators[passTypeID] = notificator
+}
+
+// Get returns notificator registered for pass type identifier.
+func (r *Registry) Get(passTypeID string) (Notificator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	notificator, ok := r.notificators[passTypeID]
+	if !ok {
+		return nil, ErrUnsupportedNotificator
+	}
+	return notificator, nil
+}
@@ -0,0 +1,50 @@
+package apns_test
+
+import (
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/apns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		PassTypeID string
+		Register   bool
+		Expected   error
+	}{
+		{
+			Name:       "Registered",
+			PassTypeID: "pass.com.example.coupon",
+			Register:   true,
+		},
+		{
+			Name:       "Unsupported",
+			PassTypeID: "pass.com.example.generic",
+			Expected:   apns.ErrUnsupportedNotificator,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			registry := apns.NewRegistry()
+			if tc.Register {
+				registry.Set(tc.PassTypeID, apns.NewMock())
+			}
+
+			notificator, err := registry.Get(tc.PassTypeID)
+			if tc.Expected != nil {
+				assert.Equal(tc.Expected, err)
+				assert.Nil(notificator)
+				return
+			}
+
+			if assert.NoError(err) {
+				assert.NotNil(notificator)
+			}
+		})
+	}
+}
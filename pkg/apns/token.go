@@ -0,0 +1,31 @@
+package apns
+
+import (
+	"github.com/sideshow/apns2"
+	"github.com/sideshow/apns2/token"
+)
+
+// NewWithToken creates a new instance of `Notificator` which uses
+// provider token authentication instead of certificate.
+// JWT is signed with `.p8` key and regenerated before it expires.
+func NewWithToken(key []byte, keyID, teamID, topic string, production bool) (Notificator, error) {
+	authKey, err := token.AuthKeyFromBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	t := &token.Token{
+		AuthKey: authKey,
+		KeyID:   keyID,
+		TeamID:  teamID,
+	}
+	if _, err := t.Generate(); err != nil {
+		return nil, err
+	}
+	client := apns2.NewTokenClient(t)
+	if production {
+		client = client.Production()
+	} else {
+		client = client.Development()
+	}
+	return &notificator{client, topic}, nil
+}
@@ -0,0 +1,184 @@
+package apns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sideshow/apns2/token"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+const (
+	testKeyID  = "ABC123DEFG"
+	testTeamID = "DEF123GHIJ"
+	testTopic  = "pass.com.okpock.coupon-dev"
+)
+
+var testTimestamp = time.Unix(1560000000, 0)
+
+func fakeAuthKey() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+type fakeRequest struct {
+	Proto    int
+	Path     string
+	Topic    string
+	IssuedAt int64
+}
+
+func fakeServer(key *ecdsa.PrivateKey, requests chan<- fakeRequest) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+		claims := jwt.MapClaims{}
+		parsed, err := jwt.ParseWithClaims(bearer, claims, func(t *jwt.Token) (interface{}, error) {
+			if t.Method != jwt.SigningMethodES256 {
+				return nil, fmt.Errorf("unexpected method: %s", t.Method.Alg())
+			}
+			if t.Header["kid"] != testKeyID {
+				return nil, fmt.Errorf("unexpected key id: %v", t.Header["kid"])
+			}
+			return &key.PublicKey, nil
+		})
+		if err != nil || !parsed.Valid || claims["iss"] != testTeamID {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"reason":"InvalidProviderToken"}`))
+			return
+		}
+
+		iat, _ := claims["iat"].(float64)
+		requests <- fakeRequest{
+			Proto:    r.ProtoMajor,
+			Path:     r.URL.Path,
+			Topic:    r.Header.Get("apns-topic"),
+			IssuedAt: int64(iat),
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/bad-token") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/removed-token") {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(fmt.Sprintf(`{"reason":"Unregistered","timestamp":%d}`, testTimestamp.UnixNano()/int64(time.Millisecond))))
+			return
+		}
+
+		w.Header().Set("apns-id", "fake-apns-id")
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	srv.StartTLS()
+	return srv
+}
+
+func withFakeServer(n Notificator, srv *httptest.Server) *notificator {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	nt := n.(*notificator)
+	nt.client.Host = srv.URL
+	nt.client.HTTPClient.Transport.(*http2.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	return nt
+}
+
+func TestNewWithToken(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		DeviceToken string
+		Expired     bool
+		Expected    Result
+	}{
+		{
+			Name:        "Success",
+			DeviceToken: "good-token",
+			Expected:    Result{StatusCode: http.StatusOK, ID: "fake-apns-id"},
+		},
+		{
+			Name:        "ExpiredJWT",
+			DeviceToken: "good-token",
+			Expired:     true,
+			Expected:    Result{StatusCode: http.StatusOK, ID: "fake-apns-id"},
+		},
+		{
+			Name:        "BadDeviceToken",
+			DeviceToken: "bad-token",
+			Expected:    Result{StatusCode: http.StatusBadRequest, Reason: ReasonBadDeviceToken},
+		},
+		{
+			Name:        "Unregistered",
+			DeviceToken: "removed-token",
+			Expected:    Result{StatusCode: http.StatusGone, Reason: ReasonUnregistered, Timestamp: testTimestamp},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			key, data, err := fakeAuthKey()
+			if !assert.NoError(err) {
+				return
+			}
+
+			requests := make(chan fakeRequest, 1)
+			srv := fakeServer(key, requests)
+			defer srv.Close()
+
+			n, err := NewWithToken(data, testKeyID, testTeamID, testTopic, false)
+			if !assert.NoError(err) {
+				return
+			}
+			nt := withFakeServer(n, srv)
+
+			if tc.Expired {
+				nt.client.Token.IssuedAt = time.Now().Unix() - token.TokenTimeout
+			}
+
+			result, err := nt.Push(context.Background(), tc.DeviceToken)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Expected.StatusCode, result.StatusCode)
+			assert.Equal(tc.Expected.ID, result.ID)
+			assert.Equal(tc.Expected.Reason, result.Reason)
+			assert.True(tc.Expected.Timestamp.Equal(result.Timestamp))
+			assert.Equal(tc.Expected.Reason != "", result.Unregistered())
+
+			req := <-requests
+			assert.Equal(2, req.Proto)
+			assert.Equal("/3/device/"+tc.DeviceToken, req.Path)
+			assert.Equal(testTopic, req.Topic)
+			assert.True(time.Now().Unix()-req.IssuedAt < token.TokenTimeout)
+			assert.False(nt.client.Token.Expired())
+		})
+	}
+}
+
+func TestNewWithTokenInvalidKey(t *testing.T) {
+	_, err := NewWithToken([]byte("not a key"), testKeyID, testTeamID, testTopic, false)
+	assert.Equal(t, token.ErrAuthKeyNotPem, err)
+}
@@ -1,6 +1,13 @@
 package env
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/kelseyhightower/envconfig"
+)
 
 // NewConfig parses and returns a new config.
 func NewConfig() (Config, error) {
@@ -22,20 +29,98 @@ type Config struct {
 	ServerSecret string            `envconfig:"server_secret" required:"true" desc:"JWT Server Secret"`
 	MailerRegion string            `envconfig:"mailer_region" required:"true" desc:"Mailer Region"`
 	Certificates CertificateConfig `envconfig:"certificates" required:"true" desc:"Certificates Config"`
+	APNS         APNSConfig        `envconfig:"apns" desc:"APNs Config"`
+	ArchiveTTL   time.Duration     `envconfig:"archive_ttl" default:"720h" desc:"Time Archived Pass Cards Are Kept Before Purge"`
+}
+
+const (
+	// CertificateAuth refers to APNs authentication using pass type certificate.
+	CertificateAuth = "certificate"
+	// TokenAuth refers to APNs authentication using provider token.
+	TokenAuth = "token"
+)
+
+// APNSConfig holds environment variables related to push notifications.
+type APNSConfig struct {
+	Auth        string `envconfig:"auth" default:"certificate" desc:"APNs Authentication Method (certificate or token)"`
+	Key         string `envconfig:"key" desc:"APNs Auth Key (.p8) Path"`
+	KeyID       string `envconfig:"key_id" desc:"APNs Auth Key Identifier"`
+	Workers     int    `envconfig:"workers" default:"4" desc:"Number of Push Delivery Workers"`
+	MaxAttempts int    `envconfig:"max_attempts" default:"10" desc:"Push Delivery Attempts Before Dead Letter"`
+	PushRate    int    `envconfig:"push_rate" default:"50" desc:"Pushes Sent Per Second (0 is unlimited)"`
+}
+
+// UseToken checks whether provider token authentication is enabled.
+func (c APNSConfig) UseToken() bool {
+	return c.Auth == TokenAuth
+}
+
+// IsValid checks whether config is valid or not.
+func (c APNSConfig) IsValid() error {
+	switch c.Auth {
+	case CertificateAuth:
+		return nil
+	case TokenAuth:
+		if c.Key == "" {
+			return errors.New("apns: auth key is empty")
+		}
+		if c.KeyID == "" {
+			return errors.New("apns: auth key id is empty")
+		}
+		return nil
+	}
+	return fmt.Errorf("apns: unknown auth method %q", c.Auth)
 }
 
 // CertificateConfig holds environment variables related to certificates.
 type CertificateConfig struct {
-	Team     string      `envconfig:"team" required:"true" desc:"Apple Team Identifier"`
-	Bucket   string      `envconfig:"bucket" required:"true" desc:"Apple Certificates Bucket Name"`
-	RootCert string      `envconfig:"root_cert" required:"true" desc:"Apple WWDR Certificate"`
-	Coupon   Certificate `envconfig:"coupon" required:"true" desc:"Coupon Certificate"`
+	Team         string      `envconfig:"team" required:"true" desc:"Apple Team Identifier"`
+	Bucket       string      `envconfig:"bucket" required:"true" desc:"Apple Certificates Bucket Name"`
+	RootCert     string      `envconfig:"root_cert" required:"true" desc:"Apple WWDR Certificate"`
+	BoardingPass Certificate `envconfig:"boarding_pass" desc:"Boarding Pass Certificate"`
+	Coupon       Certificate `envconfig:"coupon" desc:"Coupon Certificate"`
+	EventTicket  Certificate `envconfig:"event_ticket" desc:"Event Ticket Certificate"`
+	Generic      Certificate `envconfig:"generic" desc:"Generic Certificate"`
+	StoreCard    Certificate `envconfig:"store_card" desc:"Store Card Certificate"`
+}
+
+// Certificate returns certificate config for given pass type.
+func (c CertificateConfig) Certificate(passType api.PassType) Certificate {
+	switch passType {
+	case api.BoardingPass:
+		return c.BoardingPass
+	case api.Coupon:
+		return c.Coupon
+	case api.EventTicket:
+		return c.EventTicket
+	case api.Generic:
+		return c.Generic
+	case api.StoreCard:
+		return c.StoreCard
+	}
+	return Certificate{}
+}
+
+// Missing returns pass types which have no certificate.
+func (c CertificateConfig) Missing() []api.PassType {
+	missing := []api.PassType{}
+	for _, passType := range api.PassTypes() {
+		if c.Certificate(passType).IsEmpty() {
+			missing = append(missing, passType)
+		}
+	}
+	return missing
 }
 
 // Certificate holds certificate path and password.
 type Certificate struct {
-	Path string `envconfig:"path" required:"true" desc:"Certificate Path"`
-	Pass string `envconfig:"pass" required:"true" desc:"Certificate Password"`
+	Path string `envconfig:"path" desc:"Certificate Path"`
+	Pass string `envconfig:"pass" desc:"Certificate Password"`
+}
+
+// IsEmpty checks whether certificate path is set or not.
+func (c Certificate) IsEmpty() bool {
+	return c.Path == ""
 }
 
 // Usage returns usage for config instance.
@@ -57,3 +142,26 @@ func (c Config) IsDevelopment() bool {
 func (c Config) IsProduction() bool {
 	return c.Stage == "production"
 }
+
+// PassTypeID returns pass type identifier for given pass type.
+func (c Config) PassTypeID(passType api.PassType) string {
+	domainLayout := "pass.com.okpock.%s"
+	if c.IsDevelopment() {
+		domainLayout += "-dev"
+	}
+
+	switch passType {
+	case api.BoardingPass:
+		return fmt.Sprintf(domainLayout, "boardingpass")
+	case api.Coupon:
+		return fmt.Sprintf(domainLayout, "coupon")
+	case api.EventTicket:
+		return fmt.Sprintf(domainLayout, "eventticket")
+	case api.Generic:
+		return fmt.Sprintf(domainLayout, "generic")
+	case api.StoreCard:
+		return fmt.Sprintf(domainLayout, "storecard")
+	}
+
+	return ""
+}
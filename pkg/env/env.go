@@ -10,28 +10,30 @@ import (
 
 // New returns a new instance of `Env`.
 func New(cfg Config, passkit api.PassKit, auth api.Auth, logic api.Logic,
-	storage filestore.Storage, mailer mail.Mailer, coupon pkpass.Signer,
-	notificator apns.Notificator) *Env {
+	storage filestore.Storage, mailer mail.Mailer, signers *pkpass.Registry,
+	verifier pkpass.Verifier, notificators *apns.Registry) *Env {
 	return &Env{
-		Config:            cfg,
-		PassKit:           passkit,
-		Auth:              auth,
-		Logic:             logic,
-		Storage:           storage,
-		Mailer:            mailer,
-		CouponSigner:      coupon,
-		CouponNotificator: notificator,
+		Config:       cfg,
+		PassKit:      passkit,
+		Auth:         auth,
+		Logic:        logic,
+		Storage:      storage,
+		Mailer:       mailer,
+		Signers:      signers,
+		Verifier:     verifier,
+		Notificators: notificators,
 	}
 }
 
 // Env holds stores and config.
 type Env struct {
-	Config            Config
-	PassKit           api.PassKit
-	Auth              api.Auth
-	Logic             api.Logic
-	Storage           filestore.Storage
-	Mailer            mail.Mailer
-	CouponSigner      pkpass.Signer
-	CouponNotificator apns.Notificator
+	Config       Config
+	PassKit      api.PassKit
+	Auth         api.Auth
+	Logic        api.Logic
+	Storage      filestore.Storage
+	Mailer       mail.Mailer
+	Signers      *pkpass.Registry
+	Verifier     pkpass.Verifier
+	Notificators *apns.Registry
 }
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/apns"
 	fsmock "github.com/danikarik/okpock/pkg/filestore/memory"
 	mlmock "github.com/danikarik/okpock/pkg/mail/memory"
@@ -50,5 +51,18 @@ func NewMock() (*Env, error) {
 		return nil, err
 	}
 
-	return New(cfg, db, db, db, fs, ml, couponSigner, apns.NewMock()), nil
+	verifier, err := pkpass.NewVerifier(rootCert)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only coupon certificate is available in test environment.
+	signers := pkpass.NewRegistry()
+	notificators := apns.NewRegistry()
+	for _, passType := range api.PassTypes() {
+		signers.Set(cfg.PassTypeID(passType), couponSigner)
+		notificators.Set(cfg.PassTypeID(passType), apns.NewMock())
+	}
+
+	return New(cfg, db, db, db, fs, ml, signers, verifier, notificators), nil
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -12,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/danikarik/okpock/pkg/filestore"
 )
 
@@ -52,6 +54,18 @@ func (s3h *s3handler) GetFile(ctx context.Context, bucket, key string) (*filesto
 	}, nil
 }
 
+func (s3h *s3handler) GetStream(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	output, err := s3h.srv.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("could not get object %s: %v", key, err)
+	}
+	return output.Body, nil
+}
+
 func (s3h *s3handler) GetBucketFiles(ctx context.Context, bucket, prefix string) ([]*filestore.Object, error) {
 	contents := make([]*filestore.Object, 0)
 	input := &s3.ListObjectsInput{
@@ -99,3 +113,36 @@ func (s3h *s3handler) UploadFile(ctx context.Context, bucket string, obj *filest
 	}
 	return nil
 }
+
+func (s3h *s3handler) UploadStream(ctx context.Context, bucket string, obj *filestore.Object, body io.Reader) error {
+	if obj == nil {
+		return errors.New("object cannot be empty")
+	}
+
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(obj.Path()),
+		Body:        body,
+		ContentType: aws.String(obj.ContentType),
+	}
+
+	uploader := s3manager.NewUploaderWithClient(s3h.srv)
+	_, err := uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("could not upload object %s: %v", obj.Key, err)
+	}
+
+	return nil
+}
+
+func (s3h *s3handler) DeleteFile(ctx context.Context, bucket, key string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := s3h.srv.DeleteObjectWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("could not delete object %s: %v", key, err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package awsstore_test
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"net/http"
@@ -302,3 +303,74 @@ func TestUploadFolderFile(t *testing.T) {
 	err = store.UploadFile(ctx, env.Get("TEST_PASSES_BUCKET"), obj)
 	assert.NoError(err)
 }
+
+func TestUploadStream(t *testing.T) {
+	skipTest(t)
+
+	env, err := env.NewLookup(requiredVars...)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	store, err := awsstore.New()
+	if !assert.NoError(err) {
+		return
+	}
+
+	body := []byte("Hello World\n")
+	obj := &filestore.Object{
+		Key:         uuid.NewV4().String() + ".txt",
+		ContentType: "text/plain",
+	}
+
+	err = store.UploadStream(ctx, env.Get("TEST_PASSES_BUCKET"), obj, bytes.NewReader(body))
+	if !assert.NoError(err) {
+		return
+	}
+
+	loaded, err := store.GetFile(ctx, env.Get("TEST_PASSES_BUCKET"), obj.Key)
+	assert.NoError(err)
+	assert.Equal(body, loaded.Body)
+}
+
+func TestDeleteFile(t *testing.T) {
+	skipTest(t)
+
+	env, err := env.NewLookup(requiredVars...)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	store, err := awsstore.New()
+	if !assert.NoError(err) {
+		return
+	}
+
+	obj := &filestore.Object{
+		Key:         uuid.NewV4().String() + ".txt",
+		Body:        []byte("Hello World\n"),
+		ContentType: "text/plain",
+	}
+
+	err = store.UploadFile(ctx, env.Get("TEST_PASSES_BUCKET"), obj)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = store.DeleteFile(ctx, env.Get("TEST_PASSES_BUCKET"), obj.Key)
+	if !assert.NoError(err) {
+		return
+	}
+
+	_, err = store.GetFile(ctx, env.Get("TEST_PASSES_BUCKET"), obj.Key)
+	assert.Error(err)
+
+	err = store.DeleteFile(ctx, env.Get("TEST_PASSES_BUCKET"), obj.Key)
+	assert.NoError(err)
+}
@@ -1,8 +1,11 @@
 package memory
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
 
@@ -52,6 +55,15 @@ func (m *mockHandler) GetFile(ctx context.Context, bucket, key string) (*filesto
 	return obj, nil
 }
 
+func (m *mockHandler) GetStream(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	obj, err := m.GetFile(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(obj.Body)), nil
+}
+
 func (m *mockHandler) GetBucketFiles(ctx context.Context, bucket, prefix string) ([]*filestore.Object, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -92,3 +104,26 @@ func (m *mockHandler) UploadFile(ctx context.Context, bucket string, obj *filest
 
 	return nil
 }
+
+func (m *mockHandler) UploadStream(ctx context.Context, bucket string, obj *filestore.Object, body io.Reader) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	return m.UploadFile(ctx, bucket, &filestore.Object{
+		Prefix:      obj.Prefix,
+		Key:         obj.Key,
+		Body:        data,
+		ContentType: obj.ContentType,
+	})
+}
+
+func (m *mockHandler) DeleteFile(ctx context.Context, bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.files, mockIndex(bucket, key))
+
+	return nil
+}
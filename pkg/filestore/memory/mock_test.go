@@ -1,6 +1,7 @@
 package memory_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -86,3 +87,73 @@ func TestMockUploadFile(t *testing.T) {
 		})
 	}
 }
+
+func TestMockUploadStream(t *testing.T) {
+	env, err := env.NewLookup(mockRequiredVars...)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var (
+		ctx    = context.Background()
+		store  = memory.New()
+		assert = assert.New(t)
+		bucket = env.Get("TEST_PASSES_BUCKET")
+		body   = []byte("Hello World\n")
+	)
+
+	obj := &filestore.Object{
+		Prefix:      uuid.NewV1().String(),
+		Key:         uuid.NewV4().String() + ".txt",
+		ContentType: "text/plain",
+	}
+
+	err = store.UploadStream(ctx, bucket, obj, bytes.NewReader(body))
+	if !assert.NoError(err) {
+		return
+	}
+
+	loaded, err := store.GetFile(ctx, bucket, obj.Path())
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(body, loaded.Body)
+	assert.Equal(obj.ContentType, loaded.ContentType)
+}
+
+func TestMockDeleteFile(t *testing.T) {
+	env, err := env.NewLookup(mockRequiredVars...)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var (
+		ctx    = context.Background()
+		store  = memory.New()
+		assert = assert.New(t)
+		bucket = env.Get("TEST_PASSES_BUCKET")
+	)
+
+	obj := &filestore.Object{
+		Key:         uuid.NewV4().String() + ".txt",
+		Body:        []byte("Hello World\n"),
+		ContentType: "text/plain",
+	}
+
+	err = store.UploadFile(ctx, bucket, obj)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = store.DeleteFile(ctx, bucket, obj.Key)
+	if !assert.NoError(err) {
+		return
+	}
+
+	_, err = store.GetFile(ctx, bucket, obj.Key)
+	assert.Error(err)
+
+	err = store.DeleteFile(ctx, bucket, obj.Key)
+	assert.NoError(err)
+}
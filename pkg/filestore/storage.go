@@ -3,12 +3,16 @@ package filestore
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 )
 
 const (
 	// ApplePkpass refers to `pkpass` extentsion's content-type.
 	ApplePkpass = "application/vnd.apple.pkpass"
+
+	// ApplePkpasses refers to `pkpasses` extentsion's content-type.
+	ApplePkpasses = "application/vnd.apple.pkpasses"
 )
 
 // Object represents file object in the bucket.
@@ -41,6 +45,10 @@ type Storage interface {
 	// TODO: description
 	GetFile(ctx context.Context, bucket, key string) (*Object, error)
 
+	// GetStream opens object's content for reading without buffering it.
+	// Caller must close returned reader.
+	GetStream(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+
 	// GetBucketFiles ...
 	// TODO: description
 	GetBucketFiles(ctx context.Context, bucket, prefix string) ([]*Object, error)
@@ -48,4 +56,11 @@ type Storage interface {
 	// UploadFile ...
 	// TODO: description
 	UploadFile(ctx context.Context, bucket string, obj *Object) error
+
+	// UploadStream uploads content of reader under object's path.
+	// Object's body is ignored.
+	UploadStream(ctx context.Context, bucket string, obj *Object, body io.Reader) error
+
+	// DeleteFile removes object, missing object is not an error.
+	DeleteFile(ctx context.Context, bucket, key string) error
 }
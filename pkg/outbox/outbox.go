@@ -0,0 +1,280 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/apns"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWorkers      = 4
+	defaultMaxAttempts  = 10
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultLease        = time.Minute
+	defaultMinBackoff   = 5 * time.Second
+	defaultMaxBackoff   = time.Hour
+)
+
+// Options holds dispatcher settings. Zero values are replaced by defaults.
+type Options struct {
+	// Workers is a number of concurrent deliveries.
+	Workers int
+	// MaxAttempts is a number of attempts before push is moved to dead letter.
+	MaxAttempts int
+	// BatchSize limits pushes fetched per poll.
+	BatchSize uint64
+	// PollInterval is a pause between polls of outbox.
+	PollInterval time.Duration
+	// Lease is a time after which unfinished push is retried by another worker.
+	Lease time.Duration
+	// MinBackoff is a delay before the first retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps exponential delay between retries.
+	MaxBackoff time.Duration
+	// Rate limits pushes sent per second, zero is unlimited.
+	Rate int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = defaultWorkers
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultMaxAttempts
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = defaultBatchSize
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+	if o.Lease <= 0 {
+		o.Lease = defaultLease
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = defaultMinBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaultMaxBackoff
+	}
+	return o
+}
+
+// Backoff returns delay before next attempt. Delay doubles with every
+// attempt starting from min and never exceeds max.
+func Backoff(attempt int, min, max time.Duration) time.Duration {
+	delay := min
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// New returns a new instance of `Dispatcher`.
+func New(passkit api.PassKit, notificators *apns.Registry, logger *zap.Logger, opts Options) *Dispatcher {
+	d := &Dispatcher{
+		passkit:      passkit,
+		notificators: notificators,
+		logger:       logger,
+		opts:         opts.withDefaults(),
+	}
+	if d.opts.Rate > 0 {
+		d.interval = time.Second / time.Duration(d.opts.Rate)
+	}
+	return d
+}
+
+// Dispatcher delivers pushes stored in outbox.
+type Dispatcher struct {
+	passkit      api.PassKit
+	notificators *apns.Registry
+	logger       *zap.Logger
+	opts         Options
+	interval     time.Duration
+	next         time.Time
+}
+
+// Run polls outbox and delivers pending pushes until context is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var (
+		wg    sync.WaitGroup
+		queue = make(chan *api.Push)
+	)
+
+	for i := 0; i < d.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for push := range queue {
+				d.deliver(ctx, push)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.poll(ctx, queue)
+
+		select {
+		case <-ctx.Done():
+			close(queue)
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) poll(ctx context.Context, queue chan<- *api.Push) {
+	pushes, err := d.passkit.LoadPendingPushes(ctx, d.opts.BatchSize)
+	if err != nil {
+		d.logger.Error("outbox_error", zap.Error(err), zap.String("message", "LoadPendingPushes"))
+		return
+	}
+
+	for _, push := range pushes {
+		if !d.wait(ctx) {
+			return
+		}
+
+		ok, err := d.passkit.ClaimPush(ctx, d.opts.Lease, push)
+		if err != nil {
+			d.logger.Error("outbox_error", zap.Error(err), zap.String("message", "ClaimPush"))
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case queue <- push:
+		}
+	}
+}
+
+// wait blocks until next push is allowed by rate. Returns false once
+// context is done.
+func (d *Dispatcher) wait(ctx context.Context) bool {
+	if d.interval == 0 {
+		return ctx.Err() == nil
+	}
+
+	if delay := time.Until(d.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+		}
+	}
+
+	d.next = time.Now().Add(d.interval)
+	return true
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, push *api.Push) {
+	logger := d.logger.
+		With(zap.Int64("push_id", push.ID)).
+		With(zap.String("serial_number", push.SerialNumber)).
+		With(zap.Int("attempts", push.Attempts))
+
+	notificator, err := d.notificators.Get(push.PassTypeID)
+	if err != nil {
+		d.fail(push, err.Error())
+		d.finish(logger, push)
+		return
+	}
+
+	result, err := notificator.Push(ctx, push.PushToken)
+	switch {
+	case err != nil:
+		d.retry(push, err.Error())
+	case result.Sent():
+		push.Status = api.PushDelivered
+		push.LastError = ""
+	case result.Unregistered():
+		d.fail(push, result.String())
+		d.prune(ctx, logger, push, result.Timestamp)
+	case result.Retryable():
+		d.retry(push, result.String())
+	default:
+		d.fail(push, result.String())
+	}
+
+	d.finish(logger, push)
+}
+
+func (d *Dispatcher) retry(push *api.Push, reason string) {
+	if push.Attempts >= d.opts.MaxAttempts {
+		d.fail(push, reason)
+		return
+	}
+	push.Status = api.PushPending
+	push.LastError = reason
+	push.NextAttemptAt = time.Now().Add(Backoff(push.Attempts, d.opts.MinBackoff, d.opts.MaxBackoff))
+}
+
+func (d *Dispatcher) fail(push *api.Push, reason string) {
+	push.Status = api.PushDead
+	push.LastError = reason
+}
+
+// prune removes registrations of devices which no longer have pass installed.
+// Devices registered again after APNs timestamp are kept. When APNs omits
+// timestamp the moment push was claimed is used instead.
+func (d *Dispatcher) prune(ctx context.Context, logger *zap.Logger, push *api.Push, unregisteredAt time.Time) {
+	if unregisteredAt.IsZero() {
+		unregisteredAt = push.UpdatedAt
+	}
+
+	deviceIDs, err := d.passkit.FindDeviceIDs(ctx, push.PushToken)
+	if err != nil {
+		logger.Error("outbox_error", zap.Error(err), zap.String("message", "FindDeviceIDs"))
+		return
+	}
+
+	for _, deviceID := range deviceIDs {
+		ok, err := d.passkit.DeleteStaleRegistration(ctx, deviceID, push.SerialNumber, push.PassTypeID, unregisteredAt)
+		if err != nil {
+			logger.Error("outbox_error", zap.Error(err), zap.String("message", "DeleteStaleRegistration"))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		logger.Info("registration_pruned", zap.String("device_id", deviceID), zap.String("reason", push.LastError))
+	}
+}
+
+func (d *Dispatcher) finish(logger *zap.Logger, push *api.Push) {
+	logger = logger.With(zap.String("status", string(push.Status)))
+	if push.LastError != "" {
+		logger.Warn("push_failed", zap.String("reason", push.LastError))
+	}
+
+	ok, err := d.passkit.UpdatePush(context.Background(), push)
+	if err != nil {
+		logger.Error("outbox_error", zap.Error(err), zap.String("message", "UpdatePush"))
+		return
+	}
+	if !ok {
+		logger.Warn("push_lease_lost")
+	}
+}
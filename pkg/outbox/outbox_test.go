@@ -0,0 +1,289 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/apns"
+	"github.com/danikarik/okpock/pkg/outbox"
+	"github.com/danikarik/okpock/pkg/store/memory"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// flakyNotificator fails first calls with given error or rejection result.
+type flakyNotificator struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	result   *apns.Result
+	calls    int
+}
+
+func (n *flakyNotificator) Push(ctx context.Context, token string) (*apns.Result, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	if n.calls <= n.failures {
+		return n.result, n.err
+	}
+	return &apns.Result{StatusCode: http.StatusOK}, nil
+}
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Attempt  int
+		Expected time.Duration
+	}{
+		{Name: "First", Attempt: 1, Expected: time.Second},
+		{Name: "Second", Attempt: 2, Expected: 2 * time.Second},
+		{Name: "Fourth", Attempt: 4, Expected: 8 * time.Second},
+		{Name: "Capped", Attempt: 100, Expected: time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.Expected, outbox.Backoff(tc.Attempt, time.Second, time.Minute))
+		})
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var (
+		errUnavailable = errors.New("apns is unavailable")
+		unavailable    = &apns.Result{StatusCode: http.StatusServiceUnavailable, Reason: "ServiceUnavailable"}
+		badTopic       = &apns.Result{StatusCode: http.StatusBadRequest, Reason: "BadTopic"}
+		badDeviceToken = &apns.Result{StatusCode: http.StatusBadRequest, Reason: apns.ReasonBadDeviceToken}
+		unregistered   = &apns.Result{StatusCode: http.StatusGone, Reason: apns.ReasonUnregistered, Timestamp: time.Now().Add(time.Hour)}
+		reregistered   = &apns.Result{StatusCode: http.StatusGone, Reason: apns.ReasonUnregistered, Timestamp: time.Now().Add(-time.Hour)}
+	)
+
+	testCases := []struct {
+		Name               string
+		PassTypeID         string
+		Failures           int
+		Err                error
+		Result             *apns.Result
+		ExpectedStatus     api.PushStatus
+		ExpectedAttempts   int
+		ExpectedRegistered bool
+	}{
+		{
+			Name:               "Delivered",
+			PassTypeID:         "pass.com.example.coupon",
+			ExpectedStatus:     api.PushDelivered,
+			ExpectedAttempts:   1,
+			ExpectedRegistered: true,
+		},
+		{
+			Name:               "DeliveredAfterRetry",
+			PassTypeID:         "pass.com.example.coupon",
+			Failures:           2,
+			Err:                errUnavailable,
+			ExpectedStatus:     api.PushDelivered,
+			ExpectedAttempts:   3,
+			ExpectedRegistered: true,
+		},
+		{
+			Name:               "DeliveredAfterServiceUnavailable",
+			PassTypeID:         "pass.com.example.coupon",
+			Failures:           1,
+			Result:             unavailable,
+			ExpectedStatus:     api.PushDelivered,
+			ExpectedAttempts:   2,
+			ExpectedRegistered: true,
+		},
+		{
+			Name:               "DeadLetter",
+			PassTypeID:         "pass.com.example.coupon",
+			Failures:           10,
+			Err:                errUnavailable,
+			ExpectedStatus:     api.PushDead,
+			ExpectedAttempts:   3,
+			ExpectedRegistered: true,
+		},
+		{
+			Name:               "Rejected",
+			PassTypeID:         "pass.com.example.coupon",
+			Failures:           1,
+			Result:             badTopic,
+			ExpectedStatus:     api.PushDead,
+			ExpectedAttempts:   1,
+			ExpectedRegistered: true,
+		},
+		{
+			Name:             "BadDeviceToken",
+			PassTypeID:       "pass.com.example.coupon",
+			Failures:         1,
+			Result:           badDeviceToken,
+			ExpectedStatus:   api.PushDead,
+			ExpectedAttempts: 1,
+		},
+		{
+			Name:             "Unregistered",
+			PassTypeID:       "pass.com.example.coupon",
+			Failures:         1,
+			Result:           unregistered,
+			ExpectedStatus:   api.PushDead,
+			ExpectedAttempts: 1,
+		},
+		{
+			Name:               "RegisteredAfterUnregistered",
+			PassTypeID:         "pass.com.example.coupon",
+			Failures:           1,
+			Result:             reregistered,
+			ExpectedStatus:     api.PushDead,
+			ExpectedAttempts:   1,
+			ExpectedRegistered: true,
+		},
+		{
+			Name:               "UnsupportedNotificator",
+			PassTypeID:         "pass.com.example.generic",
+			ExpectedStatus:     api.PushDead,
+			ExpectedAttempts:   1,
+			ExpectedRegistered: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			assert := assert.New(t)
+
+			passkit := memory.New()
+			notificators := apns.NewRegistry()
+			notificators.Set("pass.com.example.coupon", &flakyNotificator{
+				failures: tc.Failures,
+				err:      tc.Err,
+				result:   tc.Result,
+			})
+
+			dispatcher := outbox.New(passkit, notificators, zap.NewNop(), outbox.Options{
+				Workers:      2,
+				MaxAttempts:  3,
+				PollInterval: time.Millisecond,
+				MinBackoff:   time.Millisecond,
+				MaxBackoff:   5 * time.Millisecond,
+			})
+
+			var (
+				deviceID     = uuid.NewV4().String()
+				pushToken    = uuid.NewV4().String()
+				serialNumber = uuid.NewV4().String()
+			)
+
+			err := passkit.InsertRegistration(ctx, deviceID, pushToken, serialNumber, tc.PassTypeID)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = passkit.InsertPush(ctx, api.NewPush(serialNumber, tc.PassTypeID, pushToken))
+			if !assert.NoError(err) {
+				return
+			}
+
+			done := make(chan struct{})
+			go func() {
+				dispatcher.Run(ctx)
+				close(done)
+			}()
+
+			var push *api.Push
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				pushes, err := passkit.LoadPushes(ctx, serialNumber)
+				if !assert.NoError(err) || !assert.Len(pushes, 1) {
+					return
+				}
+				push = pushes[0]
+				if push.Status == api.PushDelivered || push.Status == api.PushDead {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			cancel()
+			<-done
+
+			assert.Equal(tc.ExpectedStatus, push.Status)
+			assert.Equal(tc.ExpectedAttempts, push.Attempts)
+			if tc.ExpectedStatus == api.PushDead {
+				assert.NotEmpty(push.LastError)
+			} else {
+				assert.Empty(push.LastError)
+			}
+
+			registered, err := passkit.FindRegistration(context.Background(), deviceID, serialNumber)
+			assert.NoError(err)
+			assert.Equal(tc.ExpectedRegistered, registered)
+		})
+	}
+}
+
+func TestDispatcherRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert := assert.New(t)
+
+	const (
+		rate   = 20
+		pushes = 5
+	)
+
+	passkit := memory.New()
+	notificators := apns.NewRegistry()
+	notificators.Set("pass.com.example.coupon", &flakyNotificator{})
+
+	dispatcher := outbox.New(passkit, notificators, zap.NewNop(), outbox.Options{
+		Workers:      pushes,
+		PollInterval: time.Millisecond,
+		Rate:         rate,
+	})
+
+	serialNumber := uuid.NewV4().String()
+	for i := 0; i < pushes; i++ {
+		err := passkit.InsertPush(ctx, api.NewPush(serialNumber, "pass.com.example.coupon", uuid.NewV4().String()))
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+
+	delivered := 0
+	deadline := start.Add(5 * time.Second)
+	for delivered < pushes && time.Now().Before(deadline) {
+		loaded, err := passkit.LoadPushes(ctx, serialNumber)
+		if !assert.NoError(err) {
+			return
+		}
+		delivered = 0
+		for _, push := range loaded {
+			if push.Status == api.PushDelivered {
+				delivered++
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	elapsed := time.Since(start)
+
+	cancel()
+	<-done
+
+	assert.Equal(pushes, delivered)
+	assert.True(elapsed >= (pushes-1)*time.Second/rate, "elapsed %s", elapsed)
+}
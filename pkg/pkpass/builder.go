@@ -0,0 +1,99 @@
+package pkpass
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrDuplicateFile returned when file with the same name is added twice.
+	ErrDuplicateFile = errors.New("pkpass: duplicate file")
+	// ErrReservedFilename returned when file name is reserved for manifest or signature.
+	ErrReservedFilename = errors.New("pkpass: reserved filename")
+)
+
+// NewBuilder returns a new instance of `Builder` writing package into w.
+func NewBuilder(w io.Writer, signer Signer) *Builder {
+	return &Builder{
+		signer:   signer,
+		zip:      zip.NewWriter(w),
+		manifest: Manifest{},
+	}
+}
+
+// Builder streams pass package into writer. Every file is hashed
+// while it is written into archive, so content is never buffered.
+type Builder struct {
+	signer   Signer
+	zip      *zip.Writer
+	manifest Manifest
+}
+
+// Add writes content of reader into archive under given name.
+func (b *Builder) Add(name string, r io.Reader) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if name == ManifestFilename || name == SignatureFilename {
+		return ErrReservedFilename
+	}
+	if _, ok := b.manifest[name]; ok {
+		return ErrDuplicateFile
+	}
+
+	zipFile, err := b.zip.Create(name)
+	if err != nil {
+		return err
+	}
+
+	h := sha1.New()
+	_, err = io.Copy(io.MultiWriter(zipFile, h), r)
+	if err != nil {
+		return err
+	}
+
+	b.manifest[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// AddFile writes file into archive.
+func (b *Builder) AddFile(file File) error {
+	return b.Add(file.Name, bytes.NewReader(file.Data))
+}
+
+// Close signs manifest, writes it along with signature and finishes archive.
+// Underlying writer is not closed.
+func (b *Builder) Close() error {
+	if len(b.manifest) == 0 {
+		return ErrEmptyFolder
+	}
+
+	data, err := json.Marshal(b.manifest)
+	if err != nil {
+		return err
+	}
+
+	signature, err := b.signer.Sign(data)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range []File{NewFile(ManifestFilename, data), *signature} {
+		zipFile, err := b.zip.Create(file.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = zipFile.Write(file.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.zip.Close()
+}
@@ -0,0 +1,191 @@
+package pkpass_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	files := []pkpass.File{
+		pkpass.NewFile(pkpass.PassFilename, []byte(`{"formatVersion":1}`)),
+		pkpass.NewFile(pkpass.IconFilename, []byte("icon")),
+		pkpass.NewFile("en"+pkpass.LocalizationExtension+"/"+pkpass.StringsFilename, []byte(`"offer" = "Offer";`)),
+	}
+
+	buf := new(bytes.Buffer)
+	builder := pkpass.NewBuilder(buf, &fakeSigner{"signed"})
+	for _, file := range files {
+		err := builder.AddFile(file)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	err := builder.Close()
+	if !assert.NoError(err) {
+		return
+	}
+
+	unzipped, err := pkpass.Unzip(buf.Bytes())
+	if !assert.NoError(err) {
+		return
+	}
+
+	expected, err := pkpass.CreateManifest(files...)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var actual, manifest pkpass.Manifest
+	err = json.Unmarshal(expected.Data, &manifest)
+	if !assert.NoError(err) {
+		return
+	}
+
+	contents := map[string][]byte{}
+	for _, file := range unzipped {
+		contents[file.Name] = file.Data
+	}
+
+	err = json.Unmarshal(contents[pkpass.ManifestFilename], &actual)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(manifest, actual)
+	assert.Equal("signed", string(contents[pkpass.SignatureFilename]))
+	for _, file := range files {
+		assert.Equal(file.Data, contents[file.Name])
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Files    []pkpass.File
+		Expected error
+	}{
+		{
+			Name:     "Empty",
+			Expected: pkpass.ErrEmptyFolder,
+		},
+		{
+			Name: "Duplicate",
+			Files: []pkpass.File{
+				pkpass.NewFile(pkpass.IconFilename, []byte("icon")),
+				pkpass.NewFile(pkpass.IconFilename, []byte("icon")),
+			},
+			Expected: pkpass.ErrDuplicateFile,
+		},
+		{
+			Name: "Manifest",
+			Files: []pkpass.File{
+				pkpass.NewFile(pkpass.ManifestFilename, []byte("{}")),
+			},
+			Expected: pkpass.ErrReservedFilename,
+		},
+		{
+			Name: "InvalidName",
+			Files: []pkpass.File{
+				pkpass.NewFile("../icon.png", []byte("icon")),
+			},
+			Expected: pkpass.ErrInvalidFilename,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			builder := pkpass.NewBuilder(ioutil.Discard, &fakeSigner{"signed"})
+
+			var err error
+			for _, file := range tc.Files {
+				if err = builder.AddFile(file); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = builder.Close()
+			}
+
+			assert.Equal(t, tc.Expected, err)
+		})
+	}
+}
+
+func benchmarkImages(b *testing.B) []pkpass.File {
+	files := []pkpass.File{pkpass.NewFile(pkpass.PassFilename, []byte(`{"formatVersion":1}`))}
+	for _, name := range []string{
+		pkpass.BackgroundFilename2x,
+		pkpass.StripFilename2x,
+		pkpass.StripFilename3x,
+		pkpass.IconFilename3x,
+	} {
+		data := make([]byte, 2<<20)
+		if _, err := io.ReadFull(rand.Reader, data); err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, pkpass.NewFile(name, data))
+	}
+	return files
+}
+
+func BenchmarkZip(b *testing.B) {
+	files := benchmarkImages(b)
+	signer := &fakeSigner{"signed"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		manifest, err := pkpass.CreateManifest(files...)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		signature, err := signer.Sign(manifest.Data)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		data, err := pkpass.Zip(append(files, *manifest, *signature)...)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_, err = ioutil.Discard.Write(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	files := benchmarkImages(b)
+	signer := &fakeSigner{"signed"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		builder := pkpass.NewBuilder(ioutil.Discard, signer)
+		for _, file := range files {
+			err := builder.AddFile(file)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		err := builder.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
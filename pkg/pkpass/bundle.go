@@ -0,0 +1,40 @@
+package pkpass
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrTooManyPasses returned when bundle exceeds `MaxBundlePasses`.
+	ErrTooManyPasses = fmt.Errorf("pkpass: bundle can contain at most %d passes", MaxBundlePasses)
+	// ErrDuplicatePass returned when bundle contains the same pass twice.
+	ErrDuplicatePass = errors.New("pkpass: duplicate pass in bundle")
+	// ErrInvalidPass returned when bundle entry is not a signed pass.
+	ErrInvalidPass = errors.New("pkpass: invalid pass in bundle")
+)
+
+// Bundle archives signed passes into `.pkpasses` package.
+// Every file must be a top-level `.pkpass` archive.
+func Bundle(passes ...File) ([]byte, error) {
+	if len(passes) == 0 {
+		return nil, ErrEmptyFolder
+	}
+	if len(passes) > MaxBundlePasses {
+		return nil, ErrTooManyPasses
+	}
+
+	names := make(map[string]bool, len(passes))
+	for _, pass := range passes {
+		if strings.Contains(pass.Name, "/") || !strings.HasSuffix(pass.Name, Extension) || len(pass.Data) == 0 {
+			return nil, ErrInvalidPass
+		}
+		if names[pass.Name] {
+			return nil, ErrDuplicatePass
+		}
+		names[pass.Name] = true
+	}
+
+	return Zip(passes...)
+}
@@ -0,0 +1,89 @@
+package pkpass_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundle(t *testing.T) {
+	tooMany := make([]pkpass.File, pkpass.MaxBundlePasses+1)
+	for i := range tooMany {
+		tooMany[i] = pkpass.NewFile(fmt.Sprintf("%d%s", i, pkpass.Extension), []byte("pass"))
+	}
+
+	testCases := []struct {
+		Name     string
+		Passes   []pkpass.File
+		Expected error
+	}{
+		{
+			Name: "Valid",
+			Passes: []pkpass.File{
+				pkpass.NewFile("first.pkpass", []byte("first")),
+				pkpass.NewFile("second.pkpass", []byte("second")),
+			},
+		},
+		{
+			Name:     "Empty",
+			Expected: pkpass.ErrEmptyFolder,
+		},
+		{
+			Name:     "TooMany",
+			Passes:   tooMany,
+			Expected: pkpass.ErrTooManyPasses,
+		},
+		{
+			Name: "Duplicate",
+			Passes: []pkpass.File{
+				pkpass.NewFile("first.pkpass", []byte("first")),
+				pkpass.NewFile("first.pkpass", []byte("first")),
+			},
+			Expected: pkpass.ErrDuplicatePass,
+		},
+		{
+			Name: "NotPkpass",
+			Passes: []pkpass.File{
+				pkpass.NewFile("pass.json", []byte("{}")),
+			},
+			Expected: pkpass.ErrInvalidPass,
+		},
+		{
+			Name: "Nested",
+			Passes: []pkpass.File{
+				pkpass.NewFile("en.lproj/first.pkpass", []byte("first")),
+			},
+			Expected: pkpass.ErrInvalidPass,
+		},
+		{
+			Name: "EmptyPass",
+			Passes: []pkpass.File{
+				pkpass.NewFile("first.pkpass", nil),
+			},
+			Expected: pkpass.ErrInvalidPass,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			data, err := pkpass.Bundle(tc.Passes...)
+			if tc.Expected != nil {
+				assert.Equal(tc.Expected, err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			files, err := pkpass.Unzip(data)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Passes, files)
+		})
+	}
+}
@@ -30,6 +30,9 @@ func CreateManifest(files ...File) (*File, error) {
 
 	manifest := make(Manifest)
 	for _, file := range files {
+		if err := checkName(file.Name); err != nil {
+			return nil, err
+		}
 		hash, err := HashFile(file.Data)
 		if err != nil {
 			return nil, err
@@ -145,3 +145,36 @@ func TestManifest(t *testing.T) {
 		})
 	}
 }
+
+func TestManifestNestedPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	files := []pkpass.File{
+		pkpass.NewFile(pkpass.PassFilename, []byte(`{}`)),
+		pkpass.NewFile("en.lproj/pass.strings", []byte(`"offer" = "Offer";`)),
+		pkpass.NewFile("en.lproj/logo.png", []byte("logo")),
+	}
+
+	manifest, err := pkpass.CreateManifest(files...)
+	if !assert.NoError(err) {
+		return
+	}
+
+	loadedManifest := pkpass.Manifest{}
+	err = json.Unmarshal(manifest.Data, &loadedManifest)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Len(loadedManifest, len(files))
+	for _, file := range files {
+		hash, err := pkpass.HashFile(file.Data)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(hash, loadedManifest[file.Name])
+	}
+
+	_, err = pkpass.CreateManifest(pkpass.NewFile("en.lproj/../pass.json", nil))
+	assert.Equal(pkpass.ErrInvalidFilename, err)
+}
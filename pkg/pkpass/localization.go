@@ -0,0 +1,238 @@
+package pkpass
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+var (
+	// ErrInvalidFilename returned when file name cannot be used inside bundle.
+	ErrInvalidFilename = errors.New("pkpass: invalid file name")
+	// ErrInvalidLanguage returned when language cannot be used as folder name.
+	ErrInvalidLanguage = errors.New("pkpass: invalid language")
+	// ErrInvalidStrings returned when `.strings` file cannot be parsed.
+	ErrInvalidStrings = errors.New("pkpass: invalid strings file")
+)
+
+// checkName validates relative slash separated path of bundle file.
+func checkName(name string) error {
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return ErrInvalidFilename
+	}
+	if path.Clean(name) != name {
+		return ErrInvalidFilename
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return ErrInvalidFilename
+		}
+	}
+	return nil
+}
+
+// LocalizedName returns path of file inside language folder,
+// e.g. `en.lproj/logo.png`.
+func LocalizedName(lang, name string) (string, error) {
+	if lang == "" || strings.ContainsAny(lang, "/\\.") {
+		return "", ErrInvalidLanguage
+	}
+	if strings.Contains(name, "/") {
+		return "", ErrInvalidFilename
+	}
+	localized := lang + LocalizationExtension + "/" + name
+	if err := checkName(localized); err != nil {
+		return "", err
+	}
+	return localized, nil
+}
+
+// NewStrings encodes translations as `pass.strings` of language folder.
+func NewStrings(lang string, translations map[string]string) (*File, error) {
+	name, err := LocalizedName(lang, StringsFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(translations))
+	for key := range translations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	for _, key := range keys {
+		buf.WriteString(quoteString(key))
+		buf.WriteString(" = ")
+		buf.WriteString(quoteString(translations[key]))
+		buf.WriteString(";\n")
+	}
+
+	return &File{
+		Name: name,
+		Data: buf.Bytes(),
+	}, nil
+}
+
+var stringsReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+func quoteString(s string) string {
+	return `"` + stringsReplacer.Replace(s) + `"`
+}
+
+// ParseStrings decodes translations of `pass.strings` file.
+// Content is expected in UTF-8 or UTF-16 with byte order mark.
+func ParseStrings(data []byte) (map[string]string, error) {
+	p := &stringsParser{src: decodeStrings(data)}
+	translations := map[string]string{}
+
+	for {
+		p.skip()
+		if p.eof() {
+			return translations, nil
+		}
+
+		key, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect('='); err != nil {
+			return nil, err
+		}
+		value, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(';'); err != nil {
+			return nil, err
+		}
+
+		translations[key] = value
+	}
+}
+
+func decodeStrings(data []byte) []rune {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return []rune(string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})))
+	}
+
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return utf16.Decode(units)
+}
+
+type stringsParser struct {
+	src []rune
+	pos int
+}
+
+func (p *stringsParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *stringsParser) hasPrefix(prefix string) bool {
+	end := p.pos + len(prefix)
+	if end > len(p.src) {
+		end = len(p.src)
+	}
+	return string(p.src[p.pos:end]) == prefix
+}
+
+// skip moves position over whitespaces and comments.
+func (p *stringsParser) skip() {
+	for !p.eof() {
+		switch {
+		case unicode.IsSpace(p.src[p.pos]):
+			p.pos++
+		case p.hasPrefix("//"):
+			for !p.eof() && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case p.hasPrefix("/*"):
+			p.pos += 2
+			for !p.eof() && !p.hasPrefix("*/") {
+				p.pos++
+			}
+			p.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+func (p *stringsParser) expect(r rune) error {
+	p.skip()
+	if p.eof() || p.src[p.pos] != r {
+		return ErrInvalidStrings
+	}
+	p.pos++
+	return nil
+}
+
+func (p *stringsParser) quoted() (string, error) {
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	for !p.eof() {
+		r := p.src[p.pos]
+		p.pos++
+
+		switch r {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", ErrInvalidStrings
+			}
+			esc := p.src[p.pos]
+			p.pos++
+			switch esc {
+			case 'n':
+				buf.WriteRune('\n')
+			case 'r':
+				buf.WriteRune('\r')
+			case 't':
+				buf.WriteRune('\t')
+			case 'u', 'U':
+				if p.pos+4 > len(p.src) {
+					return "", ErrInvalidStrings
+				}
+				code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+4]), 16, 16)
+				if err != nil {
+					return "", ErrInvalidStrings
+				}
+				buf.WriteRune(rune(code))
+				p.pos += 4
+			default:
+				buf.WriteRune(esc)
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+
+	return "", ErrInvalidStrings
+}
@@ -0,0 +1,212 @@
+package pkpass_test
+
+import (
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalizedName(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Lang     string
+		File     string
+		Expected string
+		Err      error
+	}{
+		{
+			Name:     "Strings",
+			Lang:     "en",
+			File:     pkpass.StringsFilename,
+			Expected: "en.lproj/pass.strings",
+		},
+		{
+			Name:     "Image",
+			Lang:     "zh-Hans",
+			File:     pkpass.LogoFilename2x,
+			Expected: "zh-Hans.lproj/logo@2x.png",
+		},
+		{
+			Name: "EmptyLanguage",
+			File: pkpass.LogoFilename,
+			Err:  pkpass.ErrInvalidLanguage,
+		},
+		{
+			Name: "LanguageWithPath",
+			Lang: "../en",
+			File: pkpass.LogoFilename,
+			Err:  pkpass.ErrInvalidLanguage,
+		},
+		{
+			Name: "NestedFile",
+			Lang: "en",
+			File: "images/logo.png",
+			Err:  pkpass.ErrInvalidFilename,
+		},
+		{
+			Name: "ParentFile",
+			Lang: "en",
+			File: "..",
+			Err:  pkpass.ErrInvalidFilename,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			name, err := pkpass.LocalizedName(tc.Lang, tc.File)
+			if tc.Err != nil {
+				assert.Equal(tc.Err, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Expected, name)
+		})
+	}
+}
+
+func TestNewStrings(t *testing.T) {
+	testCases := []struct {
+		Name         string
+		Lang         string
+		Translations map[string]string
+		Expected     string
+	}{
+		{
+			Name:     "Empty",
+			Lang:     "en",
+			Expected: "",
+		},
+		{
+			Name: "Sorted",
+			Lang: "fr",
+			Translations: map[string]string{
+				"offer":   "Réduction de 20%",
+				"expires": "Expire le",
+			},
+			Expected: "\"expires\" = \"Expire le\";\n\"offer\" = \"Réduction de 20%\";\n",
+		},
+		{
+			Name: "Escaped",
+			Lang: "en",
+			Translations: map[string]string{
+				"terms": "Say \"hi\"\nC:\\",
+			},
+			Expected: "\"terms\" = \"Say \\\"hi\\\"\\nC:\\\\\";\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			file, err := pkpass.NewStrings(tc.Lang, tc.Translations)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.Lang+pkpass.LocalizationExtension+"/"+pkpass.StringsFilename, file.Name)
+			assert.Equal(tc.Expected, string(file.Data))
+		})
+	}
+}
+
+func TestParseStrings(t *testing.T) {
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range `"gate" = "Выход";` {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+
+	testCases := []struct {
+		Name     string
+		Data     []byte
+		Expected map[string]string
+		Err      error
+	}{
+		{
+			Name: "Simple",
+			Data: []byte("\"offer\" = \"20% off\";\n\"gate\"=\"Gate\";"),
+			Expected: map[string]string{
+				"offer": "20% off",
+				"gate":  "Gate",
+			},
+		},
+		{
+			Name: "Comments",
+			Data: []byte("/* Primary field */\n\"offer\" = \"Offer\"; // label\n"),
+			Expected: map[string]string{
+				"offer": "Offer",
+			},
+		},
+		{
+			Name: "Escapes",
+			Data: []byte(`"quote" = "Say \"hi\"\né";`),
+			Expected: map[string]string{
+				"quote": "Say \"hi\"\né",
+			},
+		},
+		{
+			Name: "UTF16",
+			Data: utf16,
+			Expected: map[string]string{
+				"gate": "Выход",
+			},
+		},
+		{
+			Name:     "Empty",
+			Data:     []byte(" \n"),
+			Expected: map[string]string{},
+		},
+		{
+			Name: "MissingSemicolon",
+			Data: []byte(`"offer" = "Offer"`),
+			Err:  pkpass.ErrInvalidStrings,
+		},
+		{
+			Name: "UnterminatedString",
+			Data: []byte(`"offer" = "Offer;`),
+			Err:  pkpass.ErrInvalidStrings,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			translations, err := pkpass.ParseStrings(tc.Data)
+			if tc.Err != nil {
+				assert.Equal(tc.Err, err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Expected, translations)
+		})
+	}
+}
+
+func TestParseStringsRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	translations := map[string]string{
+		"offer":     "20% off",
+		"multiline": "first\nsecond\t\"quoted\" \\ slash",
+	}
+
+	file, err := pkpass.NewStrings("en", translations)
+	if !assert.NoError(err) {
+		return
+	}
+
+	parsed, err := pkpass.ParseStrings(file.Data)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(translations, parsed)
+}
@@ -4,6 +4,12 @@ const (
 	// Extension alias for pkpass extension.
 	Extension = ".pkpass"
 
+	// BundleExtension alias for multi-pass bundle extension.
+	BundleExtension = ".pkpasses"
+
+	// MaxBundlePasses is a maximum number of passes Wallet accepts in a bundle.
+	MaxBundlePasses = 10
+
 	// PassFilename is an alias for `pass.json`.
 	PassFilename = "pass.json"
 
@@ -13,6 +19,12 @@ const (
 	// SignatureFilename is an alias for `signature`.
 	SignatureFilename = "signature"
 
+	// StringsFilename is an alias for `pass.strings`.
+	StringsFilename = "pass.strings"
+
+	// LocalizationExtension is an alias for language folder extension.
+	LocalizationExtension = ".lproj"
+
 	// BackgroundFilename is an alias for ``.
 	BackgroundFilename = "background.png"
 	// BackgroundFilename2x is an alias for ``.
@@ -47,4 +59,11 @@ const (
 	StripFilename2x = "strip@2x.png"
 	// StripFilename3x is an alias for ``.
 	StripFilename3x = "strip@3x.png"
+
+	// ThumbnailFilename is an alias for ``.
+	ThumbnailFilename = "thumbnail.png"
+	// ThumbnailFilename2x is an alias for ``.
+	ThumbnailFilename2x = "thumbnail@2x.png"
+	// ThumbnailFilename3x is an alias for ``.
+	ThumbnailFilename3x = "thumbnail@3x.png"
 )
@@ -0,0 +1,38 @@
+package pkpass
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrUnsupportedSigner returned when there is no signer for pass type identifier.
+var ErrUnsupportedSigner = errors.New("pkpass: unsupported signer")
+
+// NewRegistry returns a new empty instance of `Registry`.
+func NewRegistry() *Registry {
+	return &Registry{signers: map[string]Signer{}}
+}
+
+// Registry holds signers keyed by pass type identifier.
+type Registry struct {
+	mu      sync.Mutex
+	signers map[string]Signer
+}
+
+// Set registers signer for pass type identifier.
+func (r *Registry) Set(passTypeID string, signer Signer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.signers[passTypeID] = signer
+}
+
+// Get returns signer registered for pass type identifier.
+func (r *Registry) Get(passTypeID string) (Signer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	signer, ok := r.signers[passTypeID]
+	if !ok {
+		return nil, ErrUnsupportedSigner
+	}
+	return signer, nil
+}
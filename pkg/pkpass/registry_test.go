@@ -0,0 +1,62 @@
+package pkpass_test
+
+import (
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSigner struct{ name string }
+
+func (s *fakeSigner) Sign(data []byte) (*pkpass.File, error) {
+	return &pkpass.File{Name: pkpass.SignatureFilename, Data: []byte(s.name)}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		PassTypeID string
+		Register   bool
+		Expected   error
+	}{
+		{
+			Name:       "Registered",
+			PassTypeID: "pass.com.example.coupon",
+			Register:   true,
+		},
+		{
+			Name:       "Unsupported",
+			PassTypeID: "pass.com.example.generic",
+			Expected:   pkpass.ErrUnsupportedSigner,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			registry := pkpass.NewRegistry()
+			if tc.Register {
+				registry.Set(tc.PassTypeID, &fakeSigner{tc.PassTypeID})
+			}
+
+			signer, err := registry.Get(tc.PassTypeID)
+			if tc.Expected != nil {
+				assert.Equal(tc.Expected, err)
+				assert.Nil(signer)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+
+			file, err := signer.Sign(nil)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.PassTypeID, string(file.Data))
+		})
+	}
+}
@@ -12,6 +12,14 @@ import (
 // ErrInvalidRootCert returned when pem block is nil.
 var ErrInvalidRootCert = errors.New("pkpass: invalid root certificate")
 
+func parseRootCert(root []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(root)
+	if block == nil {
+		return nil, ErrInvalidRootCert
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
 // Signer holds method working with certificates.
 type Signer interface {
 	Sign(data []byte) (*File, error)
@@ -19,12 +27,7 @@ type Signer interface {
 
 // NewSigner returns a new instance of pkcs7 signer.
 func NewSigner(root, cert []byte, pass string) (Signer, error) {
-	block, _ := pem.Decode(root)
-	if block == nil {
-		return nil, ErrInvalidRootCert
-	}
-
-	rootCert, err := x509.ParseCertificate(block.Bytes)
+	rootCert, err := parseRootCert(root)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,252 @@
+package pkpass
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/fullsailor/pkcs7"
+)
+
+var (
+	oidUserID      = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+	oidSigningTime = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+)
+
+// Verifier holds method inspecting signed pass packages.
+type Verifier interface {
+	Verify(data []byte) (*Report, error)
+}
+
+// NewVerifier returns a new instance of pkcs7 verifier.
+// Signer certificate chain is checked against root if it is given.
+func NewVerifier(root []byte) (Verifier, error) {
+	if len(root) == 0 {
+		return &verifier{}, nil
+	}
+
+	rootCert, err := parseRootCert(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifier{RootCert: rootCert}, nil
+}
+
+// Report holds result of pass package verification.
+type Report struct {
+	Valid      bool           `json:"valid"`
+	Files      []string       `json:"files"`
+	Missing    []string       `json:"missing,omitempty"`
+	Unlisted   []string       `json:"unlisted,omitempty"`
+	Mismatched []string       `json:"mismatched,omitempty"`
+	Signature  *SignatureInfo `json:"signature,omitempty"`
+	Pass       *api.PassCard  `json:"pass,omitempty"`
+	Errors     []string       `json:"errors,omitempty"`
+}
+
+func (r *Report) addError(format string, args ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// SignatureInfo holds details of package signature and its signer.
+type SignatureInfo struct {
+	Verified    bool      `json:"verified"`
+	Trusted     bool      `json:"trusted"`
+	Subject     string    `json:"subject,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	PassTypeID  string    `json:"passTypeIdentifier,omitempty"`
+	TeamID      string    `json:"teamIdentifier,omitempty"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	SigningTime time.Time `json:"signingTime"`
+}
+
+type verifier struct {
+	RootCert *x509.Certificate
+}
+
+func (v *verifier) Verify(data []byte) (*Report, error) {
+	files, err := Unzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Files: []string{}}
+	contents := make(map[string][]byte, len(files))
+	for _, file := range files {
+		report.Files = append(report.Files, file.Name)
+		contents[file.Name] = file.Data
+	}
+	sort.Strings(report.Files)
+
+	manifestData, hasManifest := contents[ManifestFilename]
+	if hasManifest {
+		v.checkManifest(report, manifestData, contents)
+	} else {
+		report.addError("%s is missing", ManifestFilename)
+	}
+
+	signatureData, hasSignature := contents[SignatureFilename]
+	if !hasSignature {
+		report.addError("%s is missing", SignatureFilename)
+	}
+	if hasSignature && hasManifest {
+		v.checkSignature(report, signatureData, manifestData)
+	}
+
+	passData, hasPass := contents[PassFilename]
+	if hasPass {
+		v.checkPass(report, passData)
+	} else {
+		report.addError("%s is missing", PassFilename)
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+func (v *verifier) checkManifest(report *Report, data []byte, contents map[string][]byte) {
+	var manifest Manifest
+	err := json.Unmarshal(data, &manifest)
+	if err != nil {
+		report.addError("manifest: %v", err)
+		return
+	}
+
+	for name, expected := range manifest {
+		content, ok := contents[name]
+		if !ok {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		hash, err := HashFile(content)
+		if err != nil {
+			report.addError("manifest: %s: %v", name, err)
+			continue
+		}
+		if !strings.EqualFold(hash, expected) {
+			report.Mismatched = append(report.Mismatched, name)
+		}
+	}
+
+	for name := range contents {
+		if name == ManifestFilename || name == SignatureFilename {
+			continue
+		}
+		if _, ok := manifest[name]; !ok {
+			report.Unlisted = append(report.Unlisted, name)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unlisted)
+	sort.Strings(report.Mismatched)
+
+	if len(report.Missing) > 0 {
+		report.addError("manifest: files are missing: %s", strings.Join(report.Missing, ", "))
+	}
+	if len(report.Unlisted) > 0 {
+		report.addError("manifest: files are not listed: %s", strings.Join(report.Unlisted, ", "))
+	}
+	if len(report.Mismatched) > 0 {
+		report.addError("manifest: hashes do not match: %s", strings.Join(report.Mismatched, ", "))
+	}
+}
+
+func (v *verifier) checkSignature(report *Report, data, manifest []byte) {
+	p7, err := pkcs7.Parse(data)
+	if err != nil {
+		report.addError("signature: %v", err)
+		return
+	}
+	p7.Content = manifest
+
+	info := &SignatureInfo{}
+	report.Signature = info
+
+	err = p7.Verify()
+	if err != nil {
+		report.addError("signature: %v", err)
+	} else {
+		info.Verified = true
+	}
+
+	var signingTime time.Time
+	if err := p7.UnmarshalSignedAttribute(oidSigningTime, &signingTime); err == nil {
+		info.SigningTime = signingTime
+	}
+
+	cert := p7.GetOnlySigner()
+	if cert == nil {
+		report.addError("signature: expected exactly one signer")
+		return
+	}
+
+	info.Subject = cert.Subject.String()
+	info.Issuer = cert.Issuer.String()
+	info.NotBefore = cert.NotBefore
+	info.NotAfter = cert.NotAfter
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		info.TeamID = cert.Subject.OrganizationalUnit[0]
+	}
+	for _, name := range cert.Subject.Names {
+		if name.Type.Equal(oidUserID) {
+			info.PassTypeID, _ = name.Value.(string)
+		}
+	}
+
+	if v.RootCert == nil {
+		return
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(v.RootCert)
+
+	intermediates := x509.NewCertPool()
+	for _, c := range p7.Certificates {
+		if c != cert {
+			intermediates.AddCert(c)
+		}
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		report.addError("signature: %v", err)
+		return
+	}
+	info.Trusted = true
+}
+
+func (v *verifier) checkPass(report *Report, data []byte) {
+	var pass api.PassCard
+	err := json.Unmarshal(data, &pass)
+	if err != nil {
+		report.addError("pass: %v", err)
+		return
+	}
+	report.Pass = &pass
+
+	err = pass.IsValid()
+	if err != nil {
+		report.addError("pass: %v", err)
+	}
+
+	if info := report.Signature; info != nil {
+		if info.PassTypeID != "" && info.PassTypeID != pass.PassTypeID {
+			report.addError("pass: pass type identifier %q does not match certificate %q", pass.PassTypeID, info.PassTypeID)
+		}
+		if info.TeamID != "" && info.TeamID != pass.TeamID {
+			report.addError("pass: team identifier %q does not match certificate %q", pass.TeamID, info.TeamID)
+		}
+	}
+}
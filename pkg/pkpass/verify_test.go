@@ -0,0 +1,316 @@
+package pkpass_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/fullsailor/pkcs7"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPassTypeID = "pass.com.example.coupon"
+	testTeamID     = "A1B2C3D4E5"
+)
+
+type testAuthority struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	pem  []byte
+}
+
+func newTestAuthority(t *testing.T) *testAuthority {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test WWDR"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testAuthority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (a *testAuthority) sign(t *testing.T, data []byte) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			CommonName:         "Pass Type ID: " + testPassTypeID,
+			OrganizationalUnit: []string{testTeamID},
+			ExtraNames: []pkix.AttributeTypeAndValue{
+				{Type: asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}, Value: testPassTypeID},
+			},
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedData, err := pkcs7.NewSignedData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedData.AddCertificate(a.cert)
+
+	err = signedData.AddSigner(cert, key, pkcs7.SignerInfoConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedData.Detach()
+
+	signature, err := signedData.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signature
+}
+
+func testPass(t *testing.T, passTypeID string) []byte {
+	data, err := json.Marshal(&api.PassCard{
+		Description:      "Test Coupon",
+		FormatVersion:    1,
+		OrganizationName: "Okpock",
+		PassTypeID:       passTypeID,
+		SerialNumber:     "E5982H-I2",
+		TeamID:           testTeamID,
+		Coupon: &api.PassStructure{
+			PrimaryFields: []*api.Field{
+				&api.Field{Key: "offer", Label: "Any premium dog food", Value: "20% off"},
+			},
+		},
+		AuthenticationToken: "vxwxd7J8AlNNFPS8k0a0FfUFtq0ewzFdc",
+		WebServiceURL:       "https://okpock.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerify(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	testCases := []struct {
+		Name       string
+		Root       []byte
+		PassTypeID string
+		Tamper     func(files []pkpass.File) []pkpass.File
+		Valid      bool
+		Trusted    bool
+		Mismatched []string
+		Unlisted   []string
+		Missing    []string
+	}{
+		{
+			Name:       "Trusted",
+			Root:       authority.pem,
+			PassTypeID: testPassTypeID,
+			Valid:      true,
+			Trusted:    true,
+		},
+		{
+			Name:       "WithoutRoot",
+			PassTypeID: testPassTypeID,
+			Valid:      true,
+		},
+		{
+			Name:       "UntrustedRoot",
+			Root:       newTestAuthority(t).pem,
+			PassTypeID: testPassTypeID,
+		},
+		{
+			Name:       "TamperedFile",
+			Root:       authority.pem,
+			PassTypeID: testPassTypeID,
+			Tamper: func(files []pkpass.File) []pkpass.File {
+				files[1].Data = []byte("changed")
+				return files
+			},
+			Trusted:    true,
+			Mismatched: []string{pkpass.IconFilename},
+		},
+		{
+			Name:       "UnlistedFile",
+			Root:       authority.pem,
+			PassTypeID: testPassTypeID,
+			Tamper: func(files []pkpass.File) []pkpass.File {
+				return append(files, pkpass.NewFile(pkpass.LogoFilename, []byte("logo")))
+			},
+			Trusted:  true,
+			Unlisted: []string{pkpass.LogoFilename},
+		},
+		{
+			Name:       "MissingFile",
+			Root:       authority.pem,
+			PassTypeID: testPassTypeID,
+			Tamper: func(files []pkpass.File) []pkpass.File {
+				return append(files[:1], files[2:]...)
+			},
+			Trusted: true,
+			Missing: []string{pkpass.IconFilename},
+		},
+		{
+			Name:       "WrongPassTypeID",
+			Root:       authority.pem,
+			PassTypeID: "pass.com.example.generic",
+			Trusted:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			files := []pkpass.File{
+				pkpass.NewFile(pkpass.PassFilename, testPass(t, tc.PassTypeID)),
+				pkpass.NewFile(pkpass.IconFilename, []byte("icon")),
+			}
+
+			manifest, err := pkpass.CreateManifest(files...)
+			if !assert.NoError(err) {
+				return
+			}
+
+			if tc.Tamper != nil {
+				files = tc.Tamper(files)
+			}
+			files = append(files, *manifest, pkpass.NewFile(pkpass.SignatureFilename, authority.sign(t, manifest.Data)))
+
+			data, err := pkpass.Zip(files...)
+			if !assert.NoError(err) {
+				return
+			}
+
+			verifier, err := pkpass.NewVerifier(tc.Root)
+			if !assert.NoError(err) {
+				return
+			}
+
+			report, err := verifier.Verify(data)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.Valid, report.Valid, report.Errors)
+			assert.Equal(tc.Valid, len(report.Errors) == 0)
+			assert.Equal(tc.Mismatched, report.Mismatched)
+			assert.Equal(tc.Unlisted, report.Unlisted)
+			assert.Equal(tc.Missing, report.Missing)
+
+			if assert.NotNil(report.Signature) {
+				assert.True(report.Signature.Verified)
+				assert.Equal(tc.Trusted, report.Signature.Trusted)
+				assert.Equal(testPassTypeID, report.Signature.PassTypeID)
+				assert.Equal(testTeamID, report.Signature.TeamID)
+			}
+
+			if assert.NotNil(report.Pass) {
+				assert.Equal(tc.PassTypeID, report.Pass.PassTypeID)
+			}
+		})
+	}
+}
+
+func TestVerifyTestdata(t *testing.T) {
+	assert := assert.New(t)
+
+	paths, err := filepath.Glob("testdata/coupon.pass/*")
+	if !assert.NoError(err) {
+		return
+	}
+
+	files := make([]pkpass.File, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if !assert.NoError(err) {
+			return
+		}
+		files = append(files, pkpass.NewFile(filepath.Base(path), data))
+	}
+
+	data, err := pkpass.Zip(files...)
+	if !assert.NoError(err) {
+		return
+	}
+
+	verifier, err := pkpass.NewVerifier(nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	report, err := verifier.Verify(data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Empty(report.Missing)
+	assert.Empty(report.Unlisted)
+	assert.Empty(report.Mismatched)
+	if assert.NotNil(report.Signature) {
+		assert.True(report.Signature.Verified)
+		assert.False(report.Signature.Trusted)
+	}
+	if assert.NotNil(report.Pass) {
+		assert.Equal("pass.com.okpock.coupon", report.Pass.PassTypeID)
+	}
+}
+
+func TestVerifyCorrupted(t *testing.T) {
+	verifier, err := pkpass.NewVerifier(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = verifier.Verify([]byte("not a zip"))
+	assert.Error(t, err)
+}
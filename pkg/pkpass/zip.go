@@ -4,11 +4,30 @@ import (
 	"archive/zip"
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 )
 
-// ErrEmptyFolder returned when there is no files to be zipped.
-var ErrEmptyFolder = errors.New("pkpass: no files given to be zipped")
+const (
+	// MaxUnzipFiles is a maximum number of entries `Unzip` accepts.
+	MaxUnzipFiles = 1000
+	// MaxUnzipFileSize is a maximum uncompressed size of single entry.
+	MaxUnzipFileSize = 10 << 20
+	// MaxUnzipSize is a maximum uncompressed size of all entries.
+	MaxUnzipSize = 32 << 20
+)
+
+var (
+	// ErrEmptyFolder returned when there is no files to be zipped.
+	ErrEmptyFolder = errors.New("pkpass: no files given to be zipped")
+	// ErrTooManyFiles returned when archive exceeds `MaxUnzipFiles`.
+	ErrTooManyFiles = fmt.Errorf("pkpass: archive can contain at most %d files", MaxUnzipFiles)
+	// ErrFileTooLarge returned when entry exceeds `MaxUnzipFileSize`.
+	ErrFileTooLarge = fmt.Errorf("pkpass: archive file can be at most %d bytes", MaxUnzipFileSize)
+	// ErrArchiveTooLarge returned when entries exceed `MaxUnzipSize` in total.
+	ErrArchiveTooLarge = fmt.Errorf("pkpass: archive can be at most %d bytes uncompressed", MaxUnzipSize)
+)
 
 // NewFile returns a new instance of `File`.
 func NewFile(name string, data []byte) File {
@@ -31,6 +50,10 @@ func Zip(files ...File) ([]byte, error) {
 	zipWriter := zip.NewWriter(buf)
 
 	for _, file := range files {
+		if err := checkName(file.Name); err != nil {
+			return nil, err
+		}
+
 		zipFile, err := zipWriter.Create(file.Name)
 		if err != nil {
 			return nil, err
@@ -50,7 +73,8 @@ func Zip(files ...File) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// Unzip opens zip package.
+// Unzip opens zip package. Archives with more than `MaxUnzipFiles` entries
+// or larger than `MaxUnzipSize` when uncompressed are rejected.
 func Unzip(data []byte) ([]File, error) {
 	if len(data) == 0 {
 		return nil, ErrEmptyFolder
@@ -64,24 +88,59 @@ func Unzip(data []byte) ([]File, error) {
 		return nil, err
 	}
 
-	files := make([]File, len(zipReader.File))
-	for i, zipFile := range zipReader.File {
-		r, err := zipFile.Open()
-		if err != nil {
-			return nil, err
+	if len(zipReader.File) > MaxUnzipFiles {
+		return nil, ErrTooManyFiles
+	}
+
+	var total int64
+	files := make([]File, 0, len(zipReader.File))
+	for _, zipFile := range zipReader.File {
+		if zipFile.FileInfo().IsDir() {
+			continue
 		}
-		defer r.Close()
 
-		data, err := ioutil.ReadAll(r)
+		if zipFile.UncompressedSize64 > MaxUnzipFileSize {
+			return nil, ErrFileTooLarge
+		}
+		if total+int64(zipFile.UncompressedSize64) > MaxUnzipSize {
+			return nil, ErrArchiveTooLarge
+		}
+
+		data, err := readZipFile(zipFile)
 		if err != nil {
 			return nil, err
 		}
 
-		files[i] = File{
+		total += int64(len(data))
+		if total > MaxUnzipSize {
+			return nil, ErrArchiveTooLarge
+		}
+
+		files = append(files, File{
 			Name: zipFile.Name,
 			Data: data,
-		}
+		})
 	}
 
 	return files, nil
 }
+
+// readZipFile reads entry content. Declared size can't be trusted
+// so reading stops right after `MaxUnzipFileSize`.
+func readZipFile(zipFile *zip.File) ([]byte, error) {
+	r, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxUnzipFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUnzipFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	return data, nil
+}
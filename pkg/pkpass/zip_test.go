@@ -1,6 +1,7 @@
 package pkpass_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -69,3 +70,105 @@ func TestZip(t *testing.T) {
 		})
 	}
 }
+
+func TestZipNestedPaths(t *testing.T) {
+	testCases := []struct {
+		Name  string
+		Files []pkpass.File
+		Err   error
+	}{
+		{
+			Name: "Localized",
+			Files: []pkpass.File{
+				pkpass.NewFile(pkpass.PassFilename, []byte(`{}`)),
+				pkpass.NewFile("en.lproj/pass.strings", []byte(`"offer" = "Offer";`)),
+				pkpass.NewFile("en.lproj/logo.png", []byte("logo")),
+				pkpass.NewFile("ru.lproj/pass.strings", []byte(`"offer" = "Скидка";`)),
+			},
+		},
+		{
+			Name: "ParentFolder",
+			Files: []pkpass.File{
+				pkpass.NewFile("../pass.json", []byte(`{}`)),
+			},
+			Err: pkpass.ErrInvalidFilename,
+		},
+		{
+			Name: "AbsolutePath",
+			Files: []pkpass.File{
+				pkpass.NewFile("/pass.json", []byte(`{}`)),
+			},
+			Err: pkpass.ErrInvalidFilename,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			zipContent, err := pkpass.Zip(tc.Files...)
+			if tc.Err != nil {
+				assert.Equal(tc.Err, err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			unzippedFiles, err := pkpass.Unzip(zipContent)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.Files, unzippedFiles)
+		})
+	}
+}
+
+func TestUnzipLimits(t *testing.T) {
+	manyFiles := make([]pkpass.File, pkpass.MaxUnzipFiles+1)
+	for i := range manyFiles {
+		manyFiles[i] = pkpass.NewFile(fmt.Sprintf("file%d.txt", i), []byte("x"))
+	}
+
+	largeFiles := make([]pkpass.File, pkpass.MaxUnzipSize/pkpass.MaxUnzipFileSize+1)
+	for i := range largeFiles {
+		largeFiles[i] = pkpass.NewFile(fmt.Sprintf("file%d.txt", i), make([]byte, pkpass.MaxUnzipFileSize))
+	}
+
+	testCases := []struct {
+		Name  string
+		Files []pkpass.File
+		Err   error
+	}{
+		{
+			Name:  "TooManyFiles",
+			Files: manyFiles,
+			Err:   pkpass.ErrTooManyFiles,
+		},
+		{
+			Name:  "FileTooLarge",
+			Files: []pkpass.File{pkpass.NewFile("file.txt", make([]byte, pkpass.MaxUnzipFileSize+1))},
+			Err:   pkpass.ErrFileTooLarge,
+		},
+		{
+			Name:  "ArchiveTooLarge",
+			Files: largeFiles,
+			Err:   pkpass.ErrArchiveTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			zipContent, err := pkpass.Zip(tc.Files...)
+			if !assert.NoError(err) {
+				return
+			}
+
+			_, err = pkpass.Unzip(zipContent)
+			assert.Equal(tc.Err, err)
+		})
+	}
+}
@@ -8,13 +8,7 @@ import (
 
 func (s *Service) passTypesHandler(w http.ResponseWriter, r *http.Request) error {
 	return sendJSON(w, http.StatusOK, M{
-		"data": []api.PassType{
-			api.BoardingPass,
-			api.Coupon,
-			api.EventTicket,
-			api.Generic,
-			api.StoreCard,
-		},
+		"data": api.PassTypes(),
 	})
 }
 
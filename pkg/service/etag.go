@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danikarik/okpock/pkg/api"
+)
+
+var (
+	// ErrIfMatchRequired raised when update request has no `If-Match` header.
+	ErrIfMatchRequired = errors.New("If-Match header is required")
+	// ErrPreconditionFailed raised when `If-Match` does not match current version.
+	ErrPreconditionFailed = errors.New("pass card is changed, reload it and try again")
+)
+
+// passCardETag returns strong entity tag of pass card version.
+func passCardETag(passcard *api.PassCardInfo) string {
+	return `"` + strconv.FormatInt(passcard.Version, 10) + `"`
+}
+
+func setPassCardETag(w http.ResponseWriter, passcard *api.PassCardInfo) {
+	w.Header().Set("ETag", passCardETag(passcard))
+}
+
+// checkIfMatch compares `If-Match` header with version of pass card.
+// Weak tags never match as strong comparison is required.
+func checkIfMatch(r *http.Request, passcard *api.PassCardInfo) error {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return ErrIfMatchRequired
+	}
+
+	if header == "*" {
+		return nil
+	}
+
+	etag := passCardETag(passcard)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return nil
+		}
+	}
+
+	return ErrPreconditionFailed
+}
+
+// ifMatchError writes response of failed `If-Match` check.
+func (s *Service) ifMatchError(w http.ResponseWriter, r *http.Request, err error) error {
+	if err == ErrIfMatchRequired {
+		return s.httpError(w, r, http.StatusPreconditionRequired, "CheckIfMatch", err)
+	}
+	return s.httpError(w, r, http.StatusPreconditionFailed, "CheckIfMatch", err)
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+)
+
+// interpolationFuncs is a restricted function set available in field values.
+var interpolationFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"title":   strings.Title,
+	"trim":    strings.TrimSpace,
+	"default": defaultValue,
+	"number":  formatNumber,
+	"date":    formatDate,
+}
+
+// interpolationBuiltins are allowed `text/template` builtins. Others like
+// `call` or `template` are rejected before execution.
+var interpolationBuiltins = map[string]bool{
+	"and":    true,
+	"or":     true,
+	"not":    true,
+	"eq":     true,
+	"ne":     true,
+	"lt":     true,
+	"le":     true,
+	"gt":     true,
+	"ge":     true,
+	"len":    true,
+	"index":  true,
+	"print":  true,
+	"printf": true,
+}
+
+func isInterpolated(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// interpolate evaluates expressions of s over data.
+func interpolate(s string, data map[string]interface{}) (string, error) {
+	if !isInterpolated(s) {
+		return s, nil
+	}
+
+	tmpl, err := template.New("value").
+		Funcs(interpolationFuncs).
+		Option("missingkey=error").
+		Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return "", errors.New("nested templates are not allowed")
+	}
+
+	err = checkInterpolationNode(tmpl.Tree.Root)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func checkInterpolationNode(node parse.Node) error {
+	switch n := node.(type) {
+	case nil, *parse.TextNode:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, node := range n.Nodes {
+			err := checkInterpolationNode(node)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.ActionNode:
+		return checkInterpolationPipe(n.Pipe)
+	case *parse.IfNode:
+		return checkInterpolationBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkInterpolationBranch(&n.BranchNode)
+	default:
+		return fmt.Errorf("%q is not allowed", node.String())
+	}
+}
+
+func checkInterpolationBranch(n *parse.BranchNode) error {
+	err := checkInterpolationPipe(n.Pipe)
+	if err != nil {
+		return err
+	}
+	err = checkInterpolationNode(n.List)
+	if err != nil {
+		return err
+	}
+	if n.ElseList != nil {
+		return checkInterpolationNode(n.ElseList)
+	}
+	return nil
+}
+
+// checkInterpolationPipe allows only restricted functions and plain values.
+// Field, variable or dot nodes with arguments or piped input are method
+// calls and are rejected.
+func checkInterpolationPipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for i, cmd := range pipe.Cmds {
+		if len(cmd.Args) == 0 {
+			continue
+		}
+		if _, ok := cmd.Args[0].(*parse.IdentifierNode); !ok && (i > 0 || len(cmd.Args) > 1) {
+			return fmt.Errorf("%q is not allowed", cmd.String())
+		}
+		for _, arg := range cmd.Args {
+			err := checkInterpolationArg(arg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkInterpolationArg(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.IdentifierNode:
+		if _, ok := interpolationFuncs[n.Ident]; !ok && !interpolationBuiltins[n.Ident] {
+			return fmt.Errorf("function %q is not allowed", n.Ident)
+		}
+	case *parse.PipeNode:
+		return checkInterpolationPipe(n)
+	case *parse.ChainNode:
+		return checkInterpolationArg(n.Node)
+	}
+	return nil
+}
+
+// interpolationData returns values available in expressions.
+func interpolationData(data *api.PassCard) map[string]interface{} {
+	userInfo := map[string]interface{}{}
+	for key, value := range data.UserInfo {
+		userInfo[key] = value
+	}
+	return map[string]interface{}{
+		"userInfo":           userInfo,
+		"serialNumber":       data.SerialNumber,
+		"description":        data.Description,
+		"organizationName":   data.OrganizationName,
+		"logoText":           data.LogoText,
+		"groupingIdentifier": data.GroupingIdentifier,
+		"expirationDate":     data.ExpirationDate,
+		"relevantDate":       data.RelevantDate,
+	}
+}
+
+// interpolatePassCard returns copy of data with evaluated expressions in
+// field values and barcodes. Stored data keeps expressions, they are
+// evaluated on every build of pass bundle.
+func interpolatePassCard(data *api.PassCard) (*api.PassCard, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &api.PassCard{}
+	err = json.Unmarshal(raw, out)
+	if err != nil {
+		return nil, err
+	}
+
+	values := interpolationData(out)
+
+	_, structure, err := out.Style()
+	if err == nil && structure != nil {
+		sections := []struct {
+			fieldType api.FieldType
+			fields    []*api.Field
+		}{
+			{api.HeaderFieldsType, structure.HeaderFields},
+			{api.PrimaryFieldsType, structure.PrimaryFields},
+			{api.SecondaryFieldsType, structure.SecondaryFields},
+			{api.AuxiliaryFieldsType, structure.AuxiliaryFields},
+			{api.BackFieldsType, structure.BackFields},
+		}
+		for _, section := range sections {
+			for _, field := range section.fields {
+				err = interpolateField(field, values)
+				if err != nil {
+					return nil, fmt.Errorf("interpolation: %s: %s: %v", section.fieldType, field.Key, err)
+				}
+			}
+		}
+	}
+
+	for i, barcode := range out.Barcodes {
+		barcode.Message, err = interpolate(barcode.Message, values)
+		if err != nil {
+			return nil, fmt.Errorf("interpolation: barcodes[%d]: message: %v", i, err)
+		}
+		barcode.AltText, err = interpolate(barcode.AltText, values)
+		if err != nil {
+			return nil, fmt.Errorf("interpolation: barcodes[%d]: altText: %v", i, err)
+		}
+	}
+
+	return out, nil
+}
+
+func interpolateField(field *api.Field, values map[string]interface{}) error {
+	if s, ok := field.Value.(string); ok {
+		value, err := interpolate(s, values)
+		if err != nil {
+			return err
+		}
+		field.Value = value
+	}
+
+	value, err := interpolate(field.AttributedValue, values)
+	if err != nil {
+		return err
+	}
+	field.AttributedValue = value
+
+	return nil
+}
+
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return def
+	}
+	return value
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("number: unsupported value %v", value)
+	}
+}
+
+// formatNumber formats value with given precision and thousands separator,
+// e.g. `{{ number 2 .userInfo.balance }}` gives "1,234.50".
+func formatNumber(precision int, value interface{}) (string, error) {
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	if precision < 0 || precision > 10 {
+		return "", fmt.Errorf("number: invalid precision %d", precision)
+	}
+
+	s := strconv.FormatFloat(math.Abs(f), 'f', precision, 64)
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i:]
+	}
+
+	var buf bytes.Buffer
+	if f < 0 {
+		buf.WriteByte('-')
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteRune(c)
+	}
+	buf.WriteString(fracPart)
+
+	return buf.String(), nil
+}
+
+// formatDate formats W3C date with Go layout, e.g. `{{ date "02.01.2006" .expirationDate }}`.
+func formatDate(layout string, value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("date: unsupported value %v", value)
+	}
+	for _, l := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		t, err := time.Parse(l, s)
+		if err == nil {
+			return t.Format(layout), nil
+		}
+	}
+	return "", fmt.Errorf("date: invalid value %q", s)
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	data := map[string]interface{}{
+		"userInfo": map[string]interface{}{
+			"firstName": "Jane",
+			"lastName":  "Doe",
+			"points":    float64(1234567.891),
+			"tier":      "",
+		},
+		"serialNumber":   "908c0abf",
+		"expirationDate": "2020-04-24T10:00-05:00",
+	}
+
+	testCases := []struct {
+		Name     string
+		Input    string
+		Expected string
+		Error    bool
+	}{
+		{Name: "Plain", Input: "20% off", Expected: "20% off"},
+		{Name: "UserInfo", Input: "{{ .userInfo.firstName }} {{ .userInfo.lastName }}", Expected: "Jane Doe"},
+		{Name: "Number", Input: "{{ number 2 .userInfo.points }} pts", Expected: "1,234,567.89 pts"},
+		{Name: "NumberPipe", Input: "{{ .userInfo.points | number 0 }}", Expected: "1,234,568"},
+		{Name: "Date", Input: `{{ date "02.01.2006" .expirationDate }}`, Expected: "24.04.2020"},
+		{Name: "Default", Input: `{{ default "Silver" .userInfo.tier }}`, Expected: "Silver"},
+		{Name: "Upper", Input: "{{ upper .userInfo.lastName }}", Expected: "DOE"},
+		{Name: "If", Input: `{{ if gt .userInfo.points 1000.0 }}Gold{{ else }}Silver{{ end }}`, Expected: "Gold"},
+		{Name: "Printf", Input: `{{ printf "%s-%s" .serialNumber "A" }}`, Expected: "908c0abf-A"},
+		{Name: "MissingKey", Input: "{{ .userInfo.middleName }}", Error: true},
+		{Name: "UnknownRoot", Input: "{{ .balance }}", Error: true},
+		{Name: "Syntax", Input: "{{ .userInfo.firstName ", Error: true},
+		{Name: "Call", Input: "{{ call .userInfo.firstName }}", Error: true},
+		{Name: "CallInChain", Input: "{{ (call .userInfo.firstName).x }}", Error: true},
+		{Name: "FieldMethod", Input: `{{ .userInfo.firstName "x" }}`, Error: true},
+		{Name: "VariableMethod", Input: `{{ $.userInfo.firstName "x" }}`, Error: true},
+		{Name: "DotMethod", Input: `{{ . "x" }}`, Error: true},
+		{Name: "ChainMethod", Input: `{{ (.userInfo).firstName "x" }}`, Error: true},
+		{Name: "PipedMethod", Input: "{{ .serialNumber | .userInfo.firstName }}", Error: true},
+		{Name: "Variable", Input: "{{ $.serialNumber }}", Expected: "908c0abf"},
+		{Name: "Chain", Input: "{{ (.userInfo).firstName }}", Expected: "Jane"},
+		{Name: "Range", Input: "{{ range .userInfo }}x{{ end }}", Error: true},
+		{Name: "Define", Input: `{{ define "x" }}x{{ end }}{{ template "x" }}`, Error: true},
+		{Name: "NumberInvalid", Input: "{{ number 0 .userInfo.firstName }}", Error: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			output, err := interpolate(tc.Input, data)
+			if tc.Error {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Expected, output)
+		})
+	}
+}
+
+func TestInterpolatePassCard(t *testing.T) {
+	assert := assert.New(t)
+
+	data := &api.PassCard{
+		SerialNumber: "908c0abf",
+		UserInfo:     api.JSONMap{"firstName": "Jane", "points": float64(1500)},
+		StoreCard: &api.PassStructure{
+			PrimaryFields: []*api.Field{
+				&api.Field{Key: "balance", Value: "{{ number 0 .userInfo.points }}"},
+				&api.Field{Key: "static", Value: float64(10)},
+			},
+			BackFields: []*api.Field{
+				&api.Field{Key: "name", Value: "{{ .userInfo.firstName }}"},
+			},
+		},
+		Barcodes: []*api.Barcode{
+			&api.Barcode{Message: "{{ .serialNumber }}:{{ .userInfo.points }}", AltText: "{{ .serialNumber }}"},
+		},
+	}
+
+	out, err := interpolatePassCard(data)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("1,500", out.StoreCard.PrimaryFields[0].Value)
+	assert.Equal(float64(10), out.StoreCard.PrimaryFields[1].Value)
+	assert.Equal("Jane", out.StoreCard.BackFields[0].Value)
+	assert.Equal("908c0abf:1500", out.Barcodes[0].Message)
+	assert.Equal("908c0abf", out.Barcodes[0].AltText)
+
+	assert.Equal("{{ number 0 .userInfo.points }}", data.StoreCard.PrimaryFields[0].Value)
+	assert.Equal("{{ .serialNumber }}:{{ .userInfo.points }}", data.Barcodes[0].Message)
+
+	data.StoreCard.SecondaryFields = []*api.Field{
+		&api.Field{Key: "tier", Value: "{{ .userInfo.tier }}"},
+	}
+	_, err = interpolatePassCard(data)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "secondaryFields: tier")
+	}
+
+	data.StoreCard.SecondaryFields = nil
+	data.Barcodes[0].Message = "{{ .userInfo.cardNumber }}"
+	_, err = interpolatePassCard(data)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "barcodes[0]: message")
+	}
+}
+
+func TestCreatePassCardInterpolation(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Request  *CreatePassCardRequest
+		Expected int
+		Message  string
+		Value    string
+		Rendered map[string]string
+	}{
+		{
+			Name: "Interpolated",
+			Request: &CreatePassCardRequest{
+				UserInfo: api.JSONMap{"firstName": "Jane", "lastName": "Doe", "member": "M-42"},
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "name", Value: "{{ .userInfo.firstName }} {{ .userInfo.lastName }}"},
+					},
+				},
+				Barcodes: []*api.Barcode{
+					&api.Barcode{
+						Message:         "{{ .userInfo.member }}",
+						Format:          api.PKBarcodeFormatQR,
+						MessageEncoding: "iso-8859-1",
+					},
+				},
+			},
+			Expected: http.StatusCreated,
+			Message:  "{{ .userInfo.member }}",
+			Value:    "{{ .userInfo.firstName }} {{ .userInfo.lastName }}",
+			Rendered: map[string]string{"value": "Jane Doe", "message": "M-42"},
+		},
+		{
+			Name: "MissingUserInfo",
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "name", Value: "{{ .userInfo.firstName }}"},
+					},
+				},
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Title:            fakeString(),
+				OrganizationName: fakeString(),
+				Description:      fakeString(),
+				PassType:         api.Coupon,
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			body, err := json.Marshal(tc.Request)
+			if !assert.NoError(err) {
+				return
+			}
+
+			req := authRequest(srv, user, newRequest("POST", fmt.Sprintf("/projects/%d/cards", project.ID), body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode != http.StatusCreated {
+				return
+			}
+
+			var data struct {
+				SerialNumber string `json:"serialNumber"`
+			}
+			err = unmarshalJSON(resp, &data)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcard, err := srv.env.Logic.LoadPassCardBySerialNumber(ctx, project, data.SerialNumber)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Value, passcard.Data.Coupon.PrimaryFields[0].Value)
+			assert.Equal(tc.Message, passcard.Data.Barcodes[0].Message)
+
+			obj, err := srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, data.SerialNumber)
+			if !assert.NoError(err) {
+				return
+			}
+
+			files, err := pkpass.Unzip(obj.Body)
+			if !assert.NoError(err) {
+				return
+			}
+
+			for _, file := range files {
+				if file.Name != pkpass.PassFilename {
+					continue
+				}
+				pass := &api.PassCard{}
+				err = json.Unmarshal(file.Data, pass)
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal(tc.Rendered["value"], pass.Coupon.PrimaryFields[0].Value)
+				assert.Equal(tc.Rendered["message"], pass.Barcodes[0].Message)
+			}
+		})
+	}
+}
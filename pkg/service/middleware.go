@@ -70,16 +70,28 @@ func parseAuthHeader(header string, re *regexp.Regexp) string {
 	return matches[1]
 }
 
-func applePassMiddleware(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+func (s *Service) applePassMiddleware(w http.ResponseWriter, r *http.Request) (context.Context, error) {
 	var (
-		ctx  = r.Context()
-		code = http.StatusUnauthorized
-		err  = mux.NewHTTPError(code, http.StatusText(code))
+		ctx          = r.Context()
+		vars         = mux.Vars(r)
+		passTypeID   = vars["passTypeID"]
+		serialNumber = vars["serialNumber"]
+		code         = http.StatusUnauthorized
 	)
+
 	token := parseAuthHeader(r.Header.Get("Authorization"), applePassRegexp)
 	if token == "" {
-		return ctx, err
+		return nil, s.httpError(w, r, code, "ParseAuthHeader", nil)
 	}
+
+	ok, err := s.env.PassKit.FindPass(ctx, serialNumber, token, passTypeID)
+	if err != nil {
+		return nil, s.httpError(w, r, http.StatusInternalServerError, "FindPass", err)
+	}
+	if !ok {
+		return nil, s.httpError(w, r, code, "FindPass", nil)
+	}
+
 	return withApplePass(ctx, token), nil
 }
 
@@ -191,6 +203,8 @@ func (s *Service) authMiddleware(w http.ResponseWriter, r *http.Request) (contex
 		return nil, s.httpError(w, r, code, "LoadUser", err)
 	}
 
+	ctx = api.WithAuthor(ctx, api.Author{UserID: user.ID, RequestID: reqIDFromContext(ctx)})
+
 	return withUser(ctx, user), nil
 }
 
@@ -245,8 +259,9 @@ func (s *Service) allowedOrigins() []string {
 
 func (s *Service) corsMiddleware(next http.Handler) http.Handler {
 	cors := cors.New(cors.Options{
-		AllowedMethods: []string{"HEAD", "GET", "POST", "PUT", "DELETE"},
-		AllowedHeaders: []string{"Accept", "Content-Type", "X-Requested-With", csrfHeader},
+		AllowedMethods: []string{"HEAD", "GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Accept", "Content-Type", "X-Requested-With", "If-Match", csrfHeader},
+		ExposedHeaders: []string{"ETag"},
 		AllowOriginRequestFunc: func(r *http.Request, origin string) bool {
 			if s.env.Config.IsDevelopment() && strings.Contains(origin, "localhost") {
 				return true
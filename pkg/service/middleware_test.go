@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -62,3 +63,89 @@ func TestCORSMiddleware(t *testing.T) {
 	assert.Equal("http://localhost:8080", resp.Header.Get("Access-Control-Allow-Origin"))
 	assert.Equal("Content-Type", resp.Header.Get("Access-Control-Allow-Headers"))
 }
+
+func TestApplePassMiddleware(t *testing.T) {
+	var (
+		serialNumber = fakeString()
+		authToken    = fakeString()
+		passTypeID   = "com.example.pass"
+	)
+
+	testCases := []struct {
+		Name         string
+		SerialNumber string
+		PassTypeID   string
+		Header       string
+		Expected     int
+	}{
+		{
+			Name:         "Authorized",
+			SerialNumber: serialNumber,
+			PassTypeID:   passTypeID,
+			Header:       "ApplePass " + authToken,
+			Expected:     http.StatusCreated,
+		},
+		{
+			Name:         "MissingHeader",
+			SerialNumber: serialNumber,
+			PassTypeID:   passTypeID,
+			Expected:     http.StatusUnauthorized,
+		},
+		{
+			Name:         "WrongToken",
+			SerialNumber: serialNumber,
+			PassTypeID:   passTypeID,
+			Header:       "ApplePass " + fakeString(),
+			Expected:     http.StatusUnauthorized,
+		},
+		{
+			Name:         "WrongPassType",
+			SerialNumber: serialNumber,
+			PassTypeID:   "com.example.another",
+			Header:       "ApplePass " + authToken,
+			Expected:     http.StatusUnauthorized,
+		},
+		{
+			Name:         "WrongSerialNumber",
+			SerialNumber: fakeString(),
+			PassTypeID:   passTypeID,
+			Header:       "ApplePass " + authToken,
+			Expected:     http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = srv.env.PassKit.InsertPass(context.Background(), serialNumber, authToken, passTypeID)
+			if !assert.NoError(err) {
+				return
+			}
+
+			headers := map[string]string{}
+			if tc.Header != "" {
+				headers["Authorization"] = tc.Header
+			}
+
+			req := newRequest(
+				"POST",
+				fmt.Sprintf("/v1/devices/%s/registrations/%s/%s", fakeString(), tc.PassTypeID, tc.SerialNumber),
+				[]byte(`{"pushToken":"test-token"}`),
+				headers,
+				nil,
+			)
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			assert.Equal(tc.Expected, resp.StatusCode)
+		})
+	}
+}
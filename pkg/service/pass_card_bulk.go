@@ -0,0 +1,517 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/filestore"
+	"github.com/danikarik/okpock/pkg/store"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	bulkWorkers      = 8
+	bulkProgressStep = 50
+	maxBulkRows      = 50000
+	maxBulkSize      = 64 * MB
+)
+
+var (
+	// ErrUnsupportedBulkFormat raised when bulk upload is neither CSV nor NDJSON.
+	ErrUnsupportedBulkFormat = errors.New("bulk: content type must be text/csv or application/x-ndjson")
+	// ErrEmptyBulk raised when bulk upload has no rows.
+	ErrEmptyBulk = errors.New("bulk: no rows")
+	// ErrTooManyBulkRows raised when bulk upload exceeds row limit.
+	ErrTooManyBulkRows = fmt.Errorf("bulk: more than %d rows", maxBulkRows)
+)
+
+// bulkRow holds pass card request read from single row of bulk upload.
+// Row numbers start from 1 and do not count CSV header.
+type bulkRow struct {
+	row int
+	req *CreatePassCardRequest
+	err error
+}
+
+// bulkMediaType returns media type of bulk upload.
+func bulkMediaType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnsupportedBulkFormat
+	}
+	return mediaType, nil
+}
+
+// parseBulkRows parses bulk upload as CSV or NDJSON depending on media type.
+func parseBulkRows(mediaType string, r io.Reader) ([]*bulkRow, error) {
+	var (
+		rows []*bulkRow
+		err  error
+	)
+
+	switch mediaType {
+	case "text/csv":
+		rows, err = readCSVRows(r)
+	case "application/x-ndjson", "application/jsonl", "application/json-lines":
+		rows, err = readNDJSONRows(r)
+	default:
+		return nil, ErrUnsupportedBulkFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyBulk
+	}
+
+	return rows, nil
+}
+
+func readNDJSONRows(r io.Reader) ([]*bulkRow, error) {
+	var (
+		rows    = []*bulkRow{}
+		scanner = bufio.NewScanner(r)
+	)
+	scanner.Buffer(make([]byte, 64*1024), MB)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(rows) == maxBulkRows {
+			return nil, ErrTooManyBulkRows
+		}
+
+		row := &bulkRow{row: len(rows) + 1, req: &CreatePassCardRequest{}}
+		row.err = json.Unmarshal(line, row.req)
+		rows = append(rows, row)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// readCSVRows maps CSV columns to `CreatePassCardRequest` keys by header.
+// Columns like `userInfo.firstName` or `values.balance` set map entries,
+// cells of non string keys like `structure` or `barcodes` hold JSON.
+func readCSVRows(r io.Reader) ([]*bulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyBulk
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, column := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+	}
+
+	rows := []*bulkRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == maxBulkRows {
+			return nil, ErrTooManyBulkRows
+		}
+
+		row := &bulkRow{row: len(rows) + 1}
+		row.req, row.err = csvRequest(header, record)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// requestStringKeys lists json keys of string fields of `CreatePassCardRequest`.
+var requestStringKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(CreatePassCardRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.String {
+			keys[strings.Split(field.Tag.Get("json"), ",")[0]] = true
+		}
+	}
+	return keys
+}()
+
+func csvRequest(header, record []string) (*CreatePassCardRequest, error) {
+	raw := map[string]interface{}{}
+
+	for i, cell := range record {
+		if i >= len(header) || header[i] == "" || cell == "" {
+			continue
+		}
+		column := header[i]
+
+		if dot := strings.IndexByte(column, '.'); dot > 0 {
+			key, sub := column[:dot], column[dot+1:]
+			if key != "userInfo" && key != "values" {
+				return nil, fmt.Errorf("csv: unsupported column %q", column)
+			}
+			m, ok := raw[key].(map[string]interface{})
+			if !ok {
+				m = map[string]interface{}{}
+				raw[key] = m
+			}
+			m[sub] = csvValue(cell)
+			continue
+		}
+
+		if requestStringKeys[column] {
+			raw[column] = cell
+			continue
+		}
+
+		var value interface{}
+		err := json.Unmarshal([]byte(cell), &value)
+		if err != nil {
+			return nil, fmt.Errorf("csv: column %q: %v", column, err)
+		}
+		raw[column] = value
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &CreatePassCardRequest{}
+	err = json.Unmarshal(data, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// csvValue decodes JSON numbers, booleans and objects, other cells are strings.
+func csvValue(cell string) interface{} {
+	var value interface{}
+	err := json.Unmarshal([]byte(cell), &value)
+	if err != nil {
+		return cell
+	}
+	return value
+}
+
+func (s *Service) bulkCreatePassCardsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	mediaType, err := bulkMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadBulkRows", err)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBulkSize))
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadBulkRows", err)
+	}
+	defer r.Body.Close()
+
+	rows, err := parseBulkRows(mediaType, bytes.NewReader(body))
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadBulkRows", err)
+	}
+
+	// Rows are kept in upload bucket until job is finished,
+	// so job is resumed by any worker.
+	object := &filestore.Object{
+		Prefix:      "jobs",
+		Key:         uuid.NewV4().String(),
+		Body:        body,
+		ContentType: mediaType,
+	}
+
+	err = s.env.Storage.UploadFile(ctx, s.env.Config.UploadBucket, object)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "UploadFile", err)
+	}
+
+	job, err := newJob(ctx, project, api.IssueJob, &jobParams{Upload: object.Path(), ContentType: mediaType})
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewJob", err)
+	}
+	job.Total = len(rows)
+
+	err = s.env.Logic.SaveNewJob(ctx, project, job)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewJob", err)
+	}
+
+	return sendJSON(w, http.StatusAccepted, job)
+}
+
+// issuePassCards creates pass cards of uploaded rows in chunks with bounded
+// number of workers. Outcome of every row is stored as job result right
+// after row is issued, so rows having result are skipped once job is resumed.
+// Job fails when result could not be stored. Upload is removed when job is
+// finished.
+func (s *Service) issuePassCards(ctx context.Context, logger *zap.Logger, user *api.User, project *api.Project, job *api.Job, params *jobParams) error {
+	rows, err := s.loadBulkRows(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	done, err := s.resumeIssueJob(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	pending := []*bulkRow{}
+	for _, row := range rows {
+		if !done[row.row] {
+			pending = append(pending, row)
+		}
+	}
+
+	for start := 0; start < len(pending); start += bulkProgressStep {
+		end := start + bulkProgressStep
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		results, err := s.issueBulkRows(ctx, job, user, project, pending[start:end])
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			job.Processed++
+			if !result.Succeeded() {
+				job.Failed++
+				job.LastError = fmt.Sprintf("row %d: %s", result.Row, result.Error)
+			}
+		}
+		job.Cursor = int64(pending[end-1].row)
+
+		err = s.saveJobProgress(ctx, job)
+		if err != nil {
+			return err
+		}
+	}
+
+	job.Finish(api.JobFinished)
+	err = s.saveJobProgress(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	err = s.env.Storage.DeleteFile(ctx, s.env.Config.UploadBucket, params.Upload)
+	if err != nil {
+		logger.Error("job_error", zap.Error(err), zap.String("message", "DeleteFile"))
+	}
+
+	return nil
+}
+
+// loadBulkRows parses rows of upload stored by bulk handler.
+func (s *Service) loadBulkRows(ctx context.Context, params *jobParams) ([]*bulkRow, error) {
+	r, err := s.env.Storage.GetStream(ctx, s.env.Config.UploadBucket, params.Upload)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return parseBulkRows(params.ContentType, r)
+}
+
+// resumeIssueJob returns rows processed by previous attempts of job and
+// counts them again, as results could be stored after last progress.
+func (s *Service) resumeIssueJob(ctx context.Context, job *api.Job) (map[int]bool, error) {
+	done := map[int]bool{}
+	if job.Attempts <= 1 {
+		return done, nil
+	}
+
+	job.Processed, job.Failed = 0, 0
+	opts := api.NewPagingOptions(0, publishPageLimit)
+
+	for {
+		results, err := s.env.Logic.LoadJobResults(ctx, job, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results.Data {
+			if done[result.Row] {
+				continue
+			}
+			done[result.Row] = true
+
+			job.Processed++
+			if !result.Succeeded() {
+				job.Failed++
+			}
+		}
+
+		if !results.Opts.HasNext() {
+			return done, nil
+		}
+		opts = api.NewPagingOptions(results.Opts.Next, publishPageLimit)
+	}
+}
+
+// issueBulkRows issues pass cards of rows with bounded number of workers and
+// stores result of every row once it is issued. Remaining rows are skipped
+// after result could not be stored.
+func (s *Service) issueBulkRows(ctx context.Context, job *api.Job, user *api.User, project *api.Project, rows []*bulkRow) ([]*api.JobResult, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failure error
+		queue   = make(chan int)
+		results = make([]*api.JobResult, len(rows))
+	)
+
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return failure != nil
+	}
+
+	for i := 0; i < bulkWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if failed() {
+					continue
+				}
+
+				result := s.issuePassCard(ctx, user, project, rows[i])
+				err := s.env.Logic.SaveJobResult(ctx, job, result)
+				if err != nil {
+					mu.Lock()
+					if failure == nil {
+						failure = err
+					}
+					mu.Unlock()
+					continue
+				}
+				results[i] = result
+			}
+		}()
+	}
+
+	for i := range rows {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+
+	return results, nil
+}
+
+func (s *Service) issuePassCard(ctx context.Context, user *api.User, project *api.Project, row *bulkRow) *api.JobResult {
+	result := api.NewJobResult(row.row)
+
+	fail := func(err error) *api.JobResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	if row.err != nil {
+		return fail(row.err)
+	}
+
+	passcard, err := s.newProjectPassCard(row.req, project)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = passcard.IsValid()
+	if err != nil {
+		return fail(err)
+	}
+
+	err = passcard.Data.CheckChangeMessages(nil)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = s.checkLocalizations(ctx, user, passcard.Localizations)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Pass card is saved last, so it is followed by its result right away
+	// and is never issued twice once job is resumed.
+	err = s.env.PassKit.InsertPass(
+		ctx,
+		passcard.Data.SerialNumber,
+		passcard.Data.AuthenticationToken,
+		passcard.Data.PassTypeID)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = s.uploadPass(ctx, project, passcard)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = s.env.Logic.SaveNewPassCard(ctx, project, passcard)
+	if err != nil {
+		return fail(err)
+	}
+	result.PassCardID = passcard.ID
+	result.SerialNumber = passcard.Data.SerialNumber
+
+	return result
+}
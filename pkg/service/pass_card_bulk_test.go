@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkCreatePassCardsHandler(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		ContentType string
+		Template    *api.Template
+		Body        string
+		Expected    int
+		Total       int
+		Failed      []int
+	}{
+		{
+			Name:        "CSV",
+			ContentType: "text/csv",
+			Template:    fakeTemplate(),
+			Body: strings.Join([]string{
+				"logoText,userInfo.firstName,values.balance,values.expires",
+				"Coupon,Jane,1500,2020-04-24T10:00-05:00",
+				"Coupon,John,,2020-04-24T10:00-05:00",
+				"Coupon,Ann,300,",
+			}, "\n"),
+			Expected: http.StatusAccepted,
+			Total:    3,
+			Failed:   []int{2},
+		},
+		{
+			Name:        "NDJSON",
+			ContentType: "application/x-ndjson",
+			Body: strings.Join([]string{
+				`{"logoText":"Coupon","structure":{"primaryFields":[{"key":"offer","value":"20% off"}]}}`,
+				`{"logoText":`,
+				``,
+				`{"structure":{"primaryFields":[{"key":"offer","value":"{{ .userInfo.name }}"}]},"userInfo":{"name":"Jane"}}`,
+			}, "\n"),
+			Expected: http.StatusAccepted,
+			Total:    3,
+			Failed:   []int{2},
+		},
+		{
+			Name:        "UnsupportedFormat",
+			ContentType: "application/xml",
+			Body:        "<cards></cards>",
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "Empty",
+			ContentType: "text/csv",
+			Body:        "logoText,userInfo.firstName\n",
+			Expected:    http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Title:            fakeString(),
+				OrganizationName: fakeString(),
+				Description:      fakeString(),
+				PassType:         api.Coupon,
+				Template:         tc.Template,
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			headers := map[string]string{"Content-Type": tc.ContentType}
+			req := authRequest(srv, user, newRequest("POST", fmt.Sprintf("/projects/%d/cards/bulk", project.ID), []byte(tc.Body), headers, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode != http.StatusAccepted {
+				return
+			}
+
+			job := &api.Job{}
+			err = unmarshalJSON(resp, job)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.IssueJob, job.Kind)
+			assert.Equal(tc.Total, job.Total)
+
+			job, err = waitJob(srv, user, project, job)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.JobFinished, job.Status)
+			assert.Equal(tc.Total, job.Processed)
+			assert.Equal(len(tc.Failed), job.Failed)
+
+			req = authRequest(srv, user, newRequest("GET", fmt.Sprintf("/projects/%d/jobs/%d/results", project.ID, job.ID), nil, nil, nil))
+			rec = httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp = rec.Result()
+
+			if !assert.Equal(http.StatusOK, resp.StatusCode) {
+				return
+			}
+
+			var list struct {
+				Data []*api.JobResult `json:"data"`
+			}
+			err = unmarshalJSON(resp, &list)
+			if !assert.NoError(err) || !assert.Len(list.Data, tc.Total) {
+				return
+			}
+
+			failed := map[int]bool{}
+			for _, row := range tc.Failed {
+				failed[row] = true
+			}
+
+			for _, result := range list.Data {
+				if failed[result.Row] {
+					assert.NotEmpty(result.Error)
+					assert.Empty(result.URL)
+					continue
+				}
+
+				if !assert.Empty(result.Error) {
+					continue
+				}
+				assert.NotEmpty(result.URL)
+
+				passcard, err := srv.env.Logic.LoadPassCard(ctx, project, result.PassCardID)
+				if assert.NoError(err) {
+					assert.Equal(result.SerialNumber, passcard.Data.SerialNumber)
+				}
+
+				_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, result.SerialNumber)
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestIssueBulkRows(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := &api.Project{
+		ID:               fakeID(),
+		Title:            fakeString(),
+		OrganizationName: fakeString(),
+		Description:      fakeString(),
+		PassType:         api.Coupon,
+	}
+
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	rows, err := parseBulkRows("application/x-ndjson", strings.NewReader(strings.Join([]string{
+		`{"logoText":"Coupon","structure":{"primaryFields":[{"key":"offer","value":"20% off"}]}}`,
+		`{"logoText":"Coupon","structure":{"primaryFields":[{"key":"offer","value":"30% off"}]}}`,
+	}, "\n")))
+	if !assert.NoError(err) {
+		return
+	}
+
+	job := api.NewJob(api.IssueJob, project.ID, api.Author{UserID: user.ID})
+	err = srv.env.Logic.SaveNewJob(ctx, project, job)
+	if !assert.NoError(err) {
+		return
+	}
+
+	results, err := srv.issueBulkRows(ctx, job, user, project, rows)
+	if !assert.NoError(err) || !assert.Len(results, len(rows)) {
+		return
+	}
+
+	stored, err := srv.env.Logic.LoadJobResults(ctx, job, nil)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(stored.Data, len(rows))
+
+	_, err = srv.issueBulkRows(ctx, nil, user, project, rows)
+	assert.Error(err)
+}
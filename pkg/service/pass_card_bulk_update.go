@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// PassCardFilter selects project pass cards. Only one criteria is allowed.
+type PassCardFilter struct {
+	All            bool            `json:"all,omitempty"`
+	SerialNumbers  []string        `json:"serialNumbers,omitempty"`
+	BarcodeMessage string          `json:"barcodeMessage,omitempty"`
+	UserInfo       *UserInfoFilter `json:"userInfo,omitempty"`
+}
+
+// UserInfoFilter matches pass cards by `userInfo` attribute.
+type UserInfoFilter struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// IsValid checks whether input is valid or not.
+func (f *PassCardFilter) IsValid() error {
+	cnt := 0
+	if f.All {
+		cnt++
+	}
+	if len(f.SerialNumbers) > 0 {
+		for _, serialNumber := range f.SerialNumbers {
+			if serialNumber == "" {
+				return errors.New("filter: serial number is empty")
+			}
+		}
+		cnt++
+	}
+	if f.BarcodeMessage != "" {
+		cnt++
+	}
+	if f.UserInfo != nil {
+		if f.UserInfo.Key == "" {
+			return errors.New("filter: user info key is empty")
+		}
+		cnt++
+	}
+
+	if cnt == 0 {
+		return errors.New("filter: all, serial numbers, barcode message or user info is required")
+	}
+	if cnt > 1 {
+		return errors.New("filter: only one criteria allowed")
+	}
+
+	return nil
+}
+
+// BulkUpdateRequest holds patch applied to every pass card matching filter.
+type BulkUpdateRequest struct {
+	Filter *PassCardFilter `json:"filter"`
+	Patch  *PassCardPatch  `json:"patch"`
+	DryRun bool            `json:"dryRun,omitempty"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *BulkUpdateRequest) IsValid() error {
+	if r.Filter == nil {
+		return errors.New("filter is required")
+	}
+	err := r.Filter.IsValid()
+	if err != nil {
+		return err
+	}
+
+	if r.Patch == nil {
+		return errors.New("patch is required")
+	}
+	return r.Patch.IsValid()
+}
+
+// String returns string representation of struct.
+func (r *BulkUpdateRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// BulkUpdateReport holds outcome of bulk update dry run.
+type BulkUpdateReport struct {
+	PassCards     int    `json:"passCards"`
+	Registrations int    `json:"registrations"`
+	Failed        int    `json:"failed"`
+	LastError     string `json:"lastError,omitempty"`
+}
+
+func (s *Service) bulkUpdatePassCardsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req BulkUpdateRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	if req.DryRun {
+		report, err := s.bulkUpdateReport(ctx, s.filterPager(project, req.Filter), req.Patch)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "BulkUpdateReport", err)
+		}
+		return sendJSON(w, http.StatusOK, report)
+	}
+
+	job, err := newJob(ctx, project, api.PatchJob, &jobParams{Filter: req.Filter, Patch: req.Patch})
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewJob", err)
+	}
+
+	err = s.env.Logic.SaveNewJob(ctx, project, job)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewJob", err)
+	}
+
+	return sendJSON(w, http.StatusAccepted, job)
+}
+
+// jobPager returns pager of pass cards selected by job params, every project
+// pass card is selected by default.
+func (s *Service) jobPager(project *api.Project, params *jobParams) passCardPager {
+	switch {
+	case params.PassCardID > 0:
+		return s.passCardPager(project, params.PassCardID)
+	case params.Filter != nil:
+		return s.filterPager(project, params.Filter)
+	default:
+		return s.projectPager(project)
+	}
+}
+
+func (s *Service) filterPager(project *api.Project, filter *PassCardFilter) passCardPager {
+	switch {
+	case len(filter.SerialNumbers) > 0:
+		return s.serialNumberPager(project, filter.SerialNumbers)
+	case filter.BarcodeMessage != "":
+		return listPager(func(ctx context.Context, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+			return s.env.Logic.LoadPassCardsByBarcodeMessage(ctx, project, filter.BarcodeMessage, opts)
+		})
+	case filter.UserInfo != nil:
+		return listPager(func(ctx context.Context, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+			return s.env.Logic.LoadPassCardsByUserInfo(ctx, project, filter.UserInfo.Key, filter.UserInfo.Value, opts)
+		})
+	default:
+		return s.projectPager(project)
+	}
+}
+
+// serialNumberPager pages pass cards by offset in list of unique serial
+// numbers. Unknown and archived pass cards are skipped like other filters do.
+func (s *Service) serialNumberPager(project *api.Project, serialNumbers []string) passCardPager {
+	var (
+		unique = []string{}
+		seen   = map[string]bool{}
+	)
+	for _, serialNumber := range serialNumbers {
+		if !seen[serialNumber] {
+			seen[serialNumber] = true
+			unique = append(unique, serialNumber)
+		}
+	}
+
+	return func(ctx context.Context, cursor int64) ([]*api.PassCardInfo, []int64, error) {
+		var (
+			passcards = []*api.PassCardInfo{}
+			next      = []int64{}
+		)
+
+		for i := int(cursor); i < len(unique) && uint64(len(passcards)) < publishPageLimit; i++ {
+			passcard, err := s.env.Logic.LoadPassCardBySerialNumber(ctx, project, unique[i])
+			if err == store.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if passcard.IsArchived() {
+				continue
+			}
+
+			passcards = append(passcards, passcard)
+			next = append(next, int64(i+1))
+		}
+
+		if n := len(next); n > 0 && next[n-1] == int64(len(unique)) {
+			next[n-1] = jobCursorDone
+		}
+
+		return passcards, next, nil
+	}
+}
+
+// passCardPager selects single pass card unless it is archived.
+func (s *Service) passCardPager(project *api.Project, id int64) passCardPager {
+	return func(ctx context.Context, cursor int64) ([]*api.PassCardInfo, []int64, error) {
+		passcard, err := s.env.Logic.LoadPassCard(ctx, project, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if passcard.IsArchived() {
+			return []*api.PassCardInfo{}, []int64{}, nil
+		}
+		return []*api.PassCardInfo{passcard}, []int64{jobCursorDone}, nil
+	}
+}
+
+// bulkUpdateReport counts pass cards and registered devices affected by
+// patch without changing anything.
+func (s *Service) bulkUpdateReport(ctx context.Context, pager passCardPager, patch *PassCardPatch) (*BulkUpdateReport, error) {
+	report := &BulkUpdateReport{}
+
+	for cursor := int64(0); cursor != jobCursorDone; {
+		passcards, next, err := pager(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(passcards) == 0 {
+			break
+		}
+		cursor = next[len(next)-1]
+
+		for _, passcard := range passcards {
+			report.PassCards++
+
+			pushTokens, err := s.env.PassKit.FindPushTokens(ctx, passcard.Data.SerialNumber)
+			if err != nil {
+				return nil, err
+			}
+			report.Registrations += len(pushTokens)
+
+			data, err := patchPassCard(passcard.Data, patch)
+			if err == nil {
+				err = data.IsValid()
+			}
+			if err != nil {
+				report.Failed++
+				report.LastError = passcard.Data.SerialNumber + ": " + err.Error()
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// patchAndPublishPassCard applies patch to latest version of pass card,
+// rebuilds bundle and notifies registered devices.
+func (s *Service) patchAndPublishPassCard(ctx context.Context, project *api.Project, passcard *api.PassCardInfo, patch *PassCardPatch) error {
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := patchPassCard(passcard.Data, patch)
+	if err != nil {
+		return err
+	}
+
+	err = data.IsValid()
+	if err != nil {
+		return err
+	}
+
+	err = s.env.Logic.UpdatePassCard(ctx, data, passcard)
+	if err != nil {
+		return err
+	}
+
+	err = s.uploadPass(ctx, project, passcard)
+	if err != nil {
+		return err
+	}
+
+	return s.updatePass(ctx, project.PassType, passcard.Data.SerialNumber)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkUpdatePassCardsHandler(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Request       string
+		Expected      int
+		Total         int
+		Failed        int
+		Registrations int
+		Check         func(assert *assert.Assertions, passcard *api.PassCardInfo, matched bool)
+	}{
+		{
+			Name:     "UserInfoFields",
+			Request:  `{"filter":{"userInfo":{"key":"tier","value":"gold"}},"patch":{"fields":{"offer":{"value":"30% off {{ .userInfo.tier }}"}}}}`,
+			Expected: http.StatusAccepted,
+			Total:    2,
+			Check: func(assert *assert.Assertions, passcard *api.PassCardInfo, matched bool) {
+				if matched {
+					assert.Equal("30% off {{ .userInfo.tier }}", passcard.Data.Coupon.PrimaryFields[0].Value)
+
+					data, err := interpolatePassCard(passcard.Data)
+					if assert.NoError(err) {
+						assert.Equal("30% off gold", data.Coupon.PrimaryFields[0].Value)
+					}
+				} else {
+					assert.Equal("20% off", passcard.Data.Coupon.PrimaryFields[0].Value)
+				}
+			},
+		},
+		{
+			Name:     "AllData",
+			Request:  `{"filter":{"all":true},"patch":{"data":{"expirationDate":"2020-04-24T10:00:00-05:00","logoText":null}}}`,
+			Expected: http.StatusAccepted,
+			Total:    3,
+			Check: func(assert *assert.Assertions, passcard *api.PassCardInfo, matched bool) {
+				assert.Equal("2020-04-24T10:00:00-05:00", passcard.Data.ExpirationDate)
+				assert.Empty(passcard.Data.LogoText)
+			},
+		},
+		{
+			Name:          "DryRun",
+			Request:       `{"filter":{"userInfo":{"key":"tier","value":"gold"}},"patch":{"fields":{"offer":{"value":"30% off"}}},"dryRun":true}`,
+			Expected:      http.StatusOK,
+			Total:         2,
+			Registrations: 1,
+		},
+		{
+			Name:          "DryRunUnknownField",
+			Request:       `{"filter":{"all":true},"patch":{"fields":{"points":{"value":10}}},"dryRun":true}`,
+			Expected:      http.StatusOK,
+			Total:         3,
+			Failed:        3,
+			Registrations: 1,
+		},
+		{
+			Name:     "FilterRequired",
+			Request:  `{"filter":{},"patch":{"data":{"logoText":"Sale"}}}`,
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:     "MultipleCriteria",
+			Request:  `{"filter":{"all":true,"barcodeMessage":"123"},"patch":{"data":{"logoText":"Sale"}}}`,
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:     "ImmutableKey",
+			Request:  `{"filter":{"all":true},"patch":{"data":{"serialNumber":"123"}}}`,
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Title:            fakeString(),
+				OrganizationName: fakeString(),
+				Description:      fakeString(),
+				PassType:         api.Coupon,
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passTypeID := srv.passTypeToString(project.PassType)
+
+			passcards := []*api.PassCardInfo{}
+			for i, tier := range []string{"gold", "gold", "silver"} {
+				passcard := fakePassCard(project)
+				passcard.ID = int64(i + 1)
+				passcard.Data.PassTypeID = passTypeID
+				passcard.Data.LogoText = "Coupon"
+				passcard.Data.UserInfo = api.JSONMap{"tier": tier}
+				passcard.Data.Coupon = &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Label: "OFFER", Value: "20% off"},
+					},
+				}
+
+				err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+
+				err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, passcard.Data.AuthenticationToken, passTypeID)
+				if !assert.NoError(err) {
+					return
+				}
+
+				passcards = append(passcards, passcard)
+			}
+
+			registered := passcards[0]
+			err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), registered.Data.SerialNumber, passTypeID)
+			if !assert.NoError(err) {
+				return
+			}
+
+			req := authRequest(srv, user, newRequest("PATCH", fmt.Sprintf("/projects/%d/cards", project.ID), []byte(tc.Request), nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			switch resp.StatusCode {
+			case http.StatusOK:
+				report := &BulkUpdateReport{}
+				err = unmarshalJSON(resp, report)
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal(tc.Total, report.PassCards)
+				assert.Equal(tc.Registrations, report.Registrations)
+				assert.Equal(tc.Failed, report.Failed)
+
+				pushes, err := srv.env.PassKit.LoadPushes(ctx, registered.Data.SerialNumber)
+				if assert.NoError(err) {
+					assert.Len(pushes, 0)
+				}
+			case http.StatusAccepted:
+				job := &api.Job{}
+				err = unmarshalJSON(resp, job)
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal(api.PatchJob, job.Kind)
+				assert.Equal(api.JobPending, job.Status)
+
+				job, err = waitJob(srv, user, project, job)
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal(api.JobFinished, job.Status)
+				assert.Equal(tc.Total, job.Total)
+				assert.Equal(tc.Total, job.Processed)
+				assert.Equal(tc.Failed, job.Failed)
+
+				var req BulkUpdateRequest
+				err = json.Unmarshal([]byte(tc.Request), &req)
+				if !assert.NoError(err) {
+					return
+				}
+
+				for _, passcard := range passcards {
+					matched := req.Filter.All || passcard.Data.UserInfo["tier"] == req.Filter.UserInfo.Value
+
+					loaded, err := srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+					if !assert.NoError(err) {
+						return
+					}
+					tc.Check(assert, loaded, matched)
+
+					_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+					if matched {
+						assert.NoError(err)
+					} else {
+						assert.Error(err)
+					}
+				}
+
+				pushes, err := srv.env.PassKit.LoadPushes(ctx, registered.Data.SerialNumber)
+				if assert.NoError(err) {
+					assert.Len(pushes, 1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/filestore"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// BundlePassCardsRequest holds either serial numbers or grouping identifier
+// of passes to be packed into a single `.pkpasses` bundle.
+type BundlePassCardsRequest struct {
+	SerialNumbers      []string `json:"serialNumbers,omitempty"`
+	GroupingIdentifier string   `json:"groupingIdentifier,omitempty"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *BundlePassCardsRequest) IsValid() error {
+	if len(r.SerialNumbers) == 0 && r.GroupingIdentifier == "" {
+		return errors.New("serial numbers or grouping identifier is required")
+	}
+	if len(r.SerialNumbers) > 0 && r.GroupingIdentifier != "" {
+		return errors.New("serial numbers and grouping identifier are mutually exclusive")
+	}
+	if len(r.SerialNumbers) > pkpass.MaxBundlePasses {
+		return pkpass.ErrTooManyPasses
+	}
+	for _, serialNumber := range r.SerialNumbers {
+		if serialNumber == "" {
+			return errors.New("serial number is empty")
+		}
+	}
+	return nil
+}
+
+// String returns string representation of struct.
+func (r *BundlePassCardsRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *Service) bundlePassCardsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	var req BundlePassCardsRequest
+	err = readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	var passcards []*api.PassCardInfo
+	if req.GroupingIdentifier != "" {
+		list, err := s.env.Logic.LoadPassCardsByGroupingIdentifier(ctx, project, req.GroupingIdentifier, api.NewPagingOptions(0, pkpass.MaxBundlePasses))
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardsByGroupingIdentifier", err)
+		}
+		if list.Opts.Next > 0 || len(list.Data) > pkpass.MaxBundlePasses {
+			return s.httpError(w, r, http.StatusBadRequest, "LoadPassCardsByGroupingIdentifier", pkpass.ErrTooManyPasses)
+		}
+		if len(list.Data) == 0 {
+			return s.httpError(w, r, http.StatusNotFound, "LoadPassCardsByGroupingIdentifier", store.ErrNotFound)
+		}
+		passcards = list.Data
+	} else {
+		for _, serialNumber := range req.SerialNumbers {
+			passcard, err := s.env.Logic.LoadPassCardBySerialNumber(ctx, project, serialNumber)
+			if err == store.ErrNotFound {
+				return s.httpError(w, r, http.StatusNotFound, "LoadPassCardBySerialNumber", fmt.Errorf("pass card %s not found", serialNumber))
+			}
+			if err != nil {
+				return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardBySerialNumber", err)
+			}
+			passcards = append(passcards, passcard)
+		}
+	}
+
+	files := make([]pkpass.File, 0, len(passcards))
+	for _, passcard := range passcards {
+		serialNumber := passcard.Data.SerialNumber
+
+		obj, err := s.env.Storage.GetFile(ctx, s.env.Config.PassesBucket, serialNumber)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "File", err)
+		}
+
+		files = append(files, pkpass.NewFile(serialNumber+pkpass.Extension, obj.Body))
+	}
+
+	data, err := pkpass.Bundle(files...)
+	if err == pkpass.ErrDuplicatePass {
+		return s.httpError(w, r, http.StatusBadRequest, "Bundle", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "Bundle", err)
+	}
+
+	obj := &filestore.Object{
+		Key:         "passes" + pkpass.BundleExtension,
+		Body:        data,
+		ContentType: filestore.ApplePkpasses,
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", obj.Key))
+	err = obj.Serve(w)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "Serve", err)
+	}
+
+	return nil
+}
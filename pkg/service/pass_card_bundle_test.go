@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/filestore"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundlePassCardsHandler(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		UseSerials      bool
+		UseGrouping     bool
+		UnknownSerial   bool
+		UnknownGrouping bool
+		Expected        int
+	}{
+		{
+			Name:       "BySerialNumbers",
+			UseSerials: true,
+			Expected:   http.StatusOK,
+		},
+		{
+			Name:        "ByGroupingIdentifier",
+			UseGrouping: true,
+			Expected:    http.StatusOK,
+		},
+		{
+			Name:     "EmptyRequest",
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:        "SerialsAndGrouping",
+			UseSerials:  true,
+			UseGrouping: true,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:          "UnknownSerialNumber",
+			UseSerials:    true,
+			UnknownSerial: true,
+			Expected:      http.StatusNotFound,
+		},
+		{
+			Name:            "UnknownGroupingIdentifier",
+			UseGrouping:     true,
+			UnknownGrouping: true,
+			Expected:        http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Description:      fakeString(),
+				OrganizationName: fakeString(),
+				PassType:         api.EventTicket,
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var (
+				groupingID = fakeString()
+				req        BundlePassCardsRequest
+				expected   = map[string][]byte{}
+			)
+
+			for i := 0; i < 3; i++ {
+				passcard := fakePassCard(project)
+				passcard.Data.GroupingIdentifier = groupingID
+				err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+
+				obj := &filestore.Object{
+					Key:         passcard.Data.SerialNumber,
+					Body:        []byte(fakeString()),
+					ContentType: filestore.ApplePkpass,
+				}
+				err = srv.env.Storage.UploadFile(ctx, srv.env.Config.PassesBucket, obj)
+				if !assert.NoError(err) {
+					return
+				}
+
+				if tc.UseSerials {
+					req.SerialNumbers = append(req.SerialNumbers, passcard.Data.SerialNumber)
+				}
+				expected[passcard.Data.SerialNumber+pkpass.Extension] = obj.Body
+			}
+
+			if tc.UseGrouping {
+				req.GroupingIdentifier = groupingID
+			}
+			if tc.UnknownSerial {
+				req.SerialNumbers = append(req.SerialNumbers, fakeString())
+			}
+			if tc.UnknownGrouping {
+				req.GroupingIdentifier = fakeString()
+			}
+
+			body, err := json.Marshal(req)
+			if !assert.NoError(err) {
+				return
+			}
+
+			url := fmt.Sprintf("/projects/%d/cards/bundle", project.ID)
+			httpReq := authRequest(srv, user, newRequest("POST", url, body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, httpReq)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode == http.StatusOK {
+				assert.Equal(filestore.ApplePkpasses, resp.Header.Get("Content-Type"))
+
+				data, err := ioutil.ReadAll(resp.Body)
+				if !assert.NoError(err) {
+					return
+				}
+
+				files, err := pkpass.Unzip(data)
+				if !assert.NoError(err) {
+					return
+				}
+
+				actual := map[string][]byte{}
+				for _, file := range files {
+					actual[file.Name] = file.Data
+				}
+				assert.Equal(expected, actual)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 
@@ -38,6 +38,9 @@ type CreatePassCardRequest struct {
 	// Style Keys
 	Structure *api.PassStructure `json:"structure,omitempty"`
 
+	// Values of project template fields keyed by field key
+	Values map[string]interface{} `json:"values,omitempty"`
+
 	// Visual Appearance Keys
 	Barcodes           []*api.Barcode `json:"barcodes,omitempty"`
 	BackgroundColor    string         `json:"backgroundColor,omitempty"`
@@ -48,6 +51,9 @@ type CreatePassCardRequest struct {
 
 	// NFC-Enabled Pass Keys
 	NFC *api.NFC `json:"nfc,omitempty"`
+
+	// Localization Keys
+	Localizations api.Localizations `json:"localizations,omitempty"`
 }
 
 func (s *Service) createPassCardHandler(w http.ResponseWriter, r *http.Request) error {
@@ -93,6 +99,16 @@ func (s *Service) createPassCardHandler(w http.ResponseWriter, r *http.Request)
 		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
 	}
 
+	err = passcard.Data.CheckChangeMessages(nil)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	err = s.checkLocalizations(ctx, user, passcard.Localizations)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "CheckLocalizations", err)
+	}
+
 	err = s.env.Logic.SaveNewPassCard(ctx, project, passcard)
 	if err != nil {
 		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewPassCard", err)
@@ -107,25 +123,15 @@ func (s *Service) createPassCardHandler(w http.ResponseWriter, r *http.Request)
 		return s.httpError(w, r, http.StatusInternalServerError, "InsertPass", err)
 	}
 
-	upload, err := s.newPassUpload(ctx, project, passcard)
-	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "NewPassUpload", err)
-	}
-
-	err = s.env.Storage.UploadFile(ctx, s.env.Config.PassesBucket, upload)
+	err = s.uploadPass(ctx, project, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UploadFile", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UploadPass", err)
 	}
 
 	return sendJSON(w, http.StatusCreated, M{
 		"id":           passcard.ID,
 		"serialNumber": passcard.Data.SerialNumber,
-		"url": fmt.Sprintf(
-			"%s/downloads/%s%s",
-			s.hostURL(),
-			passcard.Data.SerialNumber,
-			pkpass.Extension,
-		),
+		"url":          s.downloadURL(passcard.Data.SerialNumber),
 	})
 }
 
@@ -157,126 +163,161 @@ func (s *Service) newProjectPassCard(req *CreatePassCardRequest, project *api.Pr
 		NFC:                 req.NFC,
 	}
 
-	data = s.setPassStructure(req, project.PassType, data)
-	err := data.IsValid()
+	structure, err := passStructure(req, project)
+	if err != nil {
+		return nil, err
+	}
+
+	data = s.setPassStructure(structure, project.PassType, data)
+	_, err = interpolatePassCard(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = data.IsValid()
 	if err != nil {
 		return nil, err
 	}
 
-	return api.NewPassCardInfo(data), nil
+	info := api.NewPassCardInfo(data)
+	info.Localizations = req.Localizations
+
+	return info, nil
 }
 
 func (s *Service) passTypeToString(passType api.PassType) string {
-	domainLayout := "pass.com.okpock.%s"
-	if s.env.Config.IsDevelopment() {
-		domainLayout += "-dev"
+	return s.env.Config.PassTypeID(passType)
+}
+
+// passStructure returns structure sent with request or, if project has
+// template, the one rendered from request values.
+func passStructure(req *CreatePassCardRequest, project *api.Project) (*api.PassStructure, error) {
+	if project.Template == nil {
+		if len(req.Values) > 0 {
+			return nil, errors.New("values: project has no template")
+		}
+		return req.Structure, nil
 	}
 
-	switch passType {
-	case api.BoardingPass:
-		return fmt.Sprintf(domainLayout, "boardingpass")
-	case api.Coupon:
-		return fmt.Sprintf(domainLayout, "coupon")
-	case api.EventTicket:
-		return fmt.Sprintf(domainLayout, "eventticket")
-	case api.Generic:
-		return fmt.Sprintf(domainLayout, "generic")
-	case api.StoreCard:
-		return fmt.Sprintf(domainLayout, "storecard")
+	if req.Structure != nil {
+		return nil, errors.New("structure: project has template, send values instead")
 	}
 
-	return ""
+	return project.Template.Render(req.Values)
 }
 
-func (s *Service) setPassStructure(req *CreatePassCardRequest, passType api.PassType, passCard *api.PassCard) *api.PassCard {
+func (s *Service) setPassStructure(structure *api.PassStructure, passType api.PassType, passCard *api.PassCard) *api.PassCard {
 	switch passType {
 	case api.BoardingPass:
-		passCard.BoardingPass = req.Structure
+		passCard.BoardingPass = structure
 	case api.Coupon:
-		passCard.Coupon = req.Structure
+		passCard.Coupon = structure
 	case api.EventTicket:
-		passCard.EventTicket = req.Structure
+		passCard.EventTicket = structure
 	case api.Generic:
-		passCard.Generic = req.Structure
+		passCard.Generic = structure
 	case api.StoreCard:
-		passCard.StoreCard = req.Structure
+		passCard.StoreCard = structure
 	}
 	return passCard
 }
 
-func (s *Service) newPassUpload(ctx context.Context, project *api.Project, passCard *api.PassCardInfo) (*filestore.Object, error) {
-	files := []pkpass.File{}
+type passImage struct {
+	key      string
+	filename string
+}
 
-	pass, err := json.Marshal(passCard.Data)
-	if err != nil {
-		return nil, err
+// passImages maps project image keys to bundle filenames of every size.
+func passImages(project *api.Project) []passImage {
+	return []passImage{
+		{project.BackgroundImage, pkpass.BackgroundFilename},
+		{project.BackgroundImage2x, pkpass.BackgroundFilename2x},
+		{project.BackgroundImage3x, pkpass.BackgroundFilename3x},
+		{project.FooterImage, pkpass.FooterFilename},
+		{project.FooterImage2x, pkpass.FooterFilename2x},
+		{project.FooterImage3x, pkpass.FooterFilename3x},
+		{project.IconImage, pkpass.IconFilename},
+		{project.IconImage2x, pkpass.IconFilename2x},
+		{project.IconImage3x, pkpass.IconFilename3x},
+		{project.LogoImage, pkpass.LogoFilename},
+		{project.LogoImage2x, pkpass.LogoFilename2x},
+		{project.LogoImage3x, pkpass.LogoFilename3x},
+		{project.StripImage, pkpass.StripFilename},
+		{project.StripImage2x, pkpass.StripFilename2x},
+		{project.StripImage3x, pkpass.StripFilename3x},
+		{project.ThumbnailImage, pkpass.ThumbnailFilename},
+		{project.ThumbnailImage2x, pkpass.ThumbnailFilename2x},
+		{project.ThumbnailImage3x, pkpass.ThumbnailFilename3x},
 	}
-	files = append(files, pkpass.NewFile(pkpass.PassFilename, pass))
+}
 
-	if project.BackgroundImage != "" {
-		background, err := s.env.Storage.GetFile(ctx, s.env.Config.UploadBucket, project.BackgroundImage)
-		if err != nil {
-			return nil, err
-		}
-		files = append(files, pkpass.NewFile(pkpass.BackgroundFilename, background.Body))
+// writePass streams signed pass bundle into w.
+func (s *Service) writePass(ctx context.Context, w io.Writer, project *api.Project, passCard *api.PassCardInfo) error {
+	signer, err := s.env.Signers.Get(s.passTypeToString(project.PassType))
+	if err != nil {
+		return err
 	}
 
-	if project.FooterImage != "" {
-		footer, err := s.env.Storage.GetFile(ctx, s.env.Config.UploadBucket, project.FooterImage)
-		if err != nil {
-			return nil, err
-		}
-		files = append(files, pkpass.NewFile(pkpass.FooterFilename, footer.Body))
+	data, err := interpolatePassCard(passCard.Data)
+	if err != nil {
+		return err
 	}
 
-	if project.IconImage != "" {
-		icon, err := s.env.Storage.GetFile(ctx, s.env.Config.UploadBucket, project.IconImage)
-		if err != nil {
-			return nil, err
-		}
-		files = append(files, pkpass.NewFile(pkpass.IconFilename, icon.Body))
+	pass, err := json.Marshal(data)
+	if err != nil {
+		return err
 	}
 
-	if project.LogoImage != "" {
-		logo, err := s.env.Storage.GetFile(ctx, s.env.Config.UploadBucket, project.LogoImage)
-		if err != nil {
-			return nil, err
-		}
-		files = append(files, pkpass.NewFile(pkpass.LogoFilename, logo.Body))
+	builder := pkpass.NewBuilder(w, signer)
+
+	err = builder.AddFile(pkpass.NewFile(pkpass.PassFilename, pass))
+	if err != nil {
+		return err
 	}
 
-	if project.StripImage != "" {
-		strip, err := s.env.Storage.GetFile(ctx, s.env.Config.UploadBucket, project.StripImage)
+	for _, image := range passImages(project) {
+		if image.key == "" {
+			continue
+		}
+		err = s.addUploadedFile(ctx, builder, image.filename, image.key)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		files = append(files, pkpass.NewFile(pkpass.StripFilename, strip.Body))
 	}
 
-	manifest, err := pkpass.CreateManifest(files...)
+	err = s.addLocalizedFiles(ctx, builder, project, passCard)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	files = append(files, *manifest)
 
-	if project.PassType != api.Coupon {
-		return nil, errors.New("pkpass: unsupported signer")
-	}
+	return builder.Close()
+}
 
-	signature, err := s.env.CouponSigner.Sign(manifest.Data)
+// addUploadedFile streams uploaded file from storage into pass bundle.
+func (s *Service) addUploadedFile(ctx context.Context, builder *pkpass.Builder, name, key string) error {
+	r, err := s.env.Storage.GetStream(ctx, s.env.Config.UploadBucket, key)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	files = append(files, *signature)
+	defer r.Close()
 
-	zip, err := pkpass.Zip(files...)
-	if err != nil {
-		return nil, err
-	}
+	return builder.Add(name, r)
+}
+
+// uploadPass builds pass bundle and streams it into passes bucket.
+func (s *Service) uploadPass(ctx context.Context, project *api.Project, passCard *api.PassCardInfo) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writePass(ctx, pw, project, passCard))
+	}()
 
-	return &filestore.Object{
+	obj := &filestore.Object{
 		Key:         passCard.Data.SerialNumber,
-		Body:        zip,
 		ContentType: filestore.ApplePkpass,
-	}, nil
+	}
+
+	err := s.env.Storage.UploadStream(ctx, s.env.Config.PassesBucket, obj, pr)
+	pr.CloseWithError(err)
+
+	return err
 }
@@ -1,9 +1,12 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +15,7 @@ import (
 
 	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/filestore"
+	"github.com/danikarik/okpock/pkg/pkpass"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -67,6 +71,45 @@ func TestCreatePassCardHandler(t *testing.T) {
 			},
 			Expected: http.StatusCreated,
 		},
+		{
+			Name:     "Localized",
+			PassType: api.Coupon,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{
+							Key:   "offer",
+							Label: "offer_label",
+							Value: "offer_value",
+						},
+					},
+				},
+				Localizations: api.Localizations{
+					"en": {Strings: map[string]string{"offer_label": "Offer", "offer_value": "20% off"}},
+					"ru": {Strings: map[string]string{"offer_label": "Скидка", "offer_value": "20%"}},
+				},
+			},
+			Expected: http.StatusCreated,
+		},
+		{
+			Name:     "InvalidLocalization",
+			PassType: api.Coupon,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{
+							Key:   "offer",
+							Label: "offer_label",
+							Value: "20% off",
+						},
+					},
+				},
+				Localizations: api.Localizations{
+					"en": {Images: map[string]string{pkpass.LogoFilename: "missing"}},
+				},
+			},
+			Expected: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -133,7 +176,262 @@ func TestCreatePassCardHandler(t *testing.T) {
 
 				assert.True(len(content) > 0)
 				assert.Equal(resp.Header.Get("Content-Type"), filestore.ApplePkpass)
+
+				files, err := pkpass.Unzip(content)
+				if !assert.NoError(err) {
+					return
+				}
+
+				bundle := map[string]bool{}
+				for _, file := range files {
+					bundle[file.Name] = true
+				}
+				for lang := range tc.Request.Localizations {
+					assert.True(bundle[lang+pkpass.LocalizationExtension+"/"+pkpass.StringsFilename])
+				}
+			}
+		})
+	}
+}
+
+func TestWritePassImages(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Project  func(keys map[string]string) *api.Project
+		Expected []string
+	}{
+		{
+			Name: "OnlyOriginalSize",
+			Project: func(keys map[string]string) *api.Project {
+				return &api.Project{
+					IconImage: keys[pkpass.IconFilename],
+					LogoImage: keys[pkpass.LogoFilename],
+				}
+			},
+			Expected: []string{
+				pkpass.IconFilename,
+				pkpass.LogoFilename,
+			},
+		},
+		{
+			Name: "AllSizes",
+			Project: func(keys map[string]string) *api.Project {
+				return &api.Project{
+					IconImage:    keys[pkpass.IconFilename],
+					IconImage2x:  keys[pkpass.IconFilename2x],
+					IconImage3x:  keys[pkpass.IconFilename3x],
+					StripImage:   keys[pkpass.StripFilename],
+					StripImage2x: keys[pkpass.StripFilename2x],
+					StripImage3x: keys[pkpass.StripFilename3x],
+				}
+			},
+			Expected: []string{
+				pkpass.IconFilename,
+				pkpass.IconFilename2x,
+				pkpass.IconFilename3x,
+				pkpass.StripFilename,
+				pkpass.StripFilename2x,
+				pkpass.StripFilename3x,
+			},
+		},
+		{
+			Name: "Thumbnail",
+			Project: func(keys map[string]string) *api.Project {
+				return &api.Project{
+					ThumbnailImage:   keys[pkpass.ThumbnailFilename],
+					ThumbnailImage2x: keys[pkpass.ThumbnailFilename2x],
+					ThumbnailImage3x: keys[pkpass.ThumbnailFilename3x],
+				}
+			},
+			Expected: []string{
+				pkpass.ThumbnailFilename,
+				pkpass.ThumbnailFilename2x,
+				pkpass.ThumbnailFilename3x,
+			},
+		},
+		{
+			Name: "RetinaOnly",
+			Project: func(keys map[string]string) *api.Project {
+				return &api.Project{
+					FooterImage2x:     keys[pkpass.FooterFilename2x],
+					BackgroundImage3x: keys[pkpass.BackgroundFilename3x],
+				}
+			},
+			Expected: []string{
+				pkpass.FooterFilename2x,
+				pkpass.BackgroundFilename3x,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			keys := map[string]string{}
+			for _, filename := range tc.Expected {
+				keys[filename] = fakeString()
+				err = srv.env.Storage.UploadFile(ctx, srv.env.Config.UploadBucket, &filestore.Object{
+					Key:         keys[filename],
+					Body:        []byte(filename),
+					ContentType: "image/png",
+				})
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			project := tc.Project(keys)
+			project.ID = fakeID()
+			project.PassType = api.Coupon
+
+			buf := new(bytes.Buffer)
+			err = srv.writePass(ctx, buf, project, fakePassCard(project))
+			if !assert.NoError(err) {
+				return
+			}
+
+			files, err := pkpass.Unzip(buf.Bytes())
+			if !assert.NoError(err) {
+				return
+			}
+
+			bundle := map[string]string{}
+			for _, file := range files {
+				bundle[file.Name] = string(file.Data)
+			}
+
+			assert.Len(bundle, len(tc.Expected)+3)
+			for _, filename := range tc.Expected {
+				assert.Equal(filename, bundle[filename])
 			}
 		})
 	}
 }
+
+func TestUploadPass(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		PassType api.PassType
+		Valid    bool
+	}{
+		{
+			Name:     "Coupon",
+			PassType: api.Coupon,
+			Valid:    true,
+		},
+		{
+			Name:     "UnsupportedSigner",
+			PassType: api.PassType("unknown"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Description:      fakeString(),
+				OrganizationName: fakeString(),
+				PassType:         tc.PassType,
+			}
+			passcard := fakePassCard(project)
+
+			err = srv.uploadPass(ctx, project, passcard)
+			if !tc.Valid {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			obj, err := srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(filestore.ApplePkpass, obj.ContentType)
+
+			files, err := pkpass.Unzip(obj.Body)
+			if !assert.NoError(err) {
+				return
+			}
+
+			names := []string{}
+			for _, file := range files {
+				names = append(names, file.Name)
+			}
+			assert.ElementsMatch([]string{pkpass.PassFilename, pkpass.ManifestFilename, pkpass.SignatureFilename}, names)
+		})
+	}
+}
+
+func BenchmarkWritePass(b *testing.B) {
+	ctx := context.Background()
+
+	srv, err := initService(b)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	keys := map[string]string{}
+	for _, filename := range []string{
+		pkpass.BackgroundFilename3x,
+		pkpass.FooterFilename3x,
+		pkpass.IconFilename3x,
+		pkpass.LogoFilename3x,
+		pkpass.StripFilename3x,
+		pkpass.ThumbnailFilename3x,
+	} {
+		data := make([]byte, 2<<20)
+		if _, err := io.ReadFull(rand.Reader, data); err != nil {
+			b.Fatal(err)
+		}
+		keys[filename] = fakeString()
+		err = srv.env.Storage.UploadFile(ctx, srv.env.Config.UploadBucket, &filestore.Object{
+			Key:         keys[filename],
+			Body:        data,
+			ContentType: "image/png",
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	project := &api.Project{
+		ID:                fakeID(),
+		Description:       fakeString(),
+		OrganizationName:  fakeString(),
+		PassType:          api.Coupon,
+		BackgroundImage3x: keys[pkpass.BackgroundFilename3x],
+		FooterImage3x:     keys[pkpass.FooterFilename3x],
+		IconImage3x:       keys[pkpass.IconFilename3x],
+		LogoImage3x:       keys[pkpass.LogoFilename3x],
+		StripImage3x:      keys[pkpass.StripFilename3x],
+		ThumbnailImage3x:  keys[pkpass.ThumbnailFilename3x],
+	}
+	passcard := fakePassCard(project)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err = srv.writePass(ctx, ioutil.Discard, project, passcard)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
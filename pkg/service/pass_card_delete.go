@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danikarik/mux"
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"go.uber.org/zap"
+)
+
+const purgeInterval = time.Hour
+
+// ErrPassCardArchived raised when archived pass card is changed.
+var ErrPassCardArchived = errors.New("pass card is archived")
+
+// BulkDeleteRequest holds filter of pass cards to be archived.
+type BulkDeleteRequest struct {
+	Filter *PassCardFilter `json:"filter"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *BulkDeleteRequest) IsValid() error {
+	if r.Filter == nil {
+		return errors.New("filter is required")
+	}
+	return r.Filter.IsValid()
+}
+
+// String returns string representation of struct.
+func (r *BulkDeleteRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *Service) deletePassCardHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	cardID, err := s.idFromRequest(r, "cardID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, cardID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	return s.applyPassCardDelete(w, r, project, passcard)
+}
+
+func (s *Service) deletePassCardBySerialNumberHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCardBySerialNumber(ctx, project, vars["serialNumber"])
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	return s.applyPassCardDelete(w, r, project, passcard)
+}
+
+// applyPassCardDelete archives pass card. Deleting archived pass card
+// changes nothing.
+func (s *Service) applyPassCardDelete(w http.ResponseWriter, r *http.Request, project *api.Project, passcard *api.PassCardInfo) error {
+	if passcard.IsArchived() {
+		setPassCardETag(w, passcard)
+		return sendJSON(w, http.StatusOK, passcard)
+	}
+
+	err := s.archivePassCard(r.Context(), project, passcard)
+	if err == store.ErrVersionMismatch {
+		return s.httpError(w, r, http.StatusPreconditionFailed, "ArchivePassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "ArchivePassCard", err)
+	}
+
+	setPassCardETag(w, passcard)
+	return sendJSON(w, http.StatusOK, passcard)
+}
+
+func (s *Service) bulkDeletePassCardsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req BulkDeleteRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	job, err := newJob(ctx, project, api.ArchiveJob, &jobParams{Filter: req.Filter})
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewJob", err)
+	}
+
+	err = s.env.Logic.SaveNewJob(ctx, project, job)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewJob", err)
+	}
+
+	return sendJSON(w, http.StatusAccepted, job)
+}
+
+// archiveLatestPassCard archives latest version of pass card unless it has
+// been archived in the meantime.
+func (s *Service) archiveLatestPassCard(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if err != nil {
+		return err
+	}
+	if passcard.IsArchived() {
+		return nil
+	}
+
+	return s.archivePassCard(ctx, project, passcard)
+}
+
+// archivePassCard voids pass card, so pass is shown as voided. Archived pass
+// card is hidden from lists, but PassKit rows and bundle are kept, so devices
+// could fetch it, until pass card is purged. Pass card is archived after
+// devices are notified, so failed pass card is notified again once archive
+// is retried.
+func (s *Service) archivePassCard(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+	err := s.voidPassCard(ctx, project, passcard)
+	if err != nil {
+		return err
+	}
+
+	return s.env.Logic.ArchivePassCard(ctx, passcard)
+}
+
+// voidPassCard rebuilds bundle with `voided` set and notifies registered
+// devices. Pass card is saved as voided, unless it is set already, only after
+// devices are notified, so failed pass card is voided again on retry.
+func (s *Service) voidPassCard(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+	data := *passcard.Data
+	data.Voided = true
+
+	voided := *passcard
+	voided.Data = &data
+
+	err := s.uploadPass(ctx, project, &voided)
+	if err != nil {
+		return err
+	}
+
+	err = s.updatePass(ctx, project.PassType, passcard.Data.SerialNumber)
+	if err != nil {
+		return err
+	}
+
+	if passcard.Data.Voided {
+		return nil
+	}
+
+	return s.env.Logic.UpdatePassCard(ctx, &data, passcard)
+}
+
+// purgeArchivedPassCards removes pass cards archived before given time
+// along with PassKit rows and bundle. Bundle is removed last, so it is never
+// missing for pass card still stored.
+func (s *Service) purgeArchivedPassCards(ctx context.Context, before time.Time) (int, error) {
+	passcards, err := loadPassCardPages(func(opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+		return s.env.Logic.LoadArchivedPassCards(ctx, before, opts)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, passcard := range passcards {
+		err = s.env.PassKit.DeletePass(ctx, passcard.Data.SerialNumber)
+		if err != nil {
+			return i, err
+		}
+
+		err = s.env.Logic.DeletePassCard(ctx, passcard)
+		if err != nil {
+			return i, err
+		}
+
+		err = s.env.Storage.DeleteFile(ctx, s.env.Config.PassesBucket, passcard.Data.SerialNumber)
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(passcards), nil
+}
+
+// RunPurger purges pass cards archived longer than `ArchiveTTL` ago
+// until context is canceled.
+func (s *Service) RunPurger(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.purgeArchivedPassCards(ctx, time.Now().Add(-s.env.Config.ArchiveTTL))
+		if err != nil {
+			s.logger.Error("purge_error", zap.Error(err), zap.Int("purged", purged))
+		} else if purged > 0 {
+			s.logger.Info("purge", zap.Int("purged", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
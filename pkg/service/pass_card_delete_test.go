@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeRegisteredPassCard(srv *Service, project *api.Project) (*api.PassCardInfo, error) {
+	ctx := context.Background()
+	passTypeID := srv.passTypeToString(project.PassType)
+
+	passcard := fakePassCard(project)
+	passcard.ID = 0
+	passcard.Data.PassTypeID = passTypeID
+	passcard.Data.Coupon = &api.PassStructure{
+		PrimaryFields: []*api.Field{
+			&api.Field{Key: "offer", Label: "OFFER", Value: "20% off"},
+		},
+	}
+
+	err := srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+	if err != nil {
+		return nil, err
+	}
+
+	err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, passcard.Data.AuthenticationToken, passTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return passcard, nil
+}
+
+func TestDeletePassCardHandler(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		BySerialNumber bool
+		Voided         bool
+		Archived       bool
+		Unknown        bool
+		Expected       int
+		Pushes         int
+	}{
+		{
+			Name:     "ByID",
+			Expected: http.StatusOK,
+			Pushes:   1,
+		},
+		{
+			Name:           "BySerialNumber",
+			BySerialNumber: true,
+			Expected:       http.StatusOK,
+			Pushes:         1,
+		},
+		{
+			Name:     "Voided",
+			Voided:   true,
+			Expected: http.StatusOK,
+			Pushes:   1,
+		},
+		{
+			Name:     "Archived",
+			Archived: true,
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "Unknown",
+			Unknown:  true,
+			Expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcard, err := fakeRegisteredPassCard(srv, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			if tc.Voided {
+				data := *passcard.Data
+				data.Voided = true
+				err = srv.env.Logic.UpdatePassCard(ctx, &data, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			if tc.Archived {
+				err = srv.env.Logic.ArchivePassCard(ctx, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			url := fmt.Sprintf("/projects/%d/cards/%d", project.ID, passcard.ID)
+			if tc.BySerialNumber {
+				url = fmt.Sprintf("/projects/%d/cards/%s", project.ID, passcard.Data.SerialNumber)
+			}
+			if tc.Unknown {
+				url = fmt.Sprintf("/projects/%d/cards/%d", project.ID, passcard.ID+100)
+			}
+
+			req := authRequest(srv, user, newRequest("DELETE", url, nil, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+			if assert.NoError(err) {
+				assert.Len(pushes, tc.Pushes)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			data := &api.PassCardInfo{}
+			err = unmarshalJSON(resp, data)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.True(data.IsArchived())
+			assert.Equal(passCardETag(data), resp.Header.Get("ETag"))
+
+			passcards, err := srv.env.Logic.LoadPassCards(ctx, project, nil)
+			if assert.NoError(err) {
+				assert.Len(passcards.Data, 0)
+			}
+
+			ok, err := srv.env.PassKit.FindPass(ctx, passcard.Data.SerialNumber, passcard.Data.AuthenticationToken, passcard.Data.PassTypeID)
+			if assert.NoError(err) {
+				assert.True(ok)
+			}
+
+			_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+			if tc.Voided {
+				assert.NoError(err)
+				assert.True(data.Data.Voided)
+				assert.Equal(passcard.Version, data.Version)
+			} else if tc.Pushes > 0 {
+				assert.NoError(err)
+				assert.True(data.Data.Voided)
+				assert.Equal(passcard.Version+1, data.Version)
+			} else {
+				assert.Error(err)
+				assert.Equal(passcard.Version, data.Version)
+			}
+		})
+	}
+}
+
+func TestBulkDeletePassCardsHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Request  func(passcards []*api.PassCardInfo) string
+		Expected int
+		Archived int
+	}{
+		{
+			Name: "SerialNumbers",
+			Request: func(passcards []*api.PassCardInfo) string {
+				return fmt.Sprintf(`{"filter":{"serialNumbers":["%s","%s","%s"]}}`,
+					passcards[0].Data.SerialNumber, passcards[1].Data.SerialNumber, fakeString())
+			},
+			Expected: http.StatusAccepted,
+			Archived: 2,
+		},
+		{
+			Name: "All",
+			Request: func(passcards []*api.PassCardInfo) string {
+				return `{"filter":{"all":true}}`
+			},
+			Expected: http.StatusAccepted,
+			Archived: 3,
+		},
+		{
+			Name: "FilterRequired",
+			Request: func(passcards []*api.PassCardInfo) string {
+				return `{"filter":{}}`
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "EmptySerialNumber",
+			Request: func(passcards []*api.PassCardInfo) string {
+				return `{"filter":{"serialNumbers":[""]}}`
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcards := []*api.PassCardInfo{}
+			for i := 0; i < 3; i++ {
+				passcard, err := fakeRegisteredPassCard(srv, project)
+				if !assert.NoError(err) {
+					return
+				}
+				passcards = append(passcards, passcard)
+			}
+
+			url := fmt.Sprintf("/projects/%d/cards", project.ID)
+			req := authRequest(srv, user, newRequest("DELETE", url, []byte(tc.Request(passcards)), nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+			if resp.StatusCode != http.StatusAccepted {
+				return
+			}
+
+			job := &api.Job{}
+			err = unmarshalJSON(resp, job)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.ArchiveJob, job.Kind)
+			assert.Equal(api.JobPending, job.Status)
+
+			job, err = waitJob(srv, user, project, job)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.JobFinished, job.Status)
+			assert.Equal(tc.Archived, job.Total)
+			assert.Equal(tc.Archived, job.Processed)
+			assert.Equal(0, job.Failed)
+
+			list, err := srv.env.Logic.LoadPassCards(ctx, project, nil)
+			if assert.NoError(err) {
+				assert.Len(list.Data, len(passcards)-tc.Archived)
+			}
+
+			for i, passcard := range passcards {
+				loaded, err := srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal(i < tc.Archived, loaded.IsArchived())
+				assert.Equal(i < tc.Archived, loaded.Data.Voided)
+
+				pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+				if assert.NoError(err) {
+					if i < tc.Archived {
+						assert.Len(pushes, 1)
+					} else {
+						assert.Len(pushes, 0)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPurgeArchivedPassCards(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	passcards := []*api.PassCardInfo{}
+	for i := 0; i < 2; i++ {
+		passcard, err := fakeRegisteredPassCard(srv, project)
+		if !assert.NoError(err) {
+			return
+		}
+		passcards = append(passcards, passcard)
+	}
+
+	archived, active := passcards[0], passcards[1]
+	err = srv.archivePassCard(ctx, project, archived)
+	if !assert.NoError(err) {
+		return
+	}
+
+	purged, err := srv.purgeArchivedPassCards(ctx, archived.ArchivedAt.Add(-time.Hour))
+	if assert.NoError(err) {
+		assert.Equal(0, purged)
+	}
+
+	purged, err = srv.purgeArchivedPassCards(ctx, time.Now())
+	if assert.NoError(err) {
+		assert.Equal(1, purged)
+	}
+
+	_, err = srv.env.Logic.LoadPassCard(ctx, project, archived.ID)
+	assert.Equal(store.ErrNotFound, err)
+
+	ok, err := srv.env.PassKit.FindPass(ctx, archived.Data.SerialNumber, archived.Data.AuthenticationToken, archived.Data.PassTypeID)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	ok, err = srv.env.PassKit.FindRegistrationBySerialNumber(ctx, archived.Data.SerialNumber)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, archived.Data.SerialNumber)
+	assert.Error(err)
+
+	_, err = srv.env.Logic.LoadPassCard(ctx, project, active.ID)
+	assert.NoError(err)
+
+	ok, err = srv.env.PassKit.FindRegistrationBySerialNumber(ctx, active.Data.SerialNumber)
+	if assert.NoError(err) {
+		assert.True(ok)
+	}
+}
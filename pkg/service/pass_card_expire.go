@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"go.uber.org/zap"
+)
+
+const expireInterval = time.Minute
+
+// expirePassCards voids pass cards whose `expirationDate` or project
+// expiration rule has passed by given time. Failed pass cards are logged
+// and retried on next sweep.
+func (s *Service) expirePassCards(ctx context.Context, now time.Time) (int, error) {
+	passcards, err := loadPassCardPages(func(opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+		return s.env.Logic.LoadExpiredPassCards(ctx, now, opts)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, passcard := range passcards {
+		ok, err := s.expirePassCard(ctx, passcard, now)
+		if err != nil {
+			s.logger.Warn("expire_item_failed",
+				zap.Error(err),
+				zap.String("serial_number", passcard.Data.SerialNumber))
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expirePassCard voids latest version of pass card, rebuilds bundle and
+// notifies registered devices, so Wallet shows pass as voided. Pass card
+// changed in the meantime so it is no longer expired is skipped.
+func (s *Service) expirePassCard(ctx context.Context, passcard *api.PassCardInfo, now time.Time) (bool, error) {
+	project, err := s.env.Logic.LoadPassCardProject(ctx, passcard)
+	if err != nil {
+		return false, err
+	}
+
+	passcard, err = s.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if err != nil {
+		return false, err
+	}
+	if !passcard.IsExpired(project, now) {
+		return false, nil
+	}
+
+	err = s.voidPassCard(ctx, project, passcard)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RunExpirySweeper voids expired pass cards until context is canceled.
+func (s *Service) RunExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := s.expirePassCards(ctx, time.Now())
+		if err != nil {
+			s.logger.Error("expire_error", zap.Error(err))
+		} else if expired > 0 {
+			s.logger.Info("expire", zap.Int("expired", expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
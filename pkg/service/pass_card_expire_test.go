@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpirePassCards(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		ExpirationDate  time.Duration
+		ExpireAfterDays int
+		Voided          bool
+		Expired         bool
+	}{
+		{
+			Name:           "ExpirationDate",
+			ExpirationDate: -time.Minute,
+			Expired:        true,
+		},
+		{
+			Name:           "FutureExpirationDate",
+			ExpirationDate: time.Hour,
+		},
+		{
+			Name:            "ExpireAfterDays",
+			ExpireAfterDays: 1,
+			Expired:         true,
+		},
+		{
+			Name:           "Voided",
+			ExpirationDate: -time.Minute,
+			Voided:         true,
+		},
+		{
+			Name: "NoExpiration",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			project.ExpireAfterDays = tc.ExpireAfterDays
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcard, err := fakeRegisteredPassCard(srv, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			now := time.Now()
+			if tc.ExpirationDate != 0 || tc.Voided {
+				data := *passcard.Data
+				data.Voided = tc.Voided
+				if tc.ExpirationDate != 0 {
+					data.ExpirationDate = now.Add(tc.ExpirationDate).Format(time.RFC3339)
+				}
+				err = srv.env.Logic.UpdatePassCard(ctx, &data, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+			if tc.ExpireAfterDays > 0 {
+				now = now.AddDate(0, 0, tc.ExpireAfterDays).Add(time.Minute)
+			}
+
+			expired, err := srv.expirePassCards(ctx, now)
+			if !assert.NoError(err) {
+				return
+			}
+
+			loaded, err := srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+			if !assert.NoError(err) {
+				return
+			}
+
+			pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+			if !assert.NoError(err) {
+				return
+			}
+
+			if !tc.Expired {
+				assert.Equal(0, expired)
+				assert.Equal(passcard.Version, loaded.Version)
+				assert.Len(pushes, 0)
+				return
+			}
+
+			assert.Equal(1, expired)
+			assert.True(loaded.Data.Voided)
+			assert.Equal(passcard.Version+1, loaded.Version)
+			assert.Len(pushes, 1)
+
+			_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+			assert.NoError(err)
+
+			expired, err = srv.expirePassCards(ctx, now)
+			if assert.NoError(err) {
+				assert.Equal(0, expired)
+			}
+		})
+	}
+}
+
+func TestExpirePassCardsRetry(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	passcard, err := fakeRegisteredPassCard(srv, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	now := time.Now()
+	data := *passcard.Data
+	data.ExpirationDate = now.Add(-time.Minute).Format(time.RFC3339)
+	err = srv.env.Logic.UpdatePassCard(ctx, &data, passcard)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// Devices could not be notified without PassKit rows.
+	err = srv.env.PassKit.DeletePass(ctx, passcard.Data.SerialNumber)
+	if !assert.NoError(err) {
+		return
+	}
+
+	expired, err := srv.expirePassCards(ctx, now)
+	if assert.NoError(err) {
+		assert.Equal(0, expired)
+	}
+
+	loaded, err := srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.False(loaded.Data.Voided)
+
+	passTypeID := srv.passTypeToString(project.PassType)
+	err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, passcard.Data.AuthenticationToken, passTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+	err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	expired, err = srv.expirePassCards(ctx, now)
+	if assert.NoError(err) {
+		assert.Equal(1, expired)
+	}
+
+	loaded, err = srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(loaded.Data.Voided)
+
+	pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+	if assert.NoError(err) {
+		assert.Len(pushes, 1)
+	}
+}
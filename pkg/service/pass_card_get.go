@@ -41,6 +41,7 @@ func (s *Service) projectPassCardHandler(w http.ResponseWriter, r *http.Request)
 		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
 	}
 
+	setPassCardETag(w, passcard)
 	return sendJSON(w, http.StatusOK, passcard)
 }
 
@@ -74,5 +75,6 @@ func (s *Service) projectPassCardBySerialNumberHandler(w http.ResponseWriter, r
 		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardBySerialNumber", err)
 	}
 
+	setPassCardETag(w, passcard)
 	return sendJSON(w, http.StatusOK, passcard)
 }
@@ -85,6 +85,7 @@ func TestProjectPassCardHandler(t *testing.T) {
 
 				assert.Equal(passcard.ID, data.ID)
 				assert.Equal(passcard.Data, data.Data)
+				assert.Equal(`"1"`, resp.Header.Get("ETag"))
 			}
 		})
 	}
@@ -164,6 +165,7 @@ func TestProjectPassCardBySerialNumberHandler(t *testing.T) {
 
 				assert.Equal(passcard.ID, data.ID)
 				assert.Equal(passcard.Data, data.Data)
+				assert.Equal(`"1"`, resp.Header.Get("ETag"))
 			}
 		})
 	}
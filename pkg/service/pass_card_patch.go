@@ -0,0 +1,413 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/danikarik/mux"
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// immutablePassKeys can not be changed by patch.
+var immutablePassKeys = []string{
+	"serialNumber",
+	"passTypeIdentifier",
+	"teamIdentifier",
+	"authenticationToken",
+	"webServiceURL",
+}
+
+// PassCardPatch holds changes of existing pass card.
+type PassCardPatch struct {
+	// Data is JSON merge patch (RFC 7396) of pass.json.
+	Data json.RawMessage `json:"data,omitempty"`
+	// Fields holds JSON merge patches of structure fields by key,
+	// `null` removes field.
+	Fields map[string]json.RawMessage `json:"fields,omitempty"`
+}
+
+// IsValid checks whether input is valid or not.
+func (p *PassCardPatch) IsValid() error {
+	if len(p.Data) == 0 && len(p.Fields) == 0 {
+		return errors.New("patch: data or fields is required")
+	}
+
+	if len(p.Data) > 0 {
+		doc, err := decodeJSONValue(p.Data)
+		if err != nil {
+			return fmt.Errorf("patch: data: %v", err)
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return errors.New("patch: data must be an object")
+		}
+		for _, key := range immutablePassKeys {
+			if _, ok := obj[key]; ok {
+				return fmt.Errorf("patch: %s can not be changed", key)
+			}
+		}
+	}
+
+	for key := range p.Fields {
+		if key == "" {
+			return errors.New("patch: field key is empty")
+		}
+	}
+
+	return nil
+}
+
+// String returns string representation of struct.
+func (p *PassCardPatch) String() string {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// patchPassCard returns copy of pass card data with patch applied.
+// Field values are interpolated like on pass card creation.
+func patchPassCard(data *api.PassCard, patch *PassCardPatch) (*api.PassCard, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(patch.Data) > 0 {
+		doc, err := decodeJSONValue(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := decodeJSONValue(patch.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err = json.Marshal(mergePatch(doc, p))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	patched := &api.PassCard{}
+	err = json.Unmarshal(raw, patched)
+	if err != nil {
+		return nil, fmt.Errorf("patch: %v", err)
+	}
+
+	if len(patch.Fields) > 0 {
+		_, structure, err := patched.Style()
+		if err != nil {
+			return nil, err
+		}
+
+		for key, fieldPatch := range patch.Fields {
+			err = patchField(structure, key, fieldPatch)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, err = interpolatePassCard(patched)
+	if err != nil {
+		return nil, err
+	}
+
+	err = patched.CheckChangeMessages(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+// patchField merges patch into structure field with given key.
+func patchField(structure *api.PassStructure, key string, patch json.RawMessage) error {
+	sections := []*[]*api.Field{
+		&structure.HeaderFields,
+		&structure.PrimaryFields,
+		&structure.SecondaryFields,
+		&structure.AuxiliaryFields,
+		&structure.BackFields,
+	}
+
+	for _, section := range sections {
+		for i, field := range *section {
+			if field.Key != key {
+				continue
+			}
+
+			p, err := decodeJSONValue(patch)
+			if err != nil {
+				return fmt.Errorf("patch: fields: %s: %v", key, err)
+			}
+
+			if p == nil {
+				*section = append((*section)[:i], (*section)[i+1:]...)
+				return nil
+			}
+
+			raw, err := json.Marshal(field)
+			if err != nil {
+				return err
+			}
+
+			doc, err := decodeJSONValue(raw)
+			if err != nil {
+				return err
+			}
+
+			raw, err = json.Marshal(mergePatch(doc, p))
+			if err != nil {
+				return err
+			}
+
+			patched := &api.Field{}
+			err = json.Unmarshal(raw, patched)
+			if err != nil {
+				return fmt.Errorf("patch: fields: %s: %v", key, err)
+			}
+			if patched.Key != key {
+				return fmt.Errorf("patch: fields: %s: key can not be changed", key)
+			}
+
+			(*section)[i] = patched
+			return nil
+		}
+	}
+
+	return fmt.Errorf("patch: fields: %s: not found", key)
+}
+
+// mergePatch applies JSON merge patch as described in RFC 7396.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergePatch(t[key], value)
+	}
+
+	return t
+}
+
+// decodeJSONValue decodes data keeping numbers as is.
+func decodeJSONValue(data []byte) (interface{}, error) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// readPassCardPatch reads `application/merge-patch+json` body as merge patch
+// of pass.json, other bodies are read as `PassCardPatch`.
+func readPassCardPatch(r *http.Request) (*PassCardPatch, error) {
+	patch := &PassCardPatch{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchContentType {
+		err := readJSON(r, patch)
+		if err != nil {
+			return nil, err
+		}
+		return patch, nil
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	patch.Data = data
+
+	err = patch.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	return patch, nil
+}
+
+func (s *Service) patchPassCardHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	cardID, err := s.idFromRequest(r, "cardID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, cardID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	return s.applyPassCardPatch(w, r, project, passcard)
+}
+
+func (s *Service) patchPassCardBySerialNumberHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCardBySerialNumber(ctx, project, vars["serialNumber"])
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	return s.applyPassCardPatch(w, r, project, passcard)
+}
+
+// applyPassCardPatch validates merged pass card, then publishes it, so
+// `changeMessage` of changed fields is shown.
+func (s *Service) applyPassCardPatch(w http.ResponseWriter, r *http.Request, project *api.Project, passcard *api.PassCardInfo) error {
+	if passcard.IsArchived() {
+		return s.httpError(w, r, http.StatusConflict, "LoadPassCard", ErrPassCardArchived)
+	}
+
+	err := checkIfMatch(r, passcard)
+	if err != nil {
+		return s.ifMatchError(w, r, err)
+	}
+
+	patch, err := readPassCardPatch(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPassCardPatch", err)
+	}
+
+	data, err := patchPassCard(passcard.Data, patch)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "PatchPassCard", err)
+	}
+
+	err = data.IsValid()
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "PatchPassCard", err)
+	}
+
+	return s.publishPassCardChanges(w, r, project, passcard, data)
+}
+
+// publishPassCardChanges stores new data of pass card, rebuilds bundle and
+// notifies registered devices. Data without changes does not notify devices.
+func (s *Service) publishPassCardChanges(w http.ResponseWriter, r *http.Request, project *api.Project, passcard *api.PassCardInfo, data *api.PassCard) error {
+	ctx := r.Context()
+
+	changed, err := isPassCardChanged(passcard.Data, data)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "IsPassCardChanged", err)
+	}
+	if !changed {
+		setPassCardETag(w, passcard)
+		return sendJSON(w, http.StatusOK, passcard)
+	}
+
+	err = s.env.Logic.UpdatePassCard(ctx, data, passcard)
+	if err == store.ErrVersionMismatch {
+		return s.httpError(w, r, http.StatusPreconditionFailed, "UpdatePassCard", err)
+	}
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusConflict, "UpdatePassCard", ErrPassCardArchived)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePassCard", err)
+	}
+
+	err = s.uploadPass(ctx, project, passcard)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "UploadPass", err)
+	}
+
+	err = s.updatePass(ctx, project.PassType, passcard.Data.SerialNumber)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePass", err)
+	}
+
+	setPassCardETag(w, passcard)
+	return sendJSON(w, http.StatusOK, passcard)
+}
+
+// isPassCardChanged compares JSON representation of pass cards.
+func isPassCardChanged(prev, next *api.PassCard) (bool, error) {
+	a, err := json.Marshal(prev)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := json.Marshal(next)
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(a, b), nil
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchPassCardHandler(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		ContentType    string
+		BySerialNumber bool
+		IfMatch        string
+		Archived       bool
+		Body           string
+		Expected       int
+		Pushes         int
+		Check          func(assert *assert.Assertions, data *api.PassCard)
+	}{
+		{
+			Name:        "MergePatch",
+			ContentType: mergePatchContentType,
+			Body:        `{"logoText":"Sale","userInfo":{"tier":null,"visits":2}}`,
+			Expected:    http.StatusOK,
+			Pushes:      1,
+			Check: func(assert *assert.Assertions, data *api.PassCard) {
+				assert.Equal("Sale", data.LogoText)
+				assert.Equal(api.JSONMap{"visits": float64(2)}, data.UserInfo)
+				assert.Len(data.Coupon.PrimaryFields, 1)
+				assert.Len(data.Coupon.BackFields, 1)
+			},
+		},
+		{
+			Name:           "FieldBySerialNumber",
+			ContentType:    "application/json",
+			BySerialNumber: true,
+			Body:           `{"fields":{"offer":{"value":"30% off","changeMessage":"Offer is %@"}}}`,
+			Expected:       http.StatusOK,
+			Pushes:         1,
+			Check: func(assert *assert.Assertions, data *api.PassCard) {
+				field := data.Coupon.PrimaryFields[0]
+				assert.Equal("offer", field.Key)
+				assert.Equal("OFFER", field.Label)
+				assert.Equal("30% off", field.Value)
+				assert.Equal("Offer is %@", field.ChangeMessage)
+				assert.Equal("Coupon", data.LogoText)
+			},
+		},
+		{
+			Name:        "RemoveField",
+			ContentType: "application/json",
+			Body:        `{"fields":{"terms":null}}`,
+			Expected:    http.StatusOK,
+			Pushes:      1,
+			Check: func(assert *assert.Assertions, data *api.PassCard) {
+				assert.Len(data.Coupon.BackFields, 0)
+				assert.Len(data.Coupon.PrimaryFields, 1)
+			},
+		},
+		{
+			Name:        "NotChanged",
+			ContentType: mergePatchContentType,
+			Body:        `{"logoText":"Coupon"}`,
+			Expected:    http.StatusOK,
+			Check: func(assert *assert.Assertions, data *api.PassCard) {
+				assert.Equal("Coupon", data.LogoText)
+			},
+		},
+		{
+			Name:        "InvalidChangeMessage",
+			ContentType: "application/json",
+			Body:        `{"fields":{"offer":{"value":"30% off","changeMessage":"Offer changed"}}}`,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "LegacyChangeMessage",
+			ContentType: "application/json",
+			Body:        `{"fields":{"terms":{"value":"Single use"}}}`,
+			Expected:    http.StatusOK,
+			Pushes:      1,
+			Check: func(assert *assert.Assertions, data *api.PassCard) {
+				field := data.Coupon.BackFields[0]
+				assert.Equal("Single use", field.Value)
+				assert.Equal("Terms changed", field.ChangeMessage)
+			},
+		},
+		{
+			Name:        "InvalidLegacyChangeMessage",
+			ContentType: "application/json",
+			Body:        `{"fields":{"terms":{"changeMessage":"Terms are updated"}}}`,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "InvalidMergedResult",
+			ContentType: mergePatchContentType,
+			Body:        `{"description":null}`,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "UnknownField",
+			ContentType: "application/json",
+			Body:        `{"fields":{"points":{"value":10}}}`,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "StaleVersion",
+			ContentType: mergePatchContentType,
+			IfMatch:     `"2"`,
+			Body:        `{"logoText":"Sale"}`,
+			Expected:    http.StatusPreconditionFailed,
+		},
+		{
+			Name:        "MissingIfMatch",
+			ContentType: mergePatchContentType,
+			IfMatch:     " ",
+			Body:        `{"logoText":"Sale"}`,
+			Expected:    http.StatusPreconditionRequired,
+		},
+		{
+			Name:        "ImmutableKey",
+			ContentType: mergePatchContentType,
+			Body:        `{"authenticationToken":"token"}`,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "NotObject",
+			ContentType: mergePatchContentType,
+			Body:        `["logoText"]`,
+			Expected:    http.StatusBadRequest,
+		},
+		{
+			Name:        "Archived",
+			ContentType: mergePatchContentType,
+			Archived:    true,
+			Body:        `{"logoText":"Sale"}`,
+			Expected:    http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Title:            fakeString(),
+				OrganizationName: fakeString(),
+				Description:      fakeString(),
+				PassType:         api.Coupon,
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passTypeID := srv.passTypeToString(project.PassType)
+
+			passcard := fakePassCard(project)
+			passcard.Data.PassTypeID = passTypeID
+			passcard.Data.LogoText = "Coupon"
+			passcard.Data.UserInfo = api.JSONMap{"tier": "gold", "visits": 1}
+			passcard.Data.Coupon = &api.PassStructure{
+				PrimaryFields: []*api.Field{
+					&api.Field{Key: "offer", Label: "OFFER", Value: "20% off"},
+				},
+				BackFields: []*api.Field{
+					// Stored before `changeMessage` had to contain `%@`.
+					&api.Field{Key: "terms", Label: "Terms", Value: "No cash value", ChangeMessage: "Terms changed"},
+				},
+			}
+
+			err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+			if !assert.NoError(err) {
+				return
+			}
+
+			if tc.Archived {
+				err = srv.env.Logic.ArchivePassCard(ctx, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, passcard.Data.AuthenticationToken, passTypeID)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+			if !assert.NoError(err) {
+				return
+			}
+
+			url := fmt.Sprintf("/projects/%d/cards/%d", project.ID, passcard.ID)
+			if tc.BySerialNumber {
+				url = fmt.Sprintf("/projects/%d/cards/%s", project.ID, passcard.Data.SerialNumber)
+			}
+
+			ifMatch := tc.IfMatch
+			if ifMatch == "" {
+				ifMatch = passCardETag(passcard)
+			}
+
+			headers := map[string]string{"Content-Type": tc.ContentType, "If-Match": ifMatch}
+			req := authRequest(srv, user, newRequest("PATCH", url, []byte(tc.Body), headers, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+			if assert.NoError(err) {
+				assert.Len(pushes, tc.Pushes)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			data := &api.PassCardInfo{}
+			err = unmarshalJSON(resp, data)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(passcard.ID, data.ID)
+			assert.Equal(passcard.Data.SerialNumber, data.Data.SerialNumber)
+			assert.Equal(passCardETag(data), resp.Header.Get("ETag"))
+			tc.Check(assert, data.Data)
+
+			_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+			if tc.Pushes > 0 {
+				assert.NoError(err)
+				assert.Equal(passcard.Version+1, data.Version)
+			} else {
+				assert.Error(err)
+				assert.Equal(passcard.Version, data.Version)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassCardPushesHandler(t *testing.T) {
+	testCases := []struct {
+		Name         string
+		SavePassCard bool
+		Pushes       int
+		Expected     int
+	}{
+		{
+			Name:         "WithPushes",
+			SavePassCard: true,
+			Pushes:       2,
+			Expected:     http.StatusOK,
+		},
+		{
+			Name:         "WithoutPushes",
+			SavePassCard: true,
+			Expected:     http.StatusOK,
+		},
+		{
+			Name:     "NotFound",
+			Expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Description:      fakeString(),
+				OrganizationName: fakeString(),
+				PassType:         api.Coupon,
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcard := fakePassCard(project)
+			if tc.SavePassCard {
+				err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			for i := 0; i < tc.Pushes; i++ {
+				push := api.NewPush(passcard.Data.SerialNumber, srv.passTypeToString(project.PassType), fakeString())
+				err = srv.env.PassKit.InsertPush(ctx, push)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			url := fmt.Sprintf("/projects/%d/cards/%d/pushes", project.ID, passcard.ID)
+			req := authRequest(srv, user, newRequest("GET", url, nil, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode == http.StatusOK {
+				var data struct {
+					Data []*api.Push `json:"data"`
+				}
+				err = unmarshalJSON(resp, &data)
+				if !assert.NoError(err) {
+					return
+				}
+
+				assert.Len(data.Data, tc.Pushes)
+				for _, push := range data.Data {
+					assert.Equal(passcard.Data.SerialNumber, push.SerialNumber)
+					assert.Equal(api.PushPending, push.Status)
+				}
+			}
+		})
+	}
+}
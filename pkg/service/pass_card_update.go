@@ -43,12 +43,13 @@ func (s *Service) updatePassCardHandler(w http.ResponseWriter, r *http.Request)
 		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
 	}
 
-	pushToken, err := s.env.PassKit.FindPushToken(ctx, passcard.Data.SerialNumber)
-	if err == store.ErrNotFound {
-		return s.httpError(w, r, http.StatusNotFound, "FindPushToken", err)
+	if passcard.IsArchived() {
+		return s.httpError(w, r, http.StatusConflict, "LoadPassCard", ErrPassCardArchived)
 	}
+
+	err = checkIfMatch(r, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "FindPushToken", err)
+		return s.ifMatchError(w, r, err)
 	}
 
 	var req CreatePassCardRequest
@@ -73,36 +74,43 @@ func (s *Service) updatePassCardHandler(w http.ResponseWriter, r *http.Request)
 		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
 	}
 
-	err = s.env.Logic.UpdatePassCard(ctx, newPasscard.Data, passcard)
+	err = newPasscard.Data.CheckChangeMessages(passcard.Data)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePassCard", err)
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
 	}
 
-	err = s.env.PassKit.UpdatePass(ctx, passcard.Data.SerialNumber)
+	err = s.checkLocalizations(ctx, user, newPasscard.Localizations)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePass", err)
+		return s.httpError(w, r, http.StatusBadRequest, "CheckLocalizations", err)
 	}
 
-	notificator, err := s.getNotificator(project.PassType)
+	err = s.env.Logic.UpdatePassCard(ctx, newPasscard.Data, passcard)
+	if err == store.ErrVersionMismatch {
+		return s.httpError(w, r, http.StatusPreconditionFailed, "UpdatePassCard", err)
+	}
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusConflict, "UpdatePassCard", ErrPassCardArchived)
+	}
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "GetNotificator", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePassCard", err)
 	}
 
-	upload, err := s.newPassUpload(ctx, project, passcard)
+	err = s.env.Logic.SetPassCardLocalizations(ctx, newPasscard.Localizations, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "NewPassUpload", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "SetPassCardLocalizations", err)
 	}
 
-	err = s.env.Storage.UploadFile(ctx, s.env.Config.PassesBucket, upload)
+	err = s.uploadPass(ctx, project, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UploadFile", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UploadPass", err)
 	}
 
-	err = notificator.Push(ctx, pushToken)
+	err = s.updatePass(ctx, project.PassType, passcard.Data.SerialNumber)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "Push", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePass", err)
 	}
 
+	setPassCardETag(w, passcard)
 	return sendJSON(w, http.StatusOK, passcard)
 }
 
@@ -136,12 +144,13 @@ func (s *Service) updatePassCardBySerialNumberHandler(w http.ResponseWriter, r *
 		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
 	}
 
-	pushToken, err := s.env.PassKit.FindPushToken(ctx, vars["serialNumber"])
-	if err == store.ErrNotFound {
-		return s.httpError(w, r, http.StatusNotFound, "FindPushToken", err)
+	if passcard.IsArchived() {
+		return s.httpError(w, r, http.StatusConflict, "LoadPassCard", ErrPassCardArchived)
 	}
+
+	err = checkIfMatch(r, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "FindPushToken", err)
+		return s.ifMatchError(w, r, err)
 	}
 
 	var req CreatePassCardRequest
@@ -166,35 +175,42 @@ func (s *Service) updatePassCardBySerialNumberHandler(w http.ResponseWriter, r *
 		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
 	}
 
-	err = s.env.Logic.UpdatePassCard(ctx, newPasscard.Data, passcard)
+	err = newPasscard.Data.CheckChangeMessages(passcard.Data)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePassCard", err)
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
 	}
 
-	err = s.env.PassKit.UpdatePass(ctx, passcard.Data.SerialNumber)
+	err = s.checkLocalizations(ctx, user, newPasscard.Localizations)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePass", err)
+		return s.httpError(w, r, http.StatusBadRequest, "CheckLocalizations", err)
 	}
 
-	upload, err := s.newPassUpload(ctx, project, passcard)
+	err = s.env.Logic.UpdatePassCard(ctx, newPasscard.Data, passcard)
+	if err == store.ErrVersionMismatch {
+		return s.httpError(w, r, http.StatusPreconditionFailed, "UpdatePassCard", err)
+	}
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusConflict, "UpdatePassCard", ErrPassCardArchived)
+	}
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "NewPassUpload", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePassCard", err)
 	}
 
-	err = s.env.Storage.UploadFile(ctx, s.env.Config.PassesBucket, upload)
+	err = s.env.Logic.SetPassCardLocalizations(ctx, newPasscard.Localizations, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "UploadFile", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "SetPassCardLocalizations", err)
 	}
 
-	notificator, err := s.getNotificator(project.PassType)
+	err = s.uploadPass(ctx, project, passcard)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "GetNotificator", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UploadPass", err)
 	}
 
-	err = notificator.Push(ctx, pushToken)
+	err = s.updatePass(ctx, project.PassType, passcard.Data.SerialNumber)
 	if err != nil {
-		return s.httpError(w, r, http.StatusInternalServerError, "Push", err)
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdatePass", err)
 	}
 
+	setPassCardETag(w, passcard)
 	return sendJSON(w, http.StatusOK, passcard)
 }
@@ -16,10 +16,15 @@ func TestUpdatePassCardHandler(t *testing.T) {
 	testCases := []struct {
 		Name            string
 		UseSerialNumber bool
+		IfMatch         string
+		Archived        bool
 		Request         *CreatePassCardRequest
+		Expected        int
 	}{
 		{
-			Name: "UpdateByID",
+			Name:     "UpdateByID",
+			IfMatch:  `"1"`,
+			Expected: http.StatusOK,
 			Request: &CreatePassCardRequest{
 				Structure: &api.PassStructure{
 					BackFields: []*api.Field{
@@ -35,6 +40,8 @@ func TestUpdatePassCardHandler(t *testing.T) {
 		{
 			Name:            "UpdateBySerialNumber",
 			UseSerialNumber: true,
+			IfMatch:         `"0", "1"`,
+			Expected:        http.StatusOK,
 			Request: &CreatePassCardRequest{
 				Structure: &api.PassStructure{
 					AuxiliaryFields: []*api.Field{
@@ -49,6 +56,66 @@ func TestUpdatePassCardHandler(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:     "AnyVersion",
+			IfMatch:  "*",
+			Expected: http.StatusOK,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Value: "20% off"},
+					},
+				},
+			},
+		},
+		{
+			Name:     "StaleVersion",
+			IfMatch:  `"2"`,
+			Expected: http.StatusPreconditionFailed,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Value: "20% off"},
+					},
+				},
+			},
+		},
+		{
+			Name:     "WeakVersion",
+			IfMatch:  `W/"1"`,
+			Expected: http.StatusPreconditionFailed,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Value: "20% off"},
+					},
+				},
+			},
+		},
+		{
+			Name:     "MissingIfMatch",
+			Expected: http.StatusPreconditionRequired,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Value: "20% off"},
+					},
+				},
+			},
+		},
+		{
+			Name:     "Archived",
+			IfMatch:  `"1"`,
+			Archived: true,
+			Expected: http.StatusConflict,
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Value: "20% off"},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -86,6 +153,13 @@ func TestUpdatePassCardHandler(t *testing.T) {
 				return
 			}
 
+			if tc.Archived {
+				err = srv.env.Logic.ArchivePassCard(ctx, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
 			err = srv.env.PassKit.InsertPass(ctx,
 				passcard.Data.SerialNumber,
 				fakeString(),
@@ -95,13 +169,15 @@ func TestUpdatePassCardHandler(t *testing.T) {
 				return
 			}
 
-			err = srv.env.PassKit.InsertRegistration(ctx,
-				fakeString(),
-				fakeString(),
-				passcard.Data.SerialNumber,
-				srv.passTypeToString(project.PassType))
-			if !assert.NoError(err) {
-				return
+			for i := 0; i < 2; i++ {
+				err = srv.env.PassKit.InsertRegistration(ctx,
+					fakeString(),
+					fakeString(),
+					passcard.Data.SerialNumber,
+					srv.passTypeToString(project.PassType))
+				if !assert.NoError(err) {
+					return
+				}
 			}
 
 			body, err := json.Marshal(tc.Request)
@@ -113,26 +189,45 @@ func TestUpdatePassCardHandler(t *testing.T) {
 			if tc.UseSerialNumber {
 				url = fmt.Sprintf("/projects/%d/cards/%s", project.ID, passcard.Data.SerialNumber)
 			}
-			req := authRequest(srv, user, newRequest("PUT", url, body, nil, nil))
+			headers := map[string]string{"If-Match": tc.IfMatch}
+			req := authRequest(srv, user, newRequest("PUT", url, body, headers, nil))
 			rec := httptest.NewRecorder()
 
 			srv.ServeHTTP(rec, req)
 			resp := rec.Result()
 
-			if !assert.Equal(http.StatusOK, resp.StatusCode) {
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
 				return
 			}
 
-			if resp.StatusCode == http.StatusOK {
-				data := &api.PassCardInfo{}
-
-				err = unmarshalJSON(resp, &data)
-				if !assert.NoError(err) {
-					return
+			if resp.StatusCode != http.StatusOK {
+				pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+				if assert.NoError(err) {
+					assert.Len(pushes, 0)
 				}
+				return
+			}
 
-				assert.True(data.ID > 0)
-				assert.Equal(tc.Request.Structure, data.Data.Coupon)
+			data := &api.PassCardInfo{}
+			err = unmarshalJSON(resp, &data)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.True(data.ID > 0)
+			assert.Equal(tc.Request.Structure, data.Data.Coupon)
+			assert.Equal(int64(2), data.Version)
+			assert.Equal(`"2"`, resp.Header.Get("ETag"))
+
+			pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+			if !assert.NoError(err) {
+				return
+			}
+			if assert.Len(pushes, 2) {
+				for _, push := range pushes {
+					assert.Equal(api.PushPending, push.Status)
+					assert.Equal(srv.passTypeToString(project.PassType), push.PassTypeID)
+				}
 			}
 		})
 	}
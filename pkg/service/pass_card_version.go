@@ -0,0 +1,330 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// ErrInvalidVersion raised when version query parameter is not a positive number.
+var ErrInvalidVersion = errors.New("version: invalid query parameter")
+
+// PassCardDiff holds field by field difference of two pass card versions.
+type PassCardDiff struct {
+	From    int64             `json:"from"`
+	To      int64             `json:"to"`
+	Changes []*PassCardChange `json:"changes"`
+}
+
+// PassCardChange holds change of single value of pass card. Missing `from`
+// means value was added, missing `to` means value was removed.
+type PassCardChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+func (s *Service) passCardVersionsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	cardID, err := s.idFromRequest(r, "cardID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, cardID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	opts, err := readPagingOptions(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPagingOptions", err)
+	}
+
+	versions, err := s.env.Logic.LoadPassCardVersions(ctx, passcard, opts)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardVersions", err)
+	}
+
+	return sendPaginatedJSON(w, http.StatusOK, versions.Opts, versions.Data)
+}
+
+func (s *Service) passCardVersionsDiffHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	cardID, err := s.idFromRequest(r, "cardID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, cardID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	to, err := versionFromQuery(r, "to", passcard.Version)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "VersionFromQuery", err)
+	}
+
+	from, err := versionFromQuery(r, "from", to-1)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "VersionFromQuery", err)
+	}
+
+	prev, err := s.env.Logic.LoadPassCardVersion(ctx, passcard, from)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCardVersion", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardVersion", err)
+	}
+
+	next, err := s.env.Logic.LoadPassCardVersion(ctx, passcard, to)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCardVersion", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardVersion", err)
+	}
+
+	changes, err := diffPassCards(prev.Data, next.Data)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "DiffPassCards", err)
+	}
+
+	return sendJSON(w, http.StatusOK, &PassCardDiff{From: from, To: to, Changes: changes})
+}
+
+func (s *Service) rollbackPassCardHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	cardID, err := s.idFromRequest(r, "cardID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, cardID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	if passcard.IsArchived() {
+		return s.httpError(w, r, http.StatusConflict, "LoadPassCard", ErrPassCardArchived)
+	}
+
+	err = checkIfMatch(r, passcard)
+	if err != nil {
+		return s.ifMatchError(w, r, err)
+	}
+
+	versionID, err := s.idFromRequest(r, "version")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	version, err := s.env.Logic.LoadPassCardVersion(ctx, passcard, versionID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCardVersion", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCardVersion", err)
+	}
+
+	data := *version.Data
+	return s.publishPassCardChanges(w, r, project, passcard, &data)
+}
+
+// versionFromQuery reads version from query parameter, returns fallback
+// if parameter is missing.
+func versionFromQuery(r *http.Request, key string, fallback int64) (int64, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version < 1 {
+		return 0, ErrInvalidVersion
+	}
+
+	return version, nil
+}
+
+// diffPassCards compares pass cards value by value. Structure fields and
+// other items having `key` are addressed by key, e.g.
+// `coupon.primaryFields[offer].value`.
+func diffPassCards(prev, next *api.PassCard) ([]*PassCardChange, error) {
+	a, err := flattenPassCard(prev)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := flattenPassCard(next)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for path := range a {
+		paths = append(paths, path)
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := []*PassCardChange{}
+	for _, path := range paths {
+		from, okFrom := a[path]
+		to, okTo := b[path]
+		if okFrom && okTo && reflect.DeepEqual(from, to) {
+			continue
+		}
+		changes = append(changes, &PassCardChange{Path: path, From: from, To: to})
+	}
+
+	return changes, nil
+}
+
+func flattenPassCard(data *api.PassCard) (map[string]interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decodeJSONValue(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	flattenJSONValue(values, "", doc)
+
+	return values, nil
+}
+
+func flattenJSONValue(values map[string]interface{}, path string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			values[path] = t
+			return
+		}
+		for key, value := range t {
+			if path == "" {
+				flattenJSONValue(values, key, value)
+			} else {
+				flattenJSONValue(values, path+"."+key, value)
+			}
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			values[path] = t
+			return
+		}
+		keyed := itemKeys(t)
+		for i, value := range t {
+			if keyed != nil {
+				flattenJSONValue(values, path+"["+keyed[i]+"]", value)
+			} else {
+				flattenJSONValue(values, path+"["+strconv.Itoa(i)+"]", value)
+			}
+		}
+	default:
+		values[path] = v
+	}
+}
+
+// itemKeys returns keys of array items, nil if any item has no unique `key`.
+func itemKeys(items []interface{}) []string {
+	keys := make([]string, len(items))
+	seen := map[string]bool{}
+
+	for i, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		key, ok := obj["key"].(string)
+		if !ok || key == "" || seen[key] {
+			return nil
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+
+	return keys
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeVersionedPassCard(srv *Service, user *api.User) (*api.Project, *api.PassCardInfo, error) {
+	ctx := context.Background()
+
+	project := &api.Project{
+		ID:               fakeID(),
+		Title:            fakeString(),
+		OrganizationName: fakeString(),
+		Description:      fakeString(),
+		PassType:         api.Coupon,
+	}
+
+	err := srv.env.Logic.SaveNewProject(ctx, user, project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	passTypeID := srv.passTypeToString(project.PassType)
+
+	passcard := fakePassCard(project)
+	passcard.Data.PassTypeID = passTypeID
+	passcard.Data.LogoText = "Coupon"
+	passcard.Data.Coupon = &api.PassStructure{
+		PrimaryFields: []*api.Field{
+			&api.Field{Key: "offer", Label: "OFFER", Value: "20% off"},
+		},
+	}
+
+	err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, passcard.Data.AuthenticationToken, passTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, patch := range []struct{ ContentType, Body string }{
+		{mergePatchContentType, `{"logoText":"Sale"}`},
+		{"application/json", `{"fields":{"offer":{"value":"30% off"}}}`},
+	} {
+		path := fmt.Sprintf("/projects/%d/cards/%d", project.ID, passcard.ID)
+		headers := map[string]string{"Content-Type": patch.ContentType, "If-Match": "*"}
+
+		req := authRequest(srv, user, newRequest("PATCH", path, []byte(patch.Body), headers, nil))
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, nil, fmt.Errorf("patch: unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	passcard, err = srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, passcard, nil
+}
+
+func TestPassCardVersionsHandler(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project, passcard, err := fakeVersionedPassCard(srv, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	path := fmt.Sprintf("/projects/%d/cards/%d/versions", project.ID, passcard.ID)
+	req := authRequest(srv, user, newRequest("GET", path, nil, nil, nil))
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	resp := rec.Result()
+
+	if !assert.Equal(http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	var page struct {
+		Data []*api.PassCardVersion `json:"data"`
+	}
+	err = unmarshalJSON(resp, &page)
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(page.Data, 3) {
+		assert.Equal(int64(3), page.Data[0].Version)
+		assert.Equal(user.ID, page.Data[0].UserID)
+		assert.NotEmpty(page.Data[0].RequestID)
+		assert.Equal("Sale", page.Data[1].Data.LogoText)
+		assert.Equal(int64(1), page.Data[2].Version)
+	}
+}
+
+func TestPassCardVersionsDiffHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Query    url.Values
+		Expected int
+		Changes  []*PassCardChange
+	}{
+		{
+			Name:     "Latest",
+			Expected: http.StatusOK,
+			Changes: []*PassCardChange{
+				&PassCardChange{Path: "coupon.primaryFields[offer].value", From: "20% off", To: "30% off"},
+			},
+		},
+		{
+			Name:     "Range",
+			Query:    url.Values{"from": {"1"}, "to": {"3"}},
+			Expected: http.StatusOK,
+			Changes: []*PassCardChange{
+				&PassCardChange{Path: "coupon.primaryFields[offer].value", From: "20% off", To: "30% off"},
+				&PassCardChange{Path: "logoText", From: "Coupon", To: "Sale"},
+			},
+		},
+		{
+			Name:     "Reversed",
+			Query:    url.Values{"from": {"2"}, "to": {"1"}},
+			Expected: http.StatusOK,
+			Changes: []*PassCardChange{
+				&PassCardChange{Path: "logoText", From: "Sale", To: "Coupon"},
+			},
+		},
+		{
+			Name:     "UnknownVersion",
+			Query:    url.Values{"from": {"1"}, "to": {"4"}},
+			Expected: http.StatusNotFound,
+		},
+		{
+			Name:     "InvalidVersion",
+			Query:    url.Values{"from": {"first"}},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project, passcard, err := fakeVersionedPassCard(srv, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			path := fmt.Sprintf("/projects/%d/cards/%d/versions/diff", project.ID, passcard.ID)
+			req := authRequest(srv, user, newRequest("GET", path, nil, nil, tc.Query))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			diff := &PassCardDiff{}
+			err = unmarshalJSON(resp, diff)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Changes, diff.Changes)
+		})
+	}
+}
+
+func TestRollbackPassCardHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Version  int64
+		IfMatch  string
+		Archived bool
+		Expected int
+		Pushes   int
+	}{
+		{
+			Name:     "FirstVersion",
+			Version:  1,
+			Expected: http.StatusOK,
+			Pushes:   3,
+		},
+		{
+			Name:     "LatestVersion",
+			Version:  3,
+			Expected: http.StatusOK,
+			Pushes:   2,
+		},
+		{
+			Name:     "UnknownVersion",
+			Version:  4,
+			Expected: http.StatusNotFound,
+			Pushes:   2,
+		},
+		{
+			Name:     "StaleVersion",
+			Version:  1,
+			IfMatch:  `"2"`,
+			Expected: http.StatusPreconditionFailed,
+			Pushes:   2,
+		},
+		{
+			Name:     "MissingIfMatch",
+			Version:  1,
+			IfMatch:  " ",
+			Expected: http.StatusPreconditionRequired,
+			Pushes:   2,
+		},
+		{
+			Name:     "ArchivedPassCard",
+			Version:  1,
+			Archived: true,
+			Expected: http.StatusConflict,
+			Pushes:   2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project, passcard, err := fakeVersionedPassCard(srv, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			if tc.Archived {
+				err = srv.env.Logic.ArchivePassCard(ctx, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			ifMatch := tc.IfMatch
+			if ifMatch == "" {
+				ifMatch = passCardETag(passcard)
+			}
+
+			path := fmt.Sprintf("/projects/%d/cards/%d/versions/%d/rollback", project.ID, passcard.ID, tc.Version)
+			headers := map[string]string{"If-Match": ifMatch}
+			req := authRequest(srv, user, newRequest("POST", path, nil, headers, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+			if assert.NoError(err) {
+				assert.Len(pushes, tc.Pushes)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			data := &api.PassCardInfo{}
+			err = unmarshalJSON(resp, data)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(passCardETag(data), resp.Header.Get("ETag"))
+
+			version, err := srv.env.Logic.LoadPassCardVersion(ctx, passcard, tc.Version)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(version.Data.LogoText, data.Data.LogoText)
+			assert.Equal(version.Data.Coupon.PrimaryFields[0].Value, data.Data.Coupon.PrimaryFields[0].Value)
+
+			if tc.Version < passcard.Version {
+				assert.Equal(passcard.Version+1, data.Version)
+			} else {
+				assert.Equal(passcard.Version, data.Version)
+			}
+		})
+	}
+}
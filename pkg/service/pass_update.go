@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/danikarik/okpock/pkg/api"
+)
+
+// updatePass marks pass as updated and stores update notification for every
+// device registered for pass in one transaction. Delivery is done in
+// background by outbox dispatcher. Bundle must be uploaded before, so devices
+// fetch new version of pass.
+func (s *Service) updatePass(ctx context.Context, passType api.PassType, serialNumber string) error {
+	return s.env.PassKit.UpdatePassWithPushes(ctx, serialNumber, s.passTypeToString(passType))
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"io/ioutil"
+	"net/http"
+)
+
+func (s *Service) readPassUpload(r *http.Request) ([]byte, error) {
+	err := r.ParseMultipartForm(10 * MB)
+	if err != nil {
+		return nil, err
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
+
+func (s *Service) verifyPassHandler(w http.ResponseWriter, r *http.Request) error {
+	data, err := s.readPassUpload(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPassUpload", err)
+	}
+
+	report, err := s.env.Verifier.Verify(data)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "Verify", err)
+	}
+
+	return sendJSON(w, http.StatusOK, report)
+}
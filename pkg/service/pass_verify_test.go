@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPassHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Issued   bool
+		Content  []byte
+		Expected int
+	}{
+		{
+			Name:     "IssuedPass",
+			Issued:   true,
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "NotArchive",
+			Content:  []byte(fakeString()),
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:     "NoFile",
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			content := tc.Content
+			if tc.Issued {
+				project := &api.Project{
+					ID:               fakeID(),
+					Description:      fakeString(),
+					OrganizationName: fakeString(),
+					PassType:         api.Coupon,
+				}
+
+				passcard := fakePassCard(project)
+				passcard.Data.Coupon = &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "offer", Label: "Offer", Value: "20% off"},
+					},
+				}
+
+				buf := new(bytes.Buffer)
+				err = srv.writePass(ctx, buf, project, passcard)
+				if !assert.NoError(err) {
+					return
+				}
+				content = buf.Bytes()
+			}
+
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+
+			if content != nil {
+				part, err := writer.CreateFormFile("file", "pass"+pkpass.Extension)
+				if !assert.NoError(err) {
+					return
+				}
+
+				_, err = part.Write(content)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			err = writer.Close()
+			if !assert.NoError(err) {
+				return
+			}
+
+			req := authRequest(srv, user, httptest.NewRequest("POST", "/passes/verify", body))
+			req.Header.Add("Content-Type", writer.FormDataContentType())
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode == http.StatusOK {
+				var report pkpass.Report
+				err = unmarshalJSON(resp, &report)
+				if !assert.NoError(err) {
+					return
+				}
+
+				assert.Empty(report.Missing)
+				assert.Empty(report.Unlisted)
+				assert.Empty(report.Mismatched)
+				if assert.NotNil(report.Signature) {
+					assert.True(report.Signature.Verified)
+				}
+				if assert.NotNil(report.Pass) {
+					assert.Equal("pass.com.okpock.test", report.Pass.PassTypeID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// UpdateExpirationRequest holds project expiration rule.
+type UpdateExpirationRequest struct {
+	// ExpireAfterDays voids pass cards given number of days after issue,
+	// zero disables rule.
+	ExpireAfterDays int `json:"expireAfterDays"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *UpdateExpirationRequest) IsValid() error {
+	if r.ExpireAfterDays < 0 {
+		return errors.New("expireAfterDays must not be negative")
+	}
+	return nil
+}
+
+// String returns string representation of struct.
+func (r *UpdateExpirationRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *Service) updateProjectExpirationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req UpdateExpirationRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	err = s.env.Logic.SetProjectExpiration(ctx, req.ExpireAfterDays, project)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SetProjectExpiration", err)
+	}
+
+	return sendJSON(w, http.StatusOK, project)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateProjectExpirationHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Body     string
+		Expected int
+		Days     int
+	}{
+		{
+			Name:     "Valid",
+			Body:     `{"expireAfterDays":30}`,
+			Expected: http.StatusOK,
+			Days:     30,
+		},
+		{
+			Name:     "Disable",
+			Body:     `{"expireAfterDays":0}`,
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "Negative",
+			Body:     `{"expireAfterDays":-1}`,
+			Expected: http.StatusBadRequest,
+			Days:     7,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			project.ExpireAfterDays = 7
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			path := fmt.Sprintf("/projects/%d/expiration", project.ID)
+			req := authRequest(srv, user, newRequest("PUT", path, []byte(tc.Body), nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			loaded, err := srv.env.Logic.LoadProject(ctx, user, project.ID)
+			if assert.NoError(err) {
+				assert.Equal(tc.Days, loaded.ExpireAfterDays)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/filestore"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/danikarik/okpock/pkg/secure"
+	uuid "github.com/satori/go.uuid"
+)
+
+// importedPass holds content of imported `.pkpass` or zipped `.pass` folder.
+type importedPass struct {
+	pass          *api.PassCard
+	passType      api.PassType
+	images        []pkpass.File
+	localizations api.Localizations
+	// localizedImages maps language to its image files.
+	localizedImages map[string][]pkpass.File
+}
+
+// passFolderFiles strips `.pass` folder prefix and drops archiver metadata.
+func passFolderFiles(files []pkpass.File) []pkpass.File {
+	prefix := ""
+	for _, file := range files {
+		if file.Name == pkpass.PassFilename {
+			prefix = ""
+			break
+		}
+		if path.Base(file.Name) == pkpass.PassFilename && strings.Count(file.Name, "/") == 1 {
+			prefix = path.Dir(file.Name) + "/"
+		}
+	}
+
+	result := []pkpass.File{}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(file.Name, prefix)
+		if strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".") {
+			continue
+		}
+		result = append(result, pkpass.NewFile(name, file.Data))
+	}
+
+	return result
+}
+
+func readImportedPass(data []byte) (*importedPass, error) {
+	files, err := pkpass.Unzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	files = passFolderFiles(files)
+
+	imported := &importedPass{
+		localizations:   api.Localizations{},
+		localizedImages: map[string][]pkpass.File{},
+	}
+
+	for _, file := range files {
+		dir, name := path.Split(file.Name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		switch {
+		case file.Name == pkpass.PassFilename:
+			imported.pass, err = readImportedPassJSON(file.Data)
+			if err != nil {
+				return nil, err
+			}
+		case dir == "" && isPassImage(name):
+			imported.images = append(imported.images, file)
+		case strings.HasSuffix(dir, pkpass.LocalizationExtension) && !strings.Contains(dir, "/"):
+			lang := strings.TrimSuffix(dir, pkpass.LocalizationExtension)
+			loc, ok := imported.localizations[lang]
+			if !ok {
+				loc = &api.Localization{}
+				imported.localizations[lang] = loc
+			}
+			if name == pkpass.StringsFilename {
+				loc.Strings, err = pkpass.ParseStrings(file.Data)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", file.Name, err)
+				}
+			}
+			if isPassImage(name) {
+				imported.localizedImages[lang] = append(imported.localizedImages[lang], pkpass.NewFile(name, file.Data))
+			}
+		}
+	}
+
+	if imported.pass == nil {
+		return nil, fmt.Errorf("%s is missing", pkpass.PassFilename)
+	}
+
+	imported.passType, _, err = imported.pass.Style()
+	if err != nil {
+		return nil, err
+	}
+
+	err = imported.localizations.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
+
+func readImportedPassJSON(data []byte) (*api.PassCard, error) {
+	var pass struct {
+		api.PassCard
+		// Barcode is deprecated, but still used by older passes.
+		Barcode *api.Barcode `json:"barcode,omitempty"`
+	}
+
+	err := json.Unmarshal(data, &pass)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pass.Barcodes) == 0 && pass.Barcode != nil {
+		pass.Barcodes = []*api.Barcode{pass.Barcode}
+	}
+
+	return &pass.PassCard, nil
+}
+
+// projectImageSetter returns store method and size of image by bundle filename.
+func (s *Service) projectImageSetter(filename string) (func(context.Context, api.ImageSize, string, *api.Project) error, api.ImageSize) {
+	name, size := strings.TrimSuffix(filename, ".png"), api.ImageSize1x
+	switch {
+	case strings.HasSuffix(name, "@2x"):
+		name, size = strings.TrimSuffix(name, "@2x"), api.ImageSize2x
+	case strings.HasSuffix(name, "@3x"):
+		name, size = strings.TrimSuffix(name, "@3x"), api.ImageSize3x
+	}
+
+	switch name {
+	case backgroundImage:
+		return s.env.Logic.SetBackgroundImage, size
+	case footerImage:
+		return s.env.Logic.SetFooterImage, size
+	case iconImage:
+		return s.env.Logic.SetIconImage, size
+	case logoImage:
+		return s.env.Logic.SetLogoImage, size
+	case stripImage:
+		return s.env.Logic.SetStripImage, size
+	case thumbnailImage:
+		return s.env.Logic.SetThumbnailImage, size
+	}
+
+	return nil, size
+}
+
+// saveImportedFile stores file as user's upload.
+func (s *Service) saveImportedFile(ctx context.Context, user *api.User, file pkpass.File) (*api.Upload, error) {
+	hash, err := secure.Hash(file.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	object := &filestore.Object{
+		Prefix:      strconv.FormatInt(user.ID, 10),
+		Key:         uuid.NewV4().String(),
+		Body:        file.Data,
+		ContentType: http.DetectContentType(file.Data),
+	}
+
+	err = s.env.Storage.UploadFile(ctx, s.env.Config.UploadBucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &api.Upload{
+		UUID:        object.Path(),
+		Filename:    path.Base(file.Name),
+		Hash:        hash,
+		Body:        file.Data,
+		ContentType: object.ContentType,
+		CreatedAt:   time.Now(),
+	}
+
+	err = s.env.Logic.SaveNewUpload(ctx, user, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// importedPassCardRequest converts imported pass into card request.
+func importedPassCardRequest(pass *api.PassCard) (*CreatePassCardRequest, error) {
+	_, structure, err := pass.Style()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatePassCardRequest{
+		AppLaunchURL:       pass.AppLaunchURL,
+		AssociatedStoreIDs: pass.AssociatedStoreIDs,
+		UserInfo:           pass.UserInfo,
+		ExpirationDate:     pass.ExpirationDate,
+		Voided:             pass.Voided,
+		Beacons:            pass.Beacons,
+		Locations:          pass.Locations,
+		MaxDistance:        pass.MaxDistance,
+		RelevantDate:       pass.RelevantDate,
+		Structure:          structure,
+		Barcodes:           pass.Barcodes,
+		BackgroundColor:    pass.BackgroundColor,
+		ForegroundColor:    pass.ForegroundColor,
+		GroupingIdentifier: pass.GroupingIdentifier,
+		LabelColor:         pass.LabelColor,
+		LogoText:           pass.LogoText,
+		NFC:                pass.NFC,
+	}, nil
+}
+
+func (s *Service) importProjectHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	data, err := s.readPassUpload(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPassUpload", err)
+	}
+
+	createCard := false
+	if v := r.FormValue("createCard"); v != "" {
+		createCard, err = strconv.ParseBool(v)
+		if err != nil {
+			return s.httpError(w, r, http.StatusBadRequest, "ParseBool", err)
+		}
+	}
+
+	imported, err := readImportedPass(data)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadImportedPass", err)
+	}
+
+	title := r.FormValue("title")
+	if title == "" {
+		title = imported.pass.LogoText
+	}
+	if title == "" {
+		title = imported.pass.Description
+	}
+
+	project := api.NewProject(
+		title,
+		imported.pass.OrganizationName,
+		imported.pass.Description,
+		imported.passType,
+	)
+	if project.Title == "" || project.OrganizationName == "" || project.Description == "" {
+		return s.httpError(w, r, http.StatusBadRequest, "NewProject", errors.New("title, organization name and description are required"))
+	}
+
+	exists, err := s.env.Logic.IsProjectExists(
+		ctx,
+		project.Title,
+		project.OrganizationName,
+		project.Description,
+		project.PassType,
+	)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "IsProjectExists", err)
+	}
+	if exists {
+		return sendJSON(w, http.StatusNotAcceptable, M{
+			"title":            project.Title,
+			"organizationName": project.OrganizationName,
+			"description":      project.Description,
+			"passType":         project.PassType,
+		})
+	}
+
+	var passcard *api.PassCardInfo
+	if createCard {
+		req, err := importedPassCardRequest(imported.pass)
+		if err != nil {
+			return s.httpError(w, r, http.StatusBadRequest, "ImportedPassCardRequest", err)
+		}
+
+		passcard, err = s.newProjectPassCard(req, project)
+		if err != nil {
+			return s.httpError(w, r, http.StatusBadRequest, "NewProjectPassCard", err)
+		}
+	}
+
+	err = s.env.Logic.SaveNewProject(ctx, user, project)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewProject", err)
+	}
+
+	for _, image := range imported.images {
+		upload, err := s.saveImportedFile(ctx, user, image)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "SaveImportedFile", err)
+		}
+
+		setImage, size := s.projectImageSetter(image.Name)
+		if setImage == nil {
+			continue
+		}
+
+		err = setImage(ctx, size, upload.UUID, project)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "SetProjectImage", err)
+		}
+	}
+
+	if len(imported.localizations) > 0 {
+		for lang, images := range imported.localizedImages {
+			loc := imported.localizations[lang]
+			loc.Images = map[string]string{}
+			for _, image := range images {
+				upload, err := s.saveImportedFile(ctx, user, image)
+				if err != nil {
+					return s.httpError(w, r, http.StatusInternalServerError, "SaveImportedFile", err)
+				}
+				loc.Images[image.Name] = upload.UUID
+			}
+		}
+
+		err = s.env.Logic.SetProjectLocalizations(ctx, imported.localizations, project)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "SetProjectLocalizations", err)
+		}
+	}
+
+	resp := M{"id": project.ID}
+
+	if passcard != nil {
+		err = s.env.Logic.SaveNewPassCard(ctx, project, passcard)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "SaveNewPassCard", err)
+		}
+
+		err = s.env.PassKit.InsertPass(
+			ctx,
+			passcard.Data.SerialNumber,
+			passcard.Data.AuthenticationToken,
+			passcard.Data.PassTypeID)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "InsertPass", err)
+		}
+
+		err = s.uploadPass(ctx, project, passcard)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "UploadPass", err)
+		}
+
+		resp["passCard"] = M{
+			"id":           passcard.ID,
+			"serialNumber": passcard.Data.SerialNumber,
+			"url":          s.downloadURL(passcard.Data.SerialNumber),
+		}
+	}
+
+	return sendJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakePassFolder(t *testing.T) []byte {
+	pass := []byte(`{
+		"description": "Paw Planet Coupon",
+		"formatVersion": 1,
+		"organizationName": "Paw Planet",
+		"passTypeIdentifier": "pass.com.example.coupon",
+		"serialNumber": "E5982H-I2",
+		"teamIdentifier": "A1B2C3D4E5",
+		"coupon": {"primaryFields": [{"key": "offer", "label": "offer_label", "value": "20% off"}]}
+	}`)
+
+	data, err := pkpass.Zip(
+		pkpass.NewFile("Coupon.pass/pass.json", pass),
+		pkpass.NewFile("Coupon.pass/icon.png", []byte("icon")),
+		pkpass.NewFile("Coupon.pass/logo@2x.png", []byte("logo")),
+		pkpass.NewFile("Coupon.pass/en.lproj/pass.strings", []byte(`"offer_label" = "Offer";`)),
+		pkpass.NewFile("Coupon.pass/ru.lproj/logo@2x.png", []byte("логотип")),
+		pkpass.NewFile("Coupon.pass/.DS_Store", []byte("finder")),
+		pkpass.NewFile("__MACOSX/Coupon.pass/._pass.json", []byte("resource fork")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestImportProjectHandler(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Content    func(t *testing.T) []byte
+		Title      string
+		CreateCard bool
+		Twice      bool
+		Expected   int
+		PassType   api.PassType
+		Images     []string
+		Languages  []string
+	}{
+		{
+			Name: "Pkpass",
+			Content: func(t *testing.T) []byte {
+				data, err := fakeFile("testdata/9973af9d-9cfa-4d9f-8c6c-32255de8d96b.pkpass")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return data
+			},
+			CreateCard: true,
+			Expected:   http.StatusCreated,
+			PassType:   api.StoreCard,
+			Images: []string{
+				pkpass.IconFilename,
+				pkpass.IconFilename2x,
+				pkpass.LogoFilename,
+				pkpass.StripFilename,
+				pkpass.StripFilename2x,
+			},
+		},
+		{
+			Name:     "PassFolder",
+			Content:  fakePassFolder,
+			Title:    fakeString(),
+			Expected: http.StatusCreated,
+			PassType: api.Coupon,
+			Images: []string{
+				pkpass.IconFilename,
+				pkpass.LogoFilename2x,
+			},
+			Languages: []string{"en", "ru"},
+		},
+		{
+			Name:     "AlreadyExists",
+			Content:  fakePassFolder,
+			Title:    fakeString(),
+			Twice:    true,
+			Expected: http.StatusNotAcceptable,
+		},
+		{
+			Name: "MissingPass",
+			Content: func(t *testing.T) []byte {
+				data, err := pkpass.Zip(pkpass.NewFile(pkpass.IconFilename, []byte("icon")))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return data
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "NotArchive",
+			Content: func(t *testing.T) []byte {
+				return []byte(fakeString())
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			content := tc.Content(t)
+			newImportRequest := func() (*http.Request, error) {
+				body := new(bytes.Buffer)
+				writer := multipart.NewWriter(body)
+
+				part, err := writer.CreateFormFile("file", "import"+pkpass.Extension)
+				if err != nil {
+					return nil, err
+				}
+				_, err = part.Write(content)
+				if err != nil {
+					return nil, err
+				}
+
+				err = writer.WriteField("title", tc.Title)
+				if err != nil {
+					return nil, err
+				}
+				err = writer.WriteField("createCard", fmt.Sprint(tc.CreateCard))
+				if err != nil {
+					return nil, err
+				}
+
+				err = writer.Close()
+				if err != nil {
+					return nil, err
+				}
+
+				req := authRequest(srv, user, httptest.NewRequest("POST", "/projects/import", body))
+				req.Header.Add("Content-Type", writer.FormDataContentType())
+				return req, nil
+			}
+
+			if tc.Twice {
+				req, err := newImportRequest()
+				if !assert.NoError(err) {
+					return
+				}
+				srv.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			req, err := newImportRequest()
+			if !assert.NoError(err) {
+				return
+			}
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode != http.StatusCreated {
+				return
+			}
+
+			var data struct {
+				ID       int64 `json:"id"`
+				PassCard *struct {
+					ID           int64  `json:"id"`
+					SerialNumber string `json:"serialNumber"`
+				} `json:"passCard"`
+			}
+			err = unmarshalJSON(resp, &data)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project, err := srv.env.Logic.LoadProject(ctx, user, data.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.PassType, project.PassType)
+
+			images := map[string]string{}
+			for _, image := range passImages(project) {
+				if image.key != "" {
+					images[image.filename] = image.key
+				}
+			}
+			assert.Len(images, len(tc.Images))
+			for _, filename := range tc.Images {
+				obj, err := srv.env.Storage.GetFile(ctx, srv.env.Config.UploadBucket, images[filename])
+				if assert.NoError(err) {
+					assert.NotEmpty(obj.Body)
+				}
+			}
+
+			assert.Len(project.Localizations, len(tc.Languages))
+			for _, lang := range tc.Languages {
+				assert.Contains(project.Localizations, lang)
+			}
+
+			if !tc.CreateCard {
+				assert.Nil(data.PassCard)
+				return
+			}
+
+			if !assert.NotNil(data.PassCard) {
+				return
+			}
+
+			passcard, err := srv.env.Logic.LoadPassCard(ctx, project, data.PassCard.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(data.PassCard.SerialNumber, passcard.Data.SerialNumber)
+			assert.Len(passcard.Data.Barcodes, 1)
+			assert.NotNil(passcard.Data.StoreCard)
+
+			_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+			assert.NoError(err)
+		})
+	}
+}
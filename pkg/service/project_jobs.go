@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"go.uber.org/zap"
+)
+
+const (
+	jobWorkers      = 2
+	jobPollInterval = time.Second
+	jobLease        = time.Minute
+	jobMaxAttempts  = 5
+)
+
+// jobCursorDone is a cursor of job with no pass cards left.
+const jobCursorDone int64 = -1
+
+// ErrJobAttempts raised when job is interrupted too many times.
+var ErrJobAttempts = fmt.Errorf("job: interrupted more than %d times", jobMaxAttempts)
+
+// jobParams holds input of job, so job is resumed by any worker.
+type jobParams struct {
+	Filter      *PassCardFilter `json:"filter,omitempty"`
+	PassCardID  int64           `json:"passCardId,omitempty"`
+	Patch       *PassCardPatch  `json:"patch,omitempty"`
+	Upload      string          `json:"upload,omitempty"`
+	ContentType string          `json:"contentType,omitempty"`
+}
+
+// newJob returns pending job of request author.
+func newJob(ctx context.Context, project *api.Project, kind api.JobKind, params *jobParams) (*api.Job, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	job := api.NewJob(kind, project.ID, api.AuthorFromContext(ctx))
+	job.Params = data
+
+	return job, nil
+}
+
+func (s *Service) projectJobsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	opts, err := readPagingOptions(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPagingOptions", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	jobs, err := s.env.Logic.LoadJobs(ctx, project, opts)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadJobs", err)
+	}
+
+	return sendPaginatedJSON(w, http.StatusOK, jobs.Opts, jobs.Data)
+}
+
+func (s *Service) projectJobHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	jobID, err := s.idFromRequest(r, "jobID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	job, err := s.env.Logic.LoadJob(ctx, project, jobID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadJob", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadJob", err)
+	}
+
+	return sendJSON(w, http.StatusOK, job)
+}
+
+func (s *Service) projectJobResultsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	opts, err := readPagingOptions(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPagingOptions", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	jobID, err := s.idFromRequest(r, "jobID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	job, err := s.env.Logic.LoadJob(ctx, project, jobID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadJob", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadJob", err)
+	}
+
+	results, err := s.env.Logic.LoadJobResults(ctx, job, opts)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadJobResults", err)
+	}
+
+	for _, result := range results.Data {
+		if result.Succeeded() && result.SerialNumber != "" {
+			result.URL = s.downloadURL(result.SerialNumber)
+		}
+	}
+
+	return sendPaginatedJSON(w, http.StatusOK, results.Opts, results.Data)
+}
+
+// RunJobs claims pending jobs and jobs whose worker has gone, like the one
+// interrupted by restart, and runs them until context is canceled.
+func (s *Service) RunJobs(ctx context.Context) {
+	var (
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, jobWorkers)
+	)
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if free := jobWorkers - len(workers); free > 0 {
+			jobs, err := s.claimDueJobs(ctx, time.Now(), uint64(free))
+			if err != nil {
+				s.logger.Error("job_error", zap.Error(err), zap.String("message", "ClaimJob"))
+			}
+
+			for _, job := range jobs {
+				workers <- struct{}{}
+				wg.Add(1)
+				go func(job *api.Job) {
+					defer wg.Done()
+					s.runJob(ctx, job)
+					<-workers
+				}(job)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimDueJobs claims pending jobs and running jobs whose lease has expired
+// by given time.
+func (s *Service) claimDueJobs(ctx context.Context, now time.Time, limit uint64) ([]*api.Job, error) {
+	jobs, err := s.env.Logic.LoadPendingJobs(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := []*api.Job{}
+	for _, job := range jobs {
+		ok, err := s.env.Logic.ClaimJob(ctx, jobLease, job)
+		if err != nil {
+			return claimed, err
+		}
+		if ok {
+			claimed = append(claimed, job)
+		}
+	}
+
+	return claimed, nil
+}
+
+// runJob runs claimed job on behalf of its author. Job is left to another
+// worker once lease is lost and to the next claim once context is canceled.
+func (s *Service) runJob(ctx context.Context, job *api.Job) {
+	logger := s.logger.
+		With(zap.Int64("job_id", job.ID)).
+		With(zap.Int64("project_id", job.ProjectID)).
+		With(zap.Int("attempts", job.Attempts))
+
+	err := s.processJob(api.WithAuthor(ctx, job.Author()), logger, job)
+	switch {
+	case err == nil:
+		return
+	case err == store.ErrVersionMismatch:
+		logger.Warn("job_lease_lost")
+		return
+	case ctx.Err() != nil:
+		return
+	}
+
+	logger.Error("job_failed", zap.Error(err))
+	job.LastError = err.Error()
+	job.Finish(api.JobFailed)
+
+	err = s.saveJobProgress(ctx, job)
+	if err != nil {
+		logger.Error("job_error", zap.Error(err), zap.String("message", "UpdateJob"))
+	}
+}
+
+func (s *Service) processJob(ctx context.Context, logger *zap.Logger, job *api.Job) error {
+	if job.Attempts > jobMaxAttempts {
+		return ErrJobAttempts
+	}
+
+	params := &jobParams{}
+	if len(job.Params) > 0 {
+		err := json.Unmarshal(job.Params, params)
+		if err != nil {
+			return err
+		}
+	}
+
+	user, err := s.env.Auth.LoadUser(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, job.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	switch job.Kind {
+	case api.PublishJob:
+		return s.runPassCardsJob(ctx, logger, project, job, s.projectPager(project), s.publishPassCard)
+	case api.RenderJob:
+		if project.Template == nil {
+			return errors.New("project has no template")
+		}
+		return s.runPassCardsJob(ctx, logger, project, job, s.projectPager(project), s.renderPassCard)
+	case api.PatchJob:
+		if params.Patch == nil {
+			return errors.New("patch is required")
+		}
+		return s.runPassCardsJob(ctx, logger, project, job, s.jobPager(project, params),
+			func(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+				return s.patchAndPublishPassCard(ctx, project, passcard, params.Patch)
+			})
+	case api.ArchiveJob:
+		return s.runPassCardsJob(ctx, logger, project, job, s.jobPager(project, params),
+			func(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+				return s.archiveLatestPassCard(ctx, project, passcard)
+			})
+	case api.IssueJob:
+		return s.issuePassCards(ctx, logger, user, project, job, params)
+	}
+
+	return fmt.Errorf("job: unknown kind %q", job.Kind)
+}
+
+// saveJobProgress stores progress of job and extends lease of worker.
+// Returns `store.ErrVersionMismatch` once job is claimed by another worker.
+func (s *Service) saveJobProgress(ctx context.Context, job *api.Job) error {
+	if job.Done() {
+		job.LeaseUntil = nil
+	} else {
+		leaseUntil := time.Now().Add(jobLease)
+		job.LeaseUntil = &leaseUntil
+	}
+
+	return s.env.Logic.UpdateJob(ctx, job)
+}
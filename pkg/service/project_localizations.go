@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// UpdateLocalizationsRequest holds translations keyed by language.
+type UpdateLocalizationsRequest struct {
+	Localizations api.Localizations `json:"localizations"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *UpdateLocalizationsRequest) IsValid() error {
+	return r.Localizations.IsValid()
+}
+
+// String returns string representation of struct.
+func (r *UpdateLocalizationsRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func isPassImage(name string) bool {
+	for _, image := range passImages(&api.Project{}) {
+		if image.filename == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLocalizations verifies that every localized image is supported
+// by bundle and refers to user's upload.
+func (s *Service) checkLocalizations(ctx context.Context, user *api.User, localizations api.Localizations) error {
+	err := localizations.IsValid()
+	if err != nil {
+		return err
+	}
+
+	for lang, loc := range localizations {
+		for name, key := range loc.Images {
+			if !isPassImage(name) {
+				return fmt.Errorf("localization: %s image %q is not supported", lang, name)
+			}
+			_, err = s.env.Logic.LoadUploadByUUID(ctx, user, key)
+			if err != nil {
+				return fmt.Errorf("localization: %s image %q is not uploaded", lang, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addLocalizedFiles writes `.lproj` folders of project merged with
+// pass card ones into bundle.
+func (s *Service) addLocalizedFiles(ctx context.Context, builder *pkpass.Builder, project *api.Project, passCard *api.PassCardInfo) error {
+	localizations := project.Localizations.Merge(passCard.Localizations)
+
+	langs := make([]string, 0, len(localizations))
+	for lang := range localizations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	for _, lang := range langs {
+		loc := localizations[lang]
+
+		if len(loc.Strings) > 0 {
+			stringsFile, err := pkpass.NewStrings(lang, loc.Strings)
+			if err != nil {
+				return err
+			}
+			err = builder.AddFile(*stringsFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		names := make([]string, 0, len(loc.Images))
+		for name := range loc.Images {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			localized, err := pkpass.LocalizedName(lang, name)
+			if err != nil {
+				return err
+			}
+			err = s.addUploadedFile(ctx, builder, localized, loc.Images[name])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) updateProjectLocalizationsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req UpdateLocalizationsRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	err = s.checkLocalizations(ctx, user, req.Localizations)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "CheckLocalizations", err)
+	}
+
+	err = s.env.Logic.SetProjectLocalizations(ctx, req.Localizations, project)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SetProjectLocalizations", err)
+	}
+
+	return sendJSON(w, http.StatusOK, project)
+}
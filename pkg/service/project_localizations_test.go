@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/filestore"
+	"github.com/danikarik/okpock/pkg/pkpass"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateProjectLocalizationsHandler(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Localizations func(uploadKey string) api.Localizations
+		Expected      int
+	}{
+		{
+			Name: "Valid",
+			Localizations: func(uploadKey string) api.Localizations {
+				return api.Localizations{
+					"en": {
+						Strings: map[string]string{"offer": "Offer"},
+						Images:  map[string]string{pkpass.LogoFilename: uploadKey},
+					},
+					"zh-Hans": {
+						Strings: map[string]string{"offer": "优惠"},
+					},
+				}
+			},
+			Expected: http.StatusOK,
+		},
+		{
+			Name: "InvalidLanguage",
+			Localizations: func(uploadKey string) api.Localizations {
+				return api.Localizations{
+					"../en": {Strings: map[string]string{"offer": "Offer"}},
+				}
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "UnsupportedImage",
+			Localizations: func(uploadKey string) api.Localizations {
+				return api.Localizations{
+					"en": {Images: map[string]string{"banner.png": uploadKey}},
+				}
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "MissingUpload",
+			Localizations: func(uploadKey string) api.Localizations {
+				return api.Localizations{
+					"en": {Images: map[string]string{pkpass.LogoFilename: fakeString()}},
+				}
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			upload := api.NewUpload(fakeString(), "logo.png", fakeString())
+			err = srv.env.Logic.SaveNewUpload(ctx, user, upload)
+			if !assert.NoError(err) {
+				return
+			}
+
+			localizations := tc.Localizations(upload.UUID)
+			body, err := json.Marshal(&UpdateLocalizationsRequest{Localizations: localizations})
+			if !assert.NoError(err) {
+				return
+			}
+
+			url := fmt.Sprintf("/projects/%d/localizations", project.ID)
+			req := authRequest(srv, user, newRequest("PUT", url, body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode == http.StatusOK {
+				data := &api.Project{}
+				err = unmarshalJSON(resp, &data)
+				if !assert.NoError(err) {
+					return
+				}
+
+				assert.Equal(localizations, data.Localizations)
+			}
+		})
+	}
+}
+
+func TestWritePassLocalizations(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	logoKey := fakeString()
+	err = srv.env.Storage.UploadFile(ctx, srv.env.Config.UploadBucket, &filestore.Object{
+		Key:         logoKey,
+		Body:        []byte("localized logo"),
+		ContentType: "image/png",
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := &api.Project{
+		ID:       fakeID(),
+		PassType: api.Coupon,
+		Localizations: api.Localizations{
+			"en": {
+				Strings: map[string]string{"offer": "Offer", "expires": "Expires"},
+				Images:  map[string]string{pkpass.LogoFilename: logoKey},
+			},
+		},
+	}
+
+	passcard := fakePassCard(project)
+	passcard.Localizations = api.Localizations{
+		"en": {Strings: map[string]string{"offer": "20% off"}},
+		"ru": {Strings: map[string]string{"offer": "Скидка 20%"}},
+	}
+
+	buf := new(bytes.Buffer)
+	err = srv.writePass(ctx, buf, project, passcard)
+	if !assert.NoError(err) {
+		return
+	}
+
+	files, err := pkpass.Unzip(buf.Bytes())
+	if !assert.NoError(err) {
+		return
+	}
+
+	bundle := map[string]string{}
+	for _, file := range files {
+		bundle[file.Name] = string(file.Data)
+	}
+
+	assert.Equal("\"expires\" = \"Expires\";\n\"offer\" = \"20% off\";\n", bundle["en.lproj/pass.strings"])
+	assert.Equal("localized logo", bundle["en.lproj/logo.png"])
+	assert.Equal("\"offer\" = \"Скидка 20%\";\n", bundle["ru.lproj/pass.strings"])
+
+	manifest := pkpass.Manifest{}
+	err = json.Unmarshal([]byte(bundle[pkpass.ManifestFilename]), &manifest)
+	if !assert.NoError(err) {
+		return
+	}
+
+	for _, name := range []string{"en.lproj/pass.strings", "en.lproj/logo.png", "ru.lproj/pass.strings"} {
+		hash, err := pkpass.HashFile([]byte(bundle[name]))
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(hash, manifest[name])
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"go.uber.org/zap"
+)
+
+const publishPageLimit uint64 = 100
+
+func (s *Service) publishProjectHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	job, err := newJob(ctx, project, api.PublishJob, &jobParams{})
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewJob", err)
+	}
+
+	err = s.env.Logic.SaveNewJob(ctx, project, job)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewJob", err)
+	}
+
+	return sendJSON(w, http.StatusAccepted, job)
+}
+
+// passCardPager loads page of pass cards starting from cursor. It returns
+// cursor following every pass card, `jobCursorDone` follows the last one.
+type passCardPager func(ctx context.Context, cursor int64) ([]*api.PassCardInfo, []int64, error)
+
+// listPager pages pass cards by id like lists do.
+func listPager(load func(context.Context, *api.PagingOptions) (*api.PassCardInfoList, error)) passCardPager {
+	return func(ctx context.Context, cursor int64) ([]*api.PassCardInfo, []int64, error) {
+		list, err := load(ctx, api.NewPagingOptions(cursor, publishPageLimit))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		next := make([]int64, len(list.Data))
+		for i := range list.Data {
+			switch {
+			case i+1 < len(list.Data):
+				next[i] = list.Data[i+1].ID
+			case list.Opts.HasNext():
+				next[i] = list.Opts.Next
+			default:
+				next[i] = jobCursorDone
+			}
+		}
+
+		return list.Data, next, nil
+	}
+}
+
+func (s *Service) projectPager(project *api.Project) passCardPager {
+	return listPager(func(ctx context.Context, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+		return s.env.Logic.LoadPassCards(ctx, project, opts)
+	})
+}
+
+// runPassCardsJob applies fn to pass cards page by page. Progress and
+// cursor are stored after every card, so job is resumed from the next one.
+// Failed cards do not stop the job. Total grows as pages are loaded.
+func (s *Service) runPassCardsJob(ctx context.Context, logger *zap.Logger, project *api.Project, job *api.Job, pager passCardPager, fn func(context.Context, *api.Project, *api.PassCardInfo) error) error {
+	for job.Cursor != jobCursorDone {
+		passcards, next, err := pager(ctx, job.Cursor)
+		if err != nil {
+			return err
+		}
+		if len(passcards) == 0 {
+			break
+		}
+
+		if total := job.Processed + len(passcards); total > job.Total {
+			job.Total = total
+		}
+
+		for i, passcard := range passcards {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			err := fn(ctx, project, passcard)
+			if err != nil {
+				logger.Warn("job_item_failed",
+					zap.Error(err),
+					zap.String("serial_number", passcard.Data.SerialNumber))
+				job.Failed++
+				job.LastError = fmt.Sprintf("%s: %v", passcard.Data.SerialNumber, err)
+			}
+			job.Processed++
+			job.Cursor = next[i]
+
+			err = s.saveJobProgress(ctx, job)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	job.Total = job.Processed
+	job.Finish(api.JobFinished)
+	return s.saveJobProgress(ctx, job)
+}
+
+// publishPassCard rebuilds bundle of pass card and notifies registered devices.
+func (s *Service) publishPassCard(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+	err := s.uploadPass(ctx, project, passcard)
+	if err != nil {
+		return err
+	}
+
+	return s.updatePass(ctx, project.PassType, passcard.Data.SerialNumber)
+}
+
+// loadPassCardPages collects pass cards of every page returned by load.
+func loadPassCardPages(load func(*api.PagingOptions) (*api.PassCardInfoList, error)) ([]*api.PassCardInfo, error) {
+	var (
+		passcards = []*api.PassCardInfo{}
+		opts      = api.NewPagingOptions(0, publishPageLimit)
+	)
+
+	for {
+		list, err := load(opts)
+		if err != nil {
+			return nil, err
+		}
+		passcards = append(passcards, list.Data...)
+
+		if !list.Opts.HasNext() {
+			return passcards, nil
+		}
+		opts = api.NewPagingOptions(list.Opts.Next, publishPageLimit)
+	}
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitJob runs claimed jobs and polls job until it is done.
+func waitJob(srv *Service, user *api.User, project *api.Project, job *api.Job) (*api.Job, error) {
+	ctx := context.Background()
+
+	jobs, err := srv.claimDueJobs(ctx, time.Now(), 100)
+	if err != nil {
+		return nil, err
+	}
+	for _, claimed := range jobs {
+		srv.runJob(ctx, claimed)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.Done() {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("job %d is not done in time", job.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		req := authRequest(srv, user, newRequest("GET", fmt.Sprintf("/projects/%d/jobs/%d", project.ID, job.ID), nil, nil, nil))
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		}
+
+		job = &api.Job{}
+		err := unmarshalJSON(resp, job)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+func TestPublishProjectHandler(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := &api.Project{
+		ID:               fakeID(),
+		Title:            fakeString(),
+		OrganizationName: fakeString(),
+		Description:      fakeString(),
+		PassType:         api.Coupon,
+	}
+
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	passTypeID := srv.passTypeToString(project.PassType)
+
+	registered := []*api.PassCardInfo{}
+	for i := 0; i < 2; i++ {
+		passcard := fakePassCard(project)
+		passcard.ID = int64(i + 1)
+		err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+		if !assert.NoError(err) {
+			return
+		}
+
+		err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, fakeString(), passTypeID)
+		if !assert.NoError(err) {
+			return
+		}
+
+		err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+		if !assert.NoError(err) {
+			return
+		}
+
+		registered = append(registered, passcard)
+	}
+
+	// pass card without passkit record fails on `UpdatePass`.
+	orphan := fakePassCard(project)
+	orphan.ID = 3
+	err = srv.env.Logic.SaveNewPassCard(ctx, project, orphan)
+	if !assert.NoError(err) {
+		return
+	}
+
+	req := authRequest(srv, user, newRequest("POST", fmt.Sprintf("/projects/%d/publish", project.ID), nil, nil, nil))
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	resp := rec.Result()
+
+	if !assert.Equal(http.StatusAccepted, resp.StatusCode) {
+		return
+	}
+
+	job := &api.Job{}
+	err = unmarshalJSON(resp, job)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(job.ID > 0)
+	assert.Equal(api.PublishJob, job.Kind)
+
+	job, err = waitJob(srv, user, project, job)
+	if !assert.NoError(err) {
+		return
+	}
+
+	if !assert.Equal(api.JobFinished, job.Status) {
+		return
+	}
+	assert.Equal(3, job.Total)
+	assert.Equal(3, job.Processed)
+	assert.Equal(1, job.Failed)
+	assert.Contains(job.LastError, orphan.Data.SerialNumber)
+	assert.NotNil(job.FinishedAt)
+
+	for _, passcard := range registered {
+		_, err = srv.env.Storage.GetFile(ctx, srv.env.Config.PassesBucket, passcard.Data.SerialNumber)
+		assert.NoError(err)
+
+		pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+		if assert.NoError(err) {
+			assert.Len(pushes, 1)
+		}
+	}
+
+	req = authRequest(srv, user, newRequest("GET", fmt.Sprintf("/projects/%d/jobs", project.ID), nil, nil, nil))
+	rec = httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	resp = rec.Result()
+
+	if !assert.Equal(http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	var list struct {
+		Data []*api.Job `json:"data"`
+	}
+	err = unmarshalJSON(resp, &list)
+	if assert.NoError(err) && assert.Len(list.Data, 1) {
+		assert.Equal(job.ID, list.Data[0].ID)
+	}
+
+	req = authRequest(srv, user, newRequest("GET", fmt.Sprintf("/projects/%d/jobs/%d", project.ID, job.ID+1), nil, nil, nil))
+	rec = httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	assert.Equal(http.StatusNotFound, rec.Result().StatusCode)
+}
+
+func TestResumeJob(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := &api.Project{
+		ID:               fakeID(),
+		Title:            fakeString(),
+		OrganizationName: fakeString(),
+		Description:      fakeString(),
+		PassType:         api.Coupon,
+	}
+
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	passTypeID := srv.passTypeToString(project.PassType)
+
+	passcards := []*api.PassCardInfo{}
+	for i := 0; i < 3; i++ {
+		passcard := fakePassCard(project)
+		passcard.ID = int64(i + 1)
+		err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+		if !assert.NoError(err) {
+			return
+		}
+
+		err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, fakeString(), passTypeID)
+		if !assert.NoError(err) {
+			return
+		}
+
+		err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+		if !assert.NoError(err) {
+			return
+		}
+
+		passcards = append(passcards, passcard)
+	}
+
+	// job of worker gone after publishing the latest pass card.
+	expired := time.Now().Add(-time.Second)
+	interrupted := api.NewJob(api.PublishJob, project.ID, api.Author{UserID: user.ID})
+	interrupted.Params = api.RawJSON(`{}`)
+	interrupted.Status = api.JobRunning
+	interrupted.Attempts = 1
+	interrupted.LeaseUntil = &expired
+	interrupted.Total = 3
+	interrupted.Processed = 1
+	interrupted.Cursor = passcards[1].ID
+	err = srv.env.Logic.SaveNewJob(ctx, project, interrupted)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// job interrupted too many times is given up.
+	exhausted := api.NewJob(api.PublishJob, project.ID, api.Author{UserID: user.ID})
+	exhausted.Params = api.RawJSON(`{}`)
+	exhausted.Status = api.JobRunning
+	exhausted.Attempts = jobMaxAttempts
+	exhausted.LeaseUntil = &expired
+	err = srv.env.Logic.SaveNewJob(ctx, project, exhausted)
+	if !assert.NoError(err) {
+		return
+	}
+
+	jobs, err := srv.claimDueJobs(ctx, time.Now(), 10)
+	if !assert.NoError(err) || !assert.Len(jobs, 2) {
+		return
+	}
+	for _, job := range jobs {
+		srv.runJob(ctx, job)
+	}
+
+	job, err := srv.env.Logic.LoadJob(ctx, project, interrupted.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.JobFinished, job.Status)
+	assert.Equal(2, job.Attempts)
+	assert.Equal(3, job.Processed)
+	assert.Equal(0, job.Failed)
+	assert.Nil(job.LeaseUntil)
+
+	for i, passcard := range passcards {
+		pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+		if !assert.NoError(err) {
+			return
+		}
+		if i == len(passcards)-1 {
+			assert.Len(pushes, 0)
+		} else {
+			assert.Len(pushes, 1)
+		}
+	}
+
+	job, err = srv.env.Logic.LoadJob(ctx, project, exhausted.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.JobFailed, job.Status)
+	assert.Equal(ErrJobAttempts.Error(), job.LastError)
+
+	jobs, err = srv.claimDueJobs(ctx, time.Now().Add(2*jobLease), 10)
+	assert.NoError(err)
+	assert.Empty(jobs)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// UpdateTemplateRequest holds project template. Empty template removes it.
+type UpdateTemplateRequest struct {
+	Template *api.Template `json:"template"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *UpdateTemplateRequest) IsValid() error {
+	if r.Template == nil {
+		return nil
+	}
+	return r.Template.IsValid()
+}
+
+// String returns string representation of struct.
+func (r *UpdateTemplateRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *Service) updateProjectTemplateHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req UpdateTemplateRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	err = s.env.Logic.SetProjectTemplate(ctx, req.Template, project)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SetProjectTemplate", err)
+	}
+
+	return sendJSON(w, http.StatusOK, project)
+}
+
+func (s *Service) renderProjectHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	if project.Template == nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ProjectTemplate", errors.New("project has no template"))
+	}
+
+	job, err := newJob(ctx, project, api.RenderJob, &jobParams{})
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewJob", err)
+	}
+
+	err = s.env.Logic.SaveNewJob(ctx, project, job)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewJob", err)
+	}
+
+	return sendJSON(w, http.StatusAccepted, job)
+}
+
+// renderPassCard applies project template to field values of pass card.
+// Values of fields missing in template are dropped.
+func (s *Service) renderPassCard(ctx context.Context, project *api.Project, passcard *api.PassCardInfo) error {
+	_, current, err := passcard.Data.Style()
+	if err != nil {
+		return err
+	}
+
+	structure, err := project.Template.Render(project.Template.Values(current))
+	if err != nil {
+		return err
+	}
+
+	data := *passcard.Data
+	s.setPassStructure(structure, project.PassType, &data)
+
+	_, err = interpolatePassCard(&data)
+	if err != nil {
+		return err
+	}
+
+	err = data.IsValid()
+	if err != nil {
+		return err
+	}
+
+	err = data.CheckChangeMessages(passcard.Data)
+	if err != nil {
+		return err
+	}
+
+	err = s.env.Logic.UpdatePassCard(ctx, &data, passcard)
+	if err != nil {
+		return err
+	}
+
+	return s.publishPassCard(ctx, project, passcard)
+}
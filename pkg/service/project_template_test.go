@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeTemplate() *api.Template {
+	return &api.Template{
+		PrimaryFields: []*api.TemplateField{
+			&api.TemplateField{
+				Field: api.Field{
+					Key:         "balance",
+					Label:       "BALANCE",
+					NumberStyle: api.PKNumberStyleDecimal,
+				},
+				Required: true,
+			},
+		},
+		AuxiliaryFields: []*api.TemplateField{
+			&api.TemplateField{
+				Field: api.Field{
+					Key:       "expires",
+					Label:     "EXPIRES",
+					DateStyle: api.PKDateStyleShort,
+				},
+			},
+		},
+		BackFields: []*api.TemplateField{
+			&api.TemplateField{
+				Field: api.Field{
+					Key:   "terms",
+					Label: "Terms",
+					Value: "No cash value",
+				},
+			},
+		},
+	}
+}
+
+func TestUpdateProjectTemplateHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Template *api.Template
+		Expected int
+	}{
+		{
+			Name:     "Valid",
+			Template: fakeTemplate(),
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "Remove",
+			Expected: http.StatusOK,
+		},
+		{
+			Name: "EmptyKey",
+			Template: &api.Template{
+				PrimaryFields: []*api.TemplateField{
+					&api.TemplateField{Field: api.Field{Label: "BALANCE"}},
+				},
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "DuplicatedKey",
+			Template: &api.Template{
+				PrimaryFields: []*api.TemplateField{
+					&api.TemplateField{Field: api.Field{Key: "balance"}},
+				},
+				BackFields: []*api.TemplateField{
+					&api.TemplateField{Field: api.Field{Key: "balance"}},
+				},
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Title:            fakeString(),
+				OrganizationName: fakeString(),
+				Description:      fakeString(),
+				PassType:         api.Coupon,
+				Template:         fakeTemplate(),
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			body, err := json.Marshal(UpdateTemplateRequest{Template: tc.Template})
+			if !assert.NoError(err) {
+				return
+			}
+
+			req := authRequest(srv, user, newRequest("PUT", fmt.Sprintf("/projects/%d/template", project.ID), body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode == http.StatusOK {
+				data := &api.Project{}
+				err = unmarshalJSON(resp, data)
+				if !assert.NoError(err) {
+					return
+				}
+				assert.Equal(tc.Template, data.Template)
+			}
+		})
+	}
+}
+
+func TestCreatePassCardWithTemplate(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Request  *CreatePassCardRequest
+		Expected int
+	}{
+		{
+			Name: "Values",
+			Request: &CreatePassCardRequest{
+				Values: map[string]interface{}{
+					"balance": 1500,
+					"expires": "2020-04-24T10:00-05:00",
+				},
+			},
+			Expected: http.StatusCreated,
+		},
+		{
+			Name: "RequiredMissing",
+			Request: &CreatePassCardRequest{
+				Values: map[string]interface{}{"expires": "2020-04-24T10:00-05:00"},
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "UnknownKey",
+			Request: &CreatePassCardRequest{
+				Values: map[string]interface{}{"balance": 1500, "points": 10},
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name: "Structure",
+			Request: &CreatePassCardRequest{
+				Structure: &api.PassStructure{
+					PrimaryFields: []*api.Field{
+						&api.Field{Key: "balance", Value: 1500},
+					},
+				},
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := &api.Project{
+				ID:               fakeID(),
+				Title:            fakeString(),
+				OrganizationName: fakeString(),
+				Description:      fakeString(),
+				PassType:         api.Coupon,
+				Template:         fakeTemplate(),
+			}
+
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			body, err := json.Marshal(tc.Request)
+			if !assert.NoError(err) {
+				return
+			}
+
+			req := authRequest(srv, user, newRequest("POST", fmt.Sprintf("/projects/%d/cards", project.ID), body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			if resp.StatusCode != http.StatusCreated {
+				return
+			}
+
+			var data struct {
+				SerialNumber string `json:"serialNumber"`
+			}
+			err = unmarshalJSON(resp, &data)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcard, err := srv.env.Logic.LoadPassCardBySerialNumber(ctx, project, data.SerialNumber)
+			if !assert.NoError(err) {
+				return
+			}
+
+			structure := passcard.Data.Coupon
+			if !assert.NotNil(structure) {
+				return
+			}
+			if assert.Len(structure.PrimaryFields, 1) {
+				assert.Equal("BALANCE", structure.PrimaryFields[0].Label)
+				assert.Equal(api.PKNumberStyleDecimal, structure.PrimaryFields[0].NumberStyle)
+				assert.EqualValues(1500, structure.PrimaryFields[0].Value)
+			}
+			if assert.Len(structure.AuxiliaryFields, 1) {
+				assert.Equal(api.PKDateStyleShort, structure.AuxiliaryFields[0].DateStyle)
+			}
+			if assert.Len(structure.BackFields, 1) {
+				assert.Equal("No cash value", structure.BackFields[0].Value)
+			}
+		})
+	}
+}
+
+func TestRenderProjectHandler(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := &api.Project{
+		ID:               fakeID(),
+		Title:            fakeString(),
+		OrganizationName: fakeString(),
+		Description:      fakeString(),
+		PassType:         api.Coupon,
+	}
+
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	req := authRequest(srv, user, newRequest("POST", fmt.Sprintf("/projects/%d/render", project.ID), nil, nil, nil))
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	if !assert.Equal(http.StatusBadRequest, rec.Result().StatusCode) {
+		return
+	}
+
+	passTypeID := srv.passTypeToString(project.PassType)
+
+	passcard := fakePassCard(project)
+	passcard.Data.Coupon = &api.PassStructure{
+		PrimaryFields: []*api.Field{
+			&api.Field{Key: "balance", Label: "OLD", Value: 1500},
+		},
+		SecondaryFields: []*api.Field{
+			&api.Field{Key: "removed", Value: "dropped from template"},
+		},
+	}
+	err = srv.env.Logic.SaveNewPassCard(ctx, project, passcard)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = srv.env.PassKit.InsertPass(ctx, passcard.Data.SerialNumber, fakeString(), passTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = srv.env.PassKit.InsertRegistration(ctx, fakeString(), fakeString(), passcard.Data.SerialNumber, passTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = srv.env.Logic.SetProjectTemplate(ctx, fakeTemplate(), project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	req = authRequest(srv, user, newRequest("POST", fmt.Sprintf("/projects/%d/render", project.ID), nil, nil, nil))
+	rec = httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	resp := rec.Result()
+
+	if !assert.Equal(http.StatusAccepted, resp.StatusCode) {
+		return
+	}
+
+	job := &api.Job{}
+	err = unmarshalJSON(resp, job)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.RenderJob, job.Kind)
+
+	job, err = waitJob(srv, user, project, job)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.JobFinished, job.Status)
+	assert.Equal(1, job.Processed)
+	assert.Equal(0, job.Failed)
+
+	loaded, err := srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	structure := loaded.Data.Coupon
+	if !assert.NotNil(structure) {
+		return
+	}
+	if assert.Len(structure.PrimaryFields, 1) {
+		assert.Equal("BALANCE", structure.PrimaryFields[0].Label)
+		assert.EqualValues(1500, structure.PrimaryFields[0].Value)
+	}
+	assert.Empty(structure.SecondaryFields)
+	assert.Len(structure.BackFields, 1)
+
+	pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+	if assert.NoError(err) {
+		assert.Len(pushes, 1)
+	}
+}
@@ -15,6 +15,7 @@ const (
 	iconImage       = "icon"
 	logoImage       = "logo"
 	stripImage      = "strip"
+	thumbnailImage  = "thumbnail"
 )
 
 // UploadImageRequest holds image type and uuid from uploads.
@@ -31,7 +32,7 @@ func (r *UploadImageRequest) IsValid() error {
 	}
 
 	switch r.Type {
-	case backgroundImage, footerImage, iconImage, logoImage, stripImage:
+	case backgroundImage, footerImage, iconImage, logoImage, stripImage, thumbnailImage:
 		break
 	default:
 		return errors.New("image type is invalid")
@@ -120,6 +121,12 @@ func (s *Service) uploadProjectImage(w http.ResponseWriter, r *http.Request) err
 			return s.httpError(w, r, http.StatusInternalServerError, "SetStripImage", err)
 		}
 		break
+	case thumbnailImage:
+		err = s.env.Logic.SetThumbnailImage(ctx, req.Size, req.UUID, project)
+		if err != nil {
+			return s.httpError(w, r, http.StatusInternalServerError, "SetThumbnailImage", err)
+		}
+		break
 	}
 
 	return sendJSON(w, http.StatusOK, project)
@@ -96,6 +96,21 @@ func TestUploadProjectImage(t *testing.T) {
 			Request: &UploadImageRequest{Type: stripImage, Size: api.ImageSize3x},
 			Path:    "testdata/gopher.jpg",
 		},
+		{
+			Name:    "Thumbnail1x",
+			Request: &UploadImageRequest{Type: thumbnailImage, Size: api.ImageSize1x},
+			Path:    "testdata/gopher.jpg",
+		},
+		{
+			Name:    "Thumbnail2x",
+			Request: &UploadImageRequest{Type: thumbnailImage, Size: api.ImageSize2x},
+			Path:    "testdata/gopher.jpg",
+		},
+		{
+			Name:    "Thumbnail3x",
+			Request: &UploadImageRequest{Type: thumbnailImage, Size: api.ImageSize3x},
+			Path:    "testdata/gopher.jpg",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -217,6 +232,15 @@ func TestUploadProjectImage(t *testing.T) {
 				case api.ImageSize3x:
 					assert.Equal(tc.Request.UUID, data.StripImage3x)
 				}
+			case thumbnailImage:
+				switch tc.Request.Size {
+				case api.ImageSize1x:
+					assert.Equal(tc.Request.UUID, data.ThumbnailImage)
+				case api.ImageSize2x:
+					assert.Equal(tc.Request.UUID, data.ThumbnailImage2x)
+				case api.ImageSize3x:
+					assert.Equal(tc.Request.UUID, data.ThumbnailImage3x)
+				}
 			}
 		})
 	}
@@ -46,16 +46,18 @@ func (s *Service) registerDevice(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return s.httpError(w, r, http.StatusInternalServerError, "FindRegistration", err)
 	}
-	if exists {
-		w.WriteHeader(http.StatusOK)
-		return nil
-	}
 
+	// Registration is stored anyway, so rotated push token replaces stale one.
 	err = s.env.PassKit.InsertRegistration(ctx, deviceID, register.PushToken, serialNumber, passTypeID)
 	if err != nil {
 		return s.httpError(w, r, http.StatusInternalServerError, "InsertRegistration", err)
 	}
 
+	if exists {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	return nil
 }
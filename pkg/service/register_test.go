@@ -41,12 +41,18 @@ func TestRegisterDevice(t *testing.T) {
 		return
 	}
 
-	testStatusCodes := []int{http.StatusCreated, http.StatusOK}
-	for _, code := range testStatusCodes {
+	testRegistrations := []struct {
+		Code      int
+		PushToken string
+	}{
+		{http.StatusCreated, "test-token"},
+		{http.StatusOK, "rotated-token"},
+	}
+	for _, reg := range testRegistrations {
 		req := newRequest(
 			"POST",
 			fmt.Sprintf("/v1/devices/%s/registrations/%s/%s", testCase.DeviceID, testCase.PassTypeID, testCase.SerialNumber),
-			[]byte(`{"pushToken":"test-token"}`),
+			[]byte(fmt.Sprintf(`{"pushToken":"%s"}`, reg.PushToken)),
 			map[string]string{"Authorization": "ApplePass " + testCase.AuthToken},
 			nil,
 		)
@@ -55,6 +61,11 @@ func TestRegisterDevice(t *testing.T) {
 		srv.ServeHTTP(rec, req)
 		resp := rec.Result()
 
-		assert.Equal(code, resp.StatusCode)
+		assert.Equal(reg.Code, resp.StatusCode)
+
+		pushTokens, err := srv.env.PassKit.FindPushTokens(context.Background(), testCase.SerialNumber)
+		if assert.NoError(err) {
+			assert.Equal([]string{reg.PushToken}, pushTokens)
+		}
 	}
 }
@@ -42,7 +42,7 @@ func (s *Service) withRouter() *Service {
 		public.HandleFunc(appleLogRoute, s.errorLogs).Methods("POST")
 
 		protected := apple.NewRoute().Subrouter()
-		protected.Use(applePassMiddleware)
+		protected.Use(s.applePassMiddleware)
 		protected.HandleFunc(appleRegisterRoute, s.registerDevice).Methods("POST")
 		protected.HandleFunc(appleUnregisterRoute, s.unregisterDevice).Methods("DELETE")
 		protected.HandleFunc(appleLatestRoute, s.latestPass).Methods("GET")
@@ -87,18 +87,48 @@ func (s *Service) withRouter() *Service {
 		projects := protected.PathPrefix("/projects").Subrouter()
 		projects.HandleFunc("/check", s.checkProjectHandler).Methods("POST")
 		projects.HandleFunc("", s.createProjectHandler).Methods("POST")
+		projects.HandleFunc("/import", s.importProjectHandler).Methods("POST")
 		projects.HandleFunc("", s.userProjectsHandler).Methods("GET")
 		projects.HandleFunc("/{id:[0-9]+}", s.userProjectHandler).Methods("GET")
 		projects.HandleFunc("/{id:[0-9]+}", s.updateProjectHandler).Methods("PUT")
 		projects.HandleFunc("/{id:[0-9]+}/upload", s.uploadProjectImage).Methods("POST")
+		projects.HandleFunc("/{id:[0-9]+}/localizations", s.updateProjectLocalizationsHandler).Methods("PUT")
+		projects.HandleFunc("/{id:[0-9]+}/template", s.updateProjectTemplateHandler).Methods("PUT")
+		projects.HandleFunc("/{id:[0-9]+}/expiration", s.updateProjectExpirationHandler).Methods("PUT")
+		projects.HandleFunc("/{id:[0-9]+}/render", s.renderProjectHandler).Methods("POST")
+		projects.HandleFunc("/{id:[0-9]+}/publish", s.publishProjectHandler).Methods("POST")
+		projects.HandleFunc("/{id:[0-9]+}/jobs", s.projectJobsHandler).Methods("GET")
+		projects.HandleFunc("/{id:[0-9]+}/jobs/{jobID:[0-9]+}", s.projectJobHandler).Methods("GET")
+		projects.HandleFunc("/{id:[0-9]+}/jobs/{jobID:[0-9]+}/results", s.projectJobResultsHandler).Methods("GET")
+		projects.HandleFunc("/{id:[0-9]+}/schedules", s.schedulePassCardsHandler).Methods("POST")
+		projects.HandleFunc("/{id:[0-9]+}/schedules", s.scheduledUpdatesHandler).Methods("GET")
+		projects.HandleFunc("/{id:[0-9]+}/schedules/{scheduleID:[0-9]+}", s.scheduledUpdateHandler).Methods("GET")
+		projects.HandleFunc("/{id:[0-9]+}/schedules/{scheduleID:[0-9]+}", s.rescheduleUpdateHandler).Methods("PATCH")
+		projects.HandleFunc("/{id:[0-9]+}/schedules/{scheduleID:[0-9]+}", s.cancelScheduledUpdateHandler).Methods("DELETE")
 
 		cards := projects.PathPrefix("/{id:[0-9]+}/cards").Subrouter()
 		cards.HandleFunc("", s.createPassCardHandler).Methods("POST")
+		cards.HandleFunc("", s.bulkUpdatePassCardsHandler).Methods("PATCH")
+		cards.HandleFunc("", s.bulkDeletePassCardsHandler).Methods("DELETE")
 		cards.HandleFunc("", s.projectPassCardsHandler).Methods("GET")
 		cards.HandleFunc("/{cardID:[0-9]+}", s.projectPassCardHandler).Methods("GET")
 		cards.HandleFunc("/{serialNumber}", s.projectPassCardBySerialNumberHandler).Methods("GET")
 		cards.HandleFunc("/{cardID:[0-9]+}", s.updatePassCardHandler).Methods("PUT")
 		cards.HandleFunc("/{serialNumber}", s.updatePassCardBySerialNumberHandler).Methods("PUT")
+		cards.HandleFunc("/{cardID:[0-9]+}", s.patchPassCardHandler).Methods("PATCH")
+		cards.HandleFunc("/{serialNumber}", s.patchPassCardBySerialNumberHandler).Methods("PATCH")
+		cards.HandleFunc("/{cardID:[0-9]+}", s.deletePassCardHandler).Methods("DELETE")
+		cards.HandleFunc("/{serialNumber}", s.deletePassCardBySerialNumberHandler).Methods("DELETE")
+		cards.HandleFunc("/{cardID:[0-9]+}/pushes", s.passCardPushesHandler).Methods("GET")
+		cards.HandleFunc("/{cardID:[0-9]+}/versions", s.passCardVersionsHandler).Methods("GET")
+		cards.HandleFunc("/{cardID:[0-9]+}/versions/diff", s.passCardVersionsDiffHandler).Methods("GET")
+		cards.HandleFunc("/{cardID:[0-9]+}/versions/{version:[0-9]+}/rollback", s.rollbackPassCardHandler).Methods("POST")
+		cards.HandleFunc("/{cardID:[0-9]+}/schedules", s.schedulePassCardHandler).Methods("POST")
+		cards.HandleFunc("/bundle", s.bundlePassCardsHandler).Methods("POST")
+		cards.HandleFunc("/bulk", s.bulkCreatePassCardsHandler).Methods("POST")
+
+		passes := protected.PathPrefix("/passes").Subrouter()
+		passes.HandleFunc("/verify", s.verifyPassHandler).Methods("POST")
 
 		dictionary := protected.PathPrefix("/dictionary").Subrouter()
 		dictionary.HandleFunc("/passtypes", s.passTypesHandler).Methods("GET")
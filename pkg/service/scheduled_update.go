@@ -0,0 +1,502 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"go.uber.org/zap"
+)
+
+const (
+	scheduleInterval  = 5 * time.Second
+	scheduleBatchSize = 100
+	scheduleLease     = 30 * time.Second
+)
+
+// ErrScheduleNotPending raised when applied or canceled update is changed.
+var ErrScheduleNotPending = errors.New("schedule: update is not pending")
+
+// ScheduleRequest holds patch applied to pass cards at effective time.
+type ScheduleRequest struct {
+	Filter      *PassCardFilter `json:"filter,omitempty"`
+	Patch       *PassCardPatch  `json:"patch"`
+	EffectiveAt *time.Time      `json:"effectiveAt"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *ScheduleRequest) IsValid() error {
+	err := checkEffectiveAt(r.EffectiveAt)
+	if err != nil {
+		return err
+	}
+
+	if r.Filter != nil {
+		err = r.Filter.IsValid()
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Patch == nil {
+		return errors.New("patch is required")
+	}
+	return r.Patch.IsValid()
+}
+
+// String returns string representation of struct.
+func (r *ScheduleRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// RescheduleRequest holds new effective time of pending update.
+type RescheduleRequest struct {
+	EffectiveAt *time.Time `json:"effectiveAt"`
+}
+
+// IsValid checks whether input is valid or not.
+func (r *RescheduleRequest) IsValid() error {
+	return checkEffectiveAt(r.EffectiveAt)
+}
+
+// String returns string representation of struct.
+func (r *RescheduleRequest) String() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func checkEffectiveAt(effectiveAt *time.Time) error {
+	if effectiveAt == nil || effectiveAt.IsZero() {
+		return errors.New("effectiveAt is required")
+	}
+	if effectiveAt.Before(time.Now()) {
+		return errors.New("effectiveAt must be in future")
+	}
+	return nil
+}
+
+func (s *Service) schedulePassCardsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req ScheduleRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+	if req.Filter == nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", errors.New("filter is required"))
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	update, err := newScheduledUpdate(ctx, project, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewScheduledUpdate", err)
+	}
+
+	err = s.env.Logic.SaveNewScheduledUpdate(ctx, project, update)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewScheduledUpdate", err)
+	}
+
+	return sendJSON(w, http.StatusCreated, update)
+}
+
+func (s *Service) schedulePassCardHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req ScheduleRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+	if req.Filter != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", errors.New("filter is not allowed"))
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	cardID, err := s.idFromRequest(r, "cardID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	passcard, err := s.env.Logic.LoadPassCard(ctx, project, cardID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadPassCard", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadPassCard", err)
+	}
+
+	update, err := newScheduledUpdate(ctx, project, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "NewScheduledUpdate", err)
+	}
+	update.PassCardID = passcard.ID
+
+	err = s.env.Logic.SaveNewScheduledUpdate(ctx, project, update)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "SaveNewScheduledUpdate", err)
+	}
+
+	return sendJSON(w, http.StatusCreated, update)
+}
+
+func (s *Service) scheduledUpdatesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	opts, err := readPagingOptions(r)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadPagingOptions", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	updates, err := s.env.Logic.LoadScheduledUpdates(ctx, project, opts)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadScheduledUpdates", err)
+	}
+
+	return sendPaginatedJSON(w, http.StatusOK, updates.Opts, updates.Data)
+}
+
+func (s *Service) scheduledUpdateHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	scheduleID, err := s.idFromRequest(r, "scheduleID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	update, err := s.env.Logic.LoadScheduledUpdate(ctx, project, scheduleID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadScheduledUpdate", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadScheduledUpdate", err)
+	}
+
+	return sendJSON(w, http.StatusOK, update)
+}
+
+func (s *Service) rescheduleUpdateHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var req RescheduleRequest
+	err := readJSON(r, &req)
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "ReadJSON", err)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	scheduleID, err := s.idFromRequest(r, "scheduleID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	update, err := s.env.Logic.LoadScheduledUpdate(ctx, project, scheduleID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadScheduledUpdate", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadScheduledUpdate", err)
+	}
+
+	update.EffectiveAt = *req.EffectiveAt
+	return s.changePendingUpdate(w, r, update)
+}
+
+func (s *Service) cancelScheduledUpdateHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return s.httpError(w, r, http.StatusUnauthorized, "UserFromContext", err)
+	}
+
+	id, err := s.idFromRequest(r, "id")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, id)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadProject", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadProject", err)
+	}
+
+	scheduleID, err := s.idFromRequest(r, "scheduleID")
+	if err != nil {
+		return s.httpError(w, r, http.StatusBadRequest, "IDFromRequest", err)
+	}
+
+	update, err := s.env.Logic.LoadScheduledUpdate(ctx, project, scheduleID)
+	if err == store.ErrNotFound {
+		return s.httpError(w, r, http.StatusNotFound, "LoadScheduledUpdate", err)
+	}
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "LoadScheduledUpdate", err)
+	}
+
+	update.Status = api.ScheduleCanceled
+	return s.changePendingUpdate(w, r, update)
+}
+
+// changePendingUpdate stores changes of update unless scheduler has claimed
+// it in the meantime.
+func (s *Service) changePendingUpdate(w http.ResponseWriter, r *http.Request, update *api.ScheduledUpdate) error {
+	ok, err := s.env.Logic.UpdateScheduledUpdate(r.Context(), update, api.SchedulePending)
+	if err != nil {
+		return s.httpError(w, r, http.StatusInternalServerError, "UpdateScheduledUpdate", err)
+	}
+	if !ok {
+		return s.httpError(w, r, http.StatusConflict, "UpdateScheduledUpdate", ErrScheduleNotPending)
+	}
+
+	return sendJSON(w, http.StatusOK, update)
+}
+
+func newScheduledUpdate(ctx context.Context, project *api.Project, req *ScheduleRequest) (*api.ScheduledUpdate, error) {
+	update := api.NewScheduledUpdate(project.ID, *req.EffectiveAt, api.AuthorFromContext(ctx))
+
+	patch, err := json.Marshal(req.Patch)
+	if err != nil {
+		return nil, err
+	}
+	update.Patch = patch
+
+	if req.Filter != nil {
+		filter, err := json.Marshal(req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		update.Filter = filter
+	}
+
+	return update, nil
+}
+
+// applyDueScheduledUpdates claims pending updates effective by given time
+// and running updates whose claim has expired. Patch is applied by job
+// worker, scheduler only enqueues job and collects its outcome.
+func (s *Service) applyDueScheduledUpdates(ctx context.Context, now time.Time) (int, error) {
+	updates, err := s.env.Logic.LoadDueScheduledUpdates(ctx, now, scheduleLease, scheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	claimed := 0
+	for _, update := range updates {
+		ok, err := s.env.Logic.ClaimScheduledUpdate(ctx, update)
+		if err != nil {
+			return claimed, err
+		}
+		if !ok {
+			continue
+		}
+
+		s.applyScheduledUpdate(ctx, update)
+		claimed++
+	}
+
+	return claimed, nil
+}
+
+// applyScheduledUpdate enqueues patch job of claimed update or, once job is
+// done, stores outcome of update. Update with unfinished job is checked
+// again after claim expires.
+func (s *Service) applyScheduledUpdate(ctx context.Context, update *api.ScheduledUpdate) {
+	logger := s.logger.
+		With(zap.Int64("schedule_id", update.ID)).
+		With(zap.Int64("project_id", update.ProjectID))
+
+	job, err := s.scheduledUpdateJob(ctx, update)
+	switch {
+	case err != nil:
+		update.Status = api.ScheduleFailed
+		update.LastError = err.Error()
+	case !job.Done():
+	case job.Status == api.JobFailed, job.Total > 0 && job.Failed == job.Total:
+		update.Status = api.ScheduleFailed
+		update.LastError = job.LastError
+	default:
+		update.Status = api.ScheduleApplied
+		update.LastError = job.LastError
+	}
+	if job != nil {
+		update.JobID = job.ID
+	}
+
+	_, err = s.env.Logic.UpdateScheduledUpdate(ctx, update, api.ScheduleRunning)
+	if err != nil {
+		logger.Error("schedule_save_error", zap.Error(err))
+	}
+}
+
+// scheduledUpdateJob returns patch job of update. New job is saved on
+// behalf of author of update, so pass card history shows who has scheduled
+// changes.
+func (s *Service) scheduledUpdateJob(ctx context.Context, update *api.ScheduledUpdate) (*api.Job, error) {
+	user, err := s.env.Auth.LoadUser(ctx, update.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.env.Logic.LoadProject(ctx, user, update.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.JobID > 0 {
+		return s.env.Logic.LoadJob(ctx, project, update.JobID)
+	}
+
+	params := &jobParams{
+		PassCardID: update.PassCardID,
+		Patch:      &PassCardPatch{},
+	}
+
+	err = json.Unmarshal(update.Patch, params.Patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.PassCardID == 0 {
+		params.Filter = &PassCardFilter{}
+		err = json.Unmarshal(update.Filter, params.Filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	job, err := newJob(api.WithAuthor(ctx, update.Author()), project, api.PatchJob, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.env.Logic.SaveNewJob(ctx, project, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// RunScheduler applies scheduled updates once they are effective until
+// context is canceled.
+func (s *Service) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := s.applyDueScheduledUpdates(ctx, time.Now())
+		if err != nil {
+			s.logger.Error("schedule_error", zap.Error(err), zap.Int("claimed", claimed))
+		} else if claimed > 0 {
+			s.logger.Info("schedule", zap.Int("claimed", claimed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulePassCardsHandler(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		ByCard   bool
+		Request  func(passcards []*api.PassCardInfo, effectiveAt time.Time) string
+		Expected int
+		Applied  int
+	}{
+		{
+			Name:   "PassCard",
+			ByCard: true,
+			Request: func(passcards []*api.PassCardInfo, effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"patch":{"fields":{"offer":{"value":"50%% off"}}},"effectiveAt":"%s"}`,
+					effectiveAt.Format(time.RFC3339))
+			},
+			Expected: http.StatusCreated,
+			Applied:  1,
+		},
+		{
+			Name: "Filter",
+			Request: func(passcards []*api.PassCardInfo, effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"filter":{"serialNumbers":["%s","%s"]},"patch":{"fields":{"offer":{"value":"50%% off"}}},"effectiveAt":"%s"}`,
+					passcards[0].Data.SerialNumber, passcards[1].Data.SerialNumber, effectiveAt.Format(time.RFC3339))
+			},
+			Expected: http.StatusCreated,
+			Applied:  2,
+		},
+		{
+			Name: "FilterRequired",
+			Request: func(passcards []*api.PassCardInfo, effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"patch":{"fields":{"offer":{"value":"50%% off"}}},"effectiveAt":"%s"}`,
+					effectiveAt.Format(time.RFC3339))
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:   "FilterNotAllowed",
+			ByCard: true,
+			Request: func(passcards []*api.PassCardInfo, effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"filter":{"all":true},"patch":{"fields":{"offer":{"value":"50%% off"}}},"effectiveAt":"%s"}`,
+					effectiveAt.Format(time.RFC3339))
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:   "PastEffectiveAt",
+			ByCard: true,
+			Request: func(passcards []*api.PassCardInfo, effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"patch":{"fields":{"offer":{"value":"50%% off"}}},"effectiveAt":"%s"}`,
+					time.Now().Add(-time.Hour).Format(time.RFC3339))
+			},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Name:   "MissingEffectiveAt",
+			ByCard: true,
+			Request: func(passcards []*api.PassCardInfo, effectiveAt time.Time) string {
+				return `{"patch":{"fields":{"offer":{"value":"50% off"}}}}`
+			},
+			Expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcards := []*api.PassCardInfo{}
+			for i := 0; i < 3; i++ {
+				passcard, err := fakeRegisteredPassCard(srv, project)
+				if !assert.NoError(err) {
+					return
+				}
+				passcards = append(passcards, passcard)
+			}
+
+			effectiveAt := time.Now().Add(time.Hour)
+
+			path := fmt.Sprintf("/projects/%d/schedules", project.ID)
+			if tc.ByCard {
+				path = fmt.Sprintf("/projects/%d/cards/%d/schedules", project.ID, passcards[0].ID)
+			}
+
+			body := []byte(tc.Request(passcards, effectiveAt))
+			req := authRequest(srv, user, newRequest("POST", path, body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+			if resp.StatusCode != http.StatusCreated {
+				return
+			}
+
+			update := &api.ScheduledUpdate{}
+			err = unmarshalJSON(resp, update)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.SchedulePending, update.Status)
+			assert.Equal(user.ID, update.UserID)
+
+			applied, err := srv.applyDueScheduledUpdates(ctx, time.Now())
+			if assert.NoError(err) {
+				assert.Equal(0, applied)
+			}
+
+			applied, err = srv.applyDueScheduledUpdates(ctx, effectiveAt.Add(time.Second))
+			if assert.NoError(err) {
+				assert.Equal(1, applied)
+			}
+
+			update, err = srv.env.Logic.LoadScheduledUpdate(ctx, project, update.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.ScheduleRunning, update.Status)
+
+			job, err := srv.env.Logic.LoadJob(ctx, project, update.JobID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.PatchJob, job.Kind)
+			assert.Equal(api.JobPending, job.Status)
+
+			job, err = waitJob(srv, user, project, job)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.JobFinished, job.Status)
+			assert.Equal(tc.Applied, job.Processed)
+			assert.Equal(0, job.Failed)
+
+			applied, err = srv.applyDueScheduledUpdates(ctx, effectiveAt.Add(time.Second))
+			if assert.NoError(err) {
+				assert.Equal(1, applied)
+			}
+
+			update, err = srv.env.Logic.LoadScheduledUpdate(ctx, project, update.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(api.ScheduleApplied, update.Status)
+			assert.Equal(job.ID, update.JobID)
+			assert.Empty(update.LastError)
+
+			for i, passcard := range passcards {
+				loaded, err := srv.env.Logic.LoadPassCard(ctx, project, passcard.ID)
+				if !assert.NoError(err) {
+					return
+				}
+
+				pushes, err := srv.env.PassKit.LoadPushes(ctx, passcard.Data.SerialNumber)
+				if !assert.NoError(err) {
+					return
+				}
+
+				if i >= tc.Applied {
+					assert.Equal("20% off", loaded.Data.Coupon.PrimaryFields[0].Value)
+					assert.Len(pushes, 0)
+					continue
+				}
+
+				assert.Equal("50% off", loaded.Data.Coupon.PrimaryFields[0].Value)
+				assert.Len(pushes, 1)
+
+				version, err := srv.env.Logic.LoadPassCardVersion(ctx, loaded, loaded.Version)
+				if assert.NoError(err) {
+					assert.Equal(user.ID, version.UserID)
+				}
+			}
+		})
+	}
+}
+
+func TestChangeScheduledUpdateHandlers(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Method   string
+		Body     func(effectiveAt time.Time) string
+		Status   api.ScheduleStatus
+		Expected int
+		Result   api.ScheduleStatus
+		Delay    time.Duration
+	}{
+		{
+			Name:     "Cancel",
+			Method:   "DELETE",
+			Status:   api.SchedulePending,
+			Expected: http.StatusOK,
+			Result:   api.ScheduleCanceled,
+		},
+		{
+			Name:     "CancelApplied",
+			Method:   "DELETE",
+			Status:   api.ScheduleApplied,
+			Expected: http.StatusConflict,
+			Result:   api.ScheduleApplied,
+		},
+		{
+			Name:   "Reschedule",
+			Method: "PATCH",
+			Body: func(effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"effectiveAt":"%s"}`, effectiveAt.Add(time.Hour).Format(time.RFC3339))
+			},
+			Status:   api.SchedulePending,
+			Expected: http.StatusOK,
+			Result:   api.SchedulePending,
+			Delay:    time.Hour,
+		},
+		{
+			Name:   "RescheduleToPast",
+			Method: "PATCH",
+			Body: func(effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"effectiveAt":"%s"}`, time.Now().Add(-time.Hour).Format(time.RFC3339))
+			},
+			Status:   api.SchedulePending,
+			Expected: http.StatusBadRequest,
+			Result:   api.SchedulePending,
+		},
+		{
+			Name:   "RescheduleCanceled",
+			Method: "PATCH",
+			Body: func(effectiveAt time.Time) string {
+				return fmt.Sprintf(`{"effectiveAt":"%s"}`, effectiveAt.Add(time.Hour).Format(time.RFC3339))
+			},
+			Status:   api.ScheduleCanceled,
+			Expected: http.StatusConflict,
+			Result:   api.ScheduleCanceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			srv, err := initService(t)
+			if !assert.NoError(err) {
+				return
+			}
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+			err = srv.env.Auth.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = srv.env.Logic.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			effectiveAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+			update := api.NewScheduledUpdate(project.ID, effectiveAt, api.Author{UserID: user.ID})
+			update.Filter = api.RawJSON(`{"all":true}`)
+			update.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+			update.Status = tc.Status
+			err = srv.env.Logic.SaveNewScheduledUpdate(ctx, project, update)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var body []byte
+			if tc.Body != nil {
+				body = []byte(tc.Body(effectiveAt))
+			}
+
+			path := fmt.Sprintf("/projects/%d/schedules/%d", project.ID, update.ID)
+			req := authRequest(srv, user, newRequest(tc.Method, path, body, nil, nil))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if !assert.Equal(tc.Expected, resp.StatusCode) {
+				return
+			}
+
+			loaded, err := srv.env.Logic.LoadScheduledUpdate(ctx, project, update.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Result, loaded.Status)
+			assert.True(effectiveAt.Add(tc.Delay).Equal(loaded.EffectiveAt))
+
+			applied, err := srv.applyDueScheduledUpdates(ctx, effectiveAt.Add(time.Second))
+			if assert.NoError(err) {
+				if tc.Result == api.SchedulePending && tc.Delay == 0 {
+					assert.Equal(1, applied)
+				} else {
+					assert.Equal(0, applied)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduledUpdatesHandler(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		update := api.NewScheduledUpdate(project.ID, time.Now().Add(time.Hour), api.Author{UserID: user.ID})
+		update.Filter = api.RawJSON(`{"all":true}`)
+		update.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+		err = srv.env.Logic.SaveNewScheduledUpdate(ctx, project, update)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	path := fmt.Sprintf("/projects/%d/schedules", project.ID)
+	req := authRequest(srv, user, newRequest("GET", path, nil, nil, nil))
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+	resp := rec.Result()
+
+	if !assert.Equal(http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	var page struct {
+		Data []*api.ScheduledUpdate `json:"data"`
+	}
+	err = unmarshalJSON(resp, &page)
+	if assert.NoError(err) && assert.Len(page.Data, 2) {
+		assert.JSONEq(`{"all":true}`, string(page.Data[0].Filter))
+		assert.JSONEq(`{"data":{"logoText":"Sale"}}`, string(page.Data[0].Patch))
+	}
+}
+
+func TestReclaimScheduledUpdate(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	srv, err := initService(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakePassword(), nil)
+	err = srv.env.Auth.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = srv.env.Logic.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// Scheduler has claimed update and stopped before job is saved.
+	now := time.Now()
+	update := api.NewScheduledUpdate(project.ID, now.Add(-time.Hour), api.Author{UserID: user.ID})
+	update.Filter = api.RawJSON(`{"all":true}`)
+	update.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+	update.Status = api.ScheduleRunning
+	update.ClaimedAt = &now
+	err = srv.env.Logic.SaveNewScheduledUpdate(ctx, project, update)
+	if !assert.NoError(err) {
+		return
+	}
+
+	claimed, err := srv.applyDueScheduledUpdates(ctx, now.Add(scheduleLease/2))
+	if assert.NoError(err) {
+		assert.Equal(0, claimed)
+	}
+
+	claimed, err = srv.applyDueScheduledUpdates(ctx, now.Add(scheduleLease))
+	if assert.NoError(err) {
+		assert.Equal(1, claimed)
+	}
+
+	loaded, err := srv.env.Logic.LoadScheduledUpdate(ctx, project, update.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.ScheduleRunning, loaded.Status)
+	assert.True(loaded.ClaimedAt.After(now))
+
+	job, err := srv.env.Logic.LoadJob(ctx, project, loaded.JobID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.JobPending, job.Status)
+	assert.Equal(user.ID, job.UserID)
+
+	// Unfinished job keeps update running until the next claim.
+	claimed, err = srv.applyDueScheduledUpdates(ctx, loaded.ClaimedAt.Add(scheduleLease))
+	if assert.NoError(err) {
+		assert.Equal(1, claimed)
+	}
+
+	loaded, err = srv.env.Logic.LoadScheduledUpdate(ctx, project, update.ID)
+	if assert.NoError(err) {
+		assert.Equal(api.ScheduleRunning, loaded.Status)
+		assert.Equal(job.ID, loaded.JobID)
+	}
+}
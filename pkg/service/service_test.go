@@ -73,7 +73,7 @@ func fakePassCard(project *api.Project) *api.PassCardInfo {
 	}
 }
 
-func initService(t *testing.T) (*Service, error) {
+func initService(t testing.TB) (*Service, error) {
 	_, err := env.NewLookup(
 		"TEST_UPLOAD_BUCKET",
 		"TEST_PASSES_BUCKET",
@@ -9,6 +9,7 @@ import (
 
 	"github.com/danikarik/mux"
 	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/pkpass"
 )
 
 var (
@@ -44,6 +45,10 @@ func (s *Service) hostURL() string {
 	return ""
 }
 
+func (s *Service) downloadURL(serialNumber string) string {
+	return s.hostURL() + "/downloads/" + serialNumber + pkpass.Extension
+}
+
 func (s *Service) appURL(path string) string {
 	if s.env.Config.Debug {
 		return "http://localhost:3000" + path
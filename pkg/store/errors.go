@@ -13,6 +13,8 @@ var (
 	ErrZeroRowsAffected = errors.New("store: zero rows affected")
 	// ErrNotFound raises when record not found.
 	ErrNotFound = errors.New("store: record not found")
+	// ErrVersionMismatch raises when record is changed since it was loaded.
+	ErrVersionMismatch = errors.New("store: version mismatch")
 	// ErrWrongPassword raises when input password doesn't match.
 	ErrWrongPassword = errors.New("store: wrong password")
 )
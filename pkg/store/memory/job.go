@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// SaveNewJob ...
+func (m *Memory) SaveNewJob(ctx context.Context, project *api.Project, job *api.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job == nil {
+		return store.ErrNilStruct
+	}
+	m.jobSeq++
+	job.ID = m.jobSeq
+	job.ProjectID = project.ID
+	clone := *job
+	m.jobs[job.ID] = &clone
+	return nil
+}
+
+// LoadJob ...
+func (m *Memory) LoadJob(ctx context.Context, project *api.Project, id int64) (*api.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || job.ProjectID != project.ID {
+		return nil, store.ErrNotFound
+	}
+	clone := *job
+	return &clone, nil
+}
+
+// LoadJobs ...
+func (m *Memory) LoadJobs(ctx context.Context, project *api.Project, opts *api.PagingOptions) (*api.Jobs, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := []*api.Job{}
+	for _, job := range m.jobs {
+		if job.ProjectID == project.ID {
+			clone := *job
+			data = append(data, &clone)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID > data[j].ID })
+	return &api.Jobs{Opts: opts, Data: data}, nil
+}
+
+// LoadPendingJobs ...
+func (m *Memory) LoadPendingJobs(ctx context.Context, now time.Time, limit uint64) ([]*api.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := []*api.Job{}
+	for _, job := range m.jobs {
+		expired := job.Status == api.JobRunning && job.LeaseUntil != nil && !job.LeaseUntil.After(now)
+		if job.Status == api.JobPending || expired {
+			clone := *job
+			data = append(data, &clone)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	if limit > 0 && uint64(len(data)) > limit {
+		data = data[:limit]
+	}
+	return data, nil
+}
+
+// ClaimJob ...
+func (m *Memory) ClaimJob(ctx context.Context, lease time.Duration, job *api.Job) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job == nil {
+		return false, store.ErrNilStruct
+	}
+	stored, ok := m.jobs[job.ID]
+	if !ok || stored.Attempts != job.Attempts {
+		return false, nil
+	}
+	if stored.Status != api.JobPending && stored.Status != api.JobRunning {
+		return false, nil
+	}
+	now := time.Now()
+	leaseUntil := now.Add(lease)
+	stored.Status = api.JobRunning
+	stored.Attempts++
+	stored.LeaseUntil = &leaseUntil
+	stored.UpdatedAt = now
+	*job = *stored
+	return true, nil
+}
+
+// UpdateJob ...
+func (m *Memory) UpdateJob(ctx context.Context, job *api.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job == nil {
+		return store.ErrNilStruct
+	}
+	stored, ok := m.jobs[job.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if stored.Attempts != job.Attempts {
+		return store.ErrVersionMismatch
+	}
+	job.UpdatedAt = time.Now()
+	clone := *job
+	m.jobs[job.ID] = &clone
+	return nil
+}
+
+// SaveJobResult ...
+func (m *Memory) SaveJobResult(ctx context.Context, job *api.Job, result *api.JobResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job == nil || result == nil {
+		return store.ErrNilStruct
+	}
+	m.jobResultSeq++
+	result.ID = m.jobResultSeq
+	result.JobID = job.ID
+	clone := *result
+	m.jobResults[result.ID] = &clone
+	return nil
+}
+
+// LoadJobResults ...
+func (m *Memory) LoadJobResults(ctx context.Context, job *api.Job, opts *api.PagingOptions) (*api.JobResults, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := []*api.JobResult{}
+	for _, result := range m.jobResults {
+		if result.JobID == job.ID {
+			clone := *result
+			data = append(data, &clone)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return &api.JobResults{Opts: opts, Data: data}, nil
+}
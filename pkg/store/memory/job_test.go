@@ -0,0 +1,187 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"github.com/danikarik/okpock/pkg/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	mock := memory.New()
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	project.ID = fakeID()
+
+	other := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	other.ID = project.ID + 1
+
+	first := api.NewJob(api.PublishJob, project.ID, api.Author{})
+	err := mock.SaveNewJob(ctx, project, first)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(first.ID > 0)
+
+	second := api.NewJob(api.PublishJob, project.ID, api.Author{})
+	err = mock.SaveNewJob(ctx, project, second)
+	if !assert.NoError(err) {
+		return
+	}
+
+	first.Status = api.JobRunning
+	first.Total = 10
+	first.Processed = 4
+	first.Failed = 1
+	first.LastError = "upload failed"
+	err = mock.UpdateJob(ctx, first)
+	if !assert.NoError(err) {
+		return
+	}
+
+	loaded, err := mock.LoadJob(ctx, project, first.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.JobRunning, loaded.Status)
+	assert.Equal(10, loaded.Total)
+	assert.Equal(4, loaded.Processed)
+	assert.Equal(1, loaded.Failed)
+	assert.Equal("upload failed", loaded.LastError)
+	assert.False(loaded.Done())
+
+	loaded.Finish(api.JobFinished)
+	err = mock.UpdateJob(ctx, loaded)
+	if !assert.NoError(err) {
+		return
+	}
+
+	loaded, err = mock.LoadJob(ctx, project, first.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(loaded.Done())
+	assert.NotNil(loaded.FinishedAt)
+
+	jobs, err := mock.LoadJobs(ctx, project, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(jobs.Data, 2) {
+		return
+	}
+	assert.Equal(second.ID, jobs.Data[0].ID)
+	assert.Equal(first.ID, jobs.Data[1].ID)
+
+	_, err = mock.LoadJob(ctx, other, first.ID)
+	assert.Equal(store.ErrNotFound, err)
+
+	jobs, err = mock.LoadJobs(ctx, other, api.NewPagingOptions(0, 0))
+	assert.NoError(err)
+	assert.Empty(jobs.Data)
+
+	failed := api.NewJobResult(1)
+	failed.Error = "pass structure: only one style allowed"
+	err = mock.SaveJobResult(ctx, first, failed)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(failed.ID > 0)
+
+	succeeded := api.NewJobResult(2)
+	succeeded.PassCardID = 42
+	succeeded.SerialNumber = fakeString()
+	err = mock.SaveJobResult(ctx, first, succeeded)
+	if !assert.NoError(err) {
+		return
+	}
+
+	results, err := mock.LoadJobResults(ctx, first, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(results.Data, 2) {
+		return
+	}
+	assert.Equal(1, results.Data[0].Row)
+	assert.False(results.Data[0].Succeeded())
+	assert.Equal(2, results.Data[1].Row)
+	assert.True(results.Data[1].Succeeded())
+	assert.Equal(succeeded.SerialNumber, results.Data[1].SerialNumber)
+
+	results, err = mock.LoadJobResults(ctx, second, nil)
+	assert.NoError(err)
+	assert.Empty(results.Data)
+}
+
+func TestClaimJob(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	mock := memory.New()
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	project.ID = fakeID()
+
+	job := api.NewJob(api.PublishJob, project.ID, api.Author{UserID: fakeID()})
+	err := mock.SaveNewJob(ctx, project, job)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pending, err := mock.LoadPendingJobs(ctx, time.Now(), 10)
+	if !assert.NoError(err) || !assert.Len(pending, 1) {
+		return
+	}
+	stale := *pending[0]
+
+	ok, err := mock.ClaimJob(ctx, time.Minute, pending[0])
+	if !assert.NoError(err) || !assert.True(ok) {
+		return
+	}
+	claimed := pending[0]
+	assert.Equal(api.JobRunning, claimed.Status)
+	assert.Equal(1, claimed.Attempts)
+	assert.NotNil(claimed.LeaseUntil)
+
+	ok, err = mock.ClaimJob(ctx, time.Minute, &stale)
+	assert.NoError(err)
+	assert.False(ok)
+
+	pending, err = mock.LoadPendingJobs(ctx, time.Now(), 10)
+	assert.NoError(err)
+	assert.Empty(pending)
+
+	pending, err = mock.LoadPendingJobs(ctx, time.Now().Add(2*time.Minute), 10)
+	if !assert.NoError(err) || !assert.Len(pending, 1) {
+		return
+	}
+
+	ok, err = mock.ClaimJob(ctx, time.Minute, pending[0])
+	if !assert.NoError(err) || !assert.True(ok) {
+		return
+	}
+	reclaimed := pending[0]
+	assert.Equal(2, reclaimed.Attempts)
+
+	claimed.Processed = 1
+	err = mock.UpdateJob(ctx, claimed)
+	assert.Equal(store.ErrVersionMismatch, err)
+
+	reclaimed.Finish(api.JobFinished)
+	err = mock.UpdateJob(ctx, reclaimed)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pending, err = mock.LoadPendingJobs(ctx, time.Now().Add(2*time.Minute), 10)
+	assert.NoError(err)
+	assert.Empty(pending)
+}
@@ -20,10 +20,14 @@ type pass struct {
 }
 
 type reg struct {
-	serial string
-	device string
-	push   string
-	id     string
+	serial  string
+	device  string
+	id      string
+	updated time.Time
+}
+
+func regIndex(deviceID, serialNumber string) string {
+	return deviceID + "^" + serialNumber
 }
 
 // New returns a new instance of memory mock.
@@ -31,6 +35,7 @@ func New() *Memory {
 	mock := &Memory{
 		passes:           make(map[string]*pass),
 		regs:             make(map[string]*reg),
+		devices:          make(map[string]string),
 		users:            make(map[int64]*api.User),
 		userProjects:     make(map[int64]int64),
 		projects:         make(map[int64]*api.Project),
@@ -38,22 +43,39 @@ func New() *Memory {
 		uploads:          make(map[int64]*api.Upload),
 		passCards:        make(map[int64]*api.PassCardInfo),
 		projectPassCards: make(map[int64]int64),
+		pushes:           make(map[int64]*api.Push),
+		jobs:             make(map[int64]*api.Job),
+		jobResults:       make(map[int64]*api.JobResult),
+		passCardVersions: make(map[int64]*api.PassCardVersion),
+		scheduledUpdates: make(map[int64]*api.ScheduledUpdate),
 	}
 	return mock
 }
 
 // Memory is mock implementor.
 type Memory struct {
-	mu               sync.Mutex
-	passes           map[string]*pass
-	regs             map[string]*reg
-	users            map[int64]*api.User
-	userProjects     map[int64]int64
-	projects         map[int64]*api.Project
-	userUploads      map[int64]int64
-	uploads          map[int64]*api.Upload
-	passCards        map[int64]*api.PassCardInfo
-	projectPassCards map[int64]int64
+	mu                 sync.Mutex
+	passes             map[string]*pass
+	regs               map[string]*reg
+	devices            map[string]string
+	users              map[int64]*api.User
+	userProjects       map[int64]int64
+	projects           map[int64]*api.Project
+	userUploads        map[int64]int64
+	uploads            map[int64]*api.Upload
+	passCards          map[int64]*api.PassCardInfo
+	projectPassCards   map[int64]int64
+	passCardSeq        int64
+	pushes             map[int64]*api.Push
+	pushSeq            int64
+	jobs               map[int64]*api.Job
+	jobSeq             int64
+	jobResults         map[int64]*api.JobResult
+	jobResultSeq       int64
+	passCardVersions   map[int64]*api.PassCardVersion
+	passCardVerSeq     int64
+	scheduledUpdates   map[int64]*api.ScheduledUpdate
+	scheduledUpdateSeq int64
 }
 
 // InsertPass ...
@@ -87,22 +109,19 @@ func (m *Memory) UpdatePass(ctx context.Context, serialNumber string) error {
 func (m *Memory) FindPass(ctx context.Context, serialNumber, authToken, passTypeIdentifier string) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	_, ok := m.passes[serialNumber]
+	pass, ok := m.passes[serialNumber]
 	if !ok {
-		return false, fmt.Errorf("pass %q not found", serialNumber)
+		return false, nil
 	}
-	return true, nil
+	return pass.token == authToken && pass.id == passTypeIdentifier, nil
 }
 
 // FindRegistration ...
 func (m *Memory) FindRegistration(ctx context.Context, deviceID, serialNumber string) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	_, ok := m.regs[deviceID]
-	if !ok {
-		return false, nil
-	}
-	return true, nil
+	_, ok := m.regs[regIndex(deviceID, serialNumber)]
+	return ok, nil
 }
 
 // FindRegistrationBySerialNumber ...
@@ -117,27 +136,43 @@ func (m *Memory) FindRegistrationBySerialNumber(ctx context.Context, serialNumbe
 	return false, nil
 }
 
-// FindPushToken ...
-func (m *Memory) FindPushToken(ctx context.Context, serialNumber string) (string, error) {
+// FindPushTokens ...
+func (m *Memory) FindPushTokens(ctx context.Context, serialNumber string) ([]string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	tokens := []string{}
 	for _, reg := range m.regs {
 		if reg.serial == serialNumber {
-			return reg.push, nil
+			tokens = append(tokens, m.devices[reg.device])
 		}
 	}
-	return "", store.ErrNotFound
+	return tokens, nil
+}
+
+// FindDeviceIDs ...
+func (m *Memory) FindDeviceIDs(ctx context.Context, pushToken string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := []string{}
+	for id, token := range m.devices {
+		if token == pushToken {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
 }
 
 // FindSerialNumbers ...
 func (m *Memory) FindSerialNumbers(ctx context.Context, deviceID, passTypeIdentifier, tag string) ([]string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	reg, ok := m.regs[deviceID]
-	if !ok {
-		return nil, fmt.Errorf("registration %q not found", deviceID)
+	serials := []string{}
+	for _, reg := range m.regs {
+		if reg.device == deviceID && reg.id == passTypeIdentifier {
+			serials = append(serials, reg.serial)
+		}
 	}
-	return []string{reg.serial}, nil
+	return serials, nil
 }
 
 // LatestPass ...
@@ -158,10 +193,11 @@ func (m *Memory) InsertRegistration(ctx context.Context, deviceID, pushToken, se
 	reg := &reg{
 		serialNumber,
 		deviceID,
-		pushToken,
 		passTypeIdentifier,
+		time.Now(),
 	}
-	m.regs[deviceID] = reg
+	m.devices[deviceID] = pushToken
+	m.regs[regIndex(deviceID, serialNumber)] = reg
 	return nil
 }
 
@@ -169,10 +205,49 @@ func (m *Memory) InsertRegistration(ctx context.Context, deviceID, pushToken, se
 func (m *Memory) DeleteRegistration(ctx context.Context, deviceID, serialNumber, passTypeIdentifier string) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.regs, deviceID)
+	index := regIndex(deviceID, serialNumber)
+	reg, ok := m.regs[index]
+	if !ok || reg.id != passTypeIdentifier {
+		return false, nil
+	}
+	delete(m.regs, index)
+	return true, nil
+}
+
+// DeleteStaleRegistration ...
+func (m *Memory) DeleteStaleRegistration(ctx context.Context, deviceID, serialNumber, passTypeIdentifier string, before time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	index := regIndex(deviceID, serialNumber)
+	reg, ok := m.regs[index]
+	if !ok || reg.id != passTypeIdentifier {
+		return false, nil
+	}
+	if reg.updated.After(before) {
+		return false, nil
+	}
+	delete(m.regs, index)
 	return true, nil
 }
 
+// DeletePass ...
+func (m *Memory) DeletePass(ctx context.Context, serialNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for index, reg := range m.regs {
+		if reg.serial == serialNumber {
+			delete(m.regs, index)
+		}
+	}
+	for id, push := range m.pushes {
+		if push.SerialNumber == serialNumber {
+			delete(m.pushes, id)
+		}
+	}
+	delete(m.passes, serialNumber)
+	return nil
+}
+
 // InsertLog ...
 func (m *Memory) InsertLog(ctx context.Context, remoteAddr, requestID, message string) error {
 	m.mu.Lock()
@@ -32,6 +32,30 @@ func fakeString() string {
 	return uuid.NewV4().String()
 }
 
+func fakePassCard(project *api.Project) *api.PassCardInfo {
+	passcard := api.NewPassCardInfo(&api.PassCard{
+		Description:      project.Description,
+		FormatVersion:    1,
+		OrganizationName: project.OrganizationName,
+		PassTypeID:       "pass.com.example." + string(project.PassType),
+		SerialNumber:     fakeString(),
+		TeamID:           fakeString(),
+		Coupon: &api.PassStructure{
+			PrimaryFields: []*api.Field{
+				&api.Field{
+					Key:   "offer",
+					Label: "offer_label",
+					Value: "20% off",
+				},
+			},
+		},
+		AuthenticationToken: secure.Token(),
+		WebServiceURL:       "https://okpock.com",
+	})
+	passcard.ID = fakeID()
+	return passcard
+}
+
 func TestInsertPass(t *testing.T) {
 	var (
 		ctx                = context.Background()
@@ -62,18 +86,62 @@ func TestUpdatePass(t *testing.T) {
 
 func TestFindPass(t *testing.T) {
 	var (
-		ctx                = context.Background()
-		mock               = memory.New()
 		serialNumber       = uuid.NewV4().String()
 		authToken          = uuid.NewV4().String()
 		passTypeIdentifier = "test.passkit"
 	)
-	assert := assert.New(t)
-	err := mock.InsertPass(ctx, serialNumber, authToken, passTypeIdentifier)
-	assert.NoError(err)
-	res, err := mock.FindPass(ctx, serialNumber, authToken, passTypeIdentifier)
-	assert.NoError(err)
-	assert.True(res)
+
+	testCases := []struct {
+		Name               string
+		SerialNumber       string
+		AuthToken          string
+		PassTypeIdentifier string
+		Expected           bool
+	}{
+		{
+			Name:               "Valid",
+			SerialNumber:       serialNumber,
+			AuthToken:          authToken,
+			PassTypeIdentifier: passTypeIdentifier,
+			Expected:           true,
+		},
+		{
+			Name:               "WrongToken",
+			SerialNumber:       serialNumber,
+			AuthToken:          uuid.NewV4().String(),
+			PassTypeIdentifier: passTypeIdentifier,
+			Expected:           false,
+		},
+		{
+			Name:               "WrongPassType",
+			SerialNumber:       serialNumber,
+			AuthToken:          authToken,
+			PassTypeIdentifier: "test.another",
+			Expected:           false,
+		},
+		{
+			Name:               "UnknownSerialNumber",
+			SerialNumber:       uuid.NewV4().String(),
+			AuthToken:          authToken,
+			PassTypeIdentifier: passTypeIdentifier,
+			Expected:           false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var (
+				ctx    = context.Background()
+				mock   = memory.New()
+				assert = assert.New(t)
+			)
+			err := mock.InsertPass(ctx, serialNumber, authToken, passTypeIdentifier)
+			assert.NoError(err)
+			res, err := mock.FindPass(ctx, tc.SerialNumber, tc.AuthToken, tc.PassTypeIdentifier)
+			assert.NoError(err)
+			assert.Equal(tc.Expected, res)
+		})
+	}
 }
 
 func TestFindRegistration(t *testing.T) {
@@ -108,9 +176,90 @@ func TestFindRegistrationBySerialNumber(t *testing.T) {
 	res, err := mock.FindRegistrationBySerialNumber(ctx, serialNumber)
 	assert.NoError(err)
 	assert.True(res)
-	token, err := mock.FindPushToken(ctx, serialNumber)
+	tokens, err := mock.FindPushTokens(ctx, serialNumber)
+	assert.NoError(err)
+	assert.Equal([]string{pushToken}, tokens)
+}
+
+func TestFindPushTokensForManyDevices(t *testing.T) {
+	var (
+		ctx                = context.Background()
+		mock               = memory.New()
+		serialNumber       = uuid.NewV4().String()
+		passTypeIdentifier = "test.passkit"
+		devices            = map[string]string{
+			uuid.NewV4().String(): uuid.NewV4().String(),
+			uuid.NewV4().String(): uuid.NewV4().String(),
+		}
+	)
+	assert := assert.New(t)
+	expected := []string{}
+	for deviceID, pushToken := range devices {
+		err := mock.InsertRegistration(ctx, deviceID, pushToken, serialNumber, passTypeIdentifier)
+		assert.NoError(err)
+		expected = append(expected, pushToken)
+	}
+	tokens, err := mock.FindPushTokens(ctx, serialNumber)
+	assert.NoError(err)
+	assert.ElementsMatch(expected, tokens)
+	for deviceID := range devices {
+		res, err := mock.FindRegistration(ctx, deviceID, serialNumber)
+		assert.NoError(err)
+		assert.True(res)
+		res, err = mock.DeleteRegistration(ctx, deviceID, serialNumber, passTypeIdentifier)
+		assert.NoError(err)
+		assert.True(res)
+	}
+	tokens, err = mock.FindPushTokens(ctx, serialNumber)
 	assert.NoError(err)
-	assert.Equal(pushToken, token)
+	assert.Empty(tokens)
+}
+
+func TestDeleteStaleRegistration(t *testing.T) {
+	var (
+		ctx                = context.Background()
+		mock               = memory.New()
+		deviceID           = uuid.NewV4().String()
+		pushToken          = uuid.NewV4().String()
+		serialNumber       = uuid.NewV4().String()
+		otherSerialNumber  = uuid.NewV4().String()
+		passTypeIdentifier = "test.passkit"
+	)
+	assert := assert.New(t)
+	err := mock.InsertRegistration(ctx, deviceID, pushToken, serialNumber, passTypeIdentifier)
+	assert.NoError(err)
+	before := time.Now()
+	err = mock.InsertRegistration(ctx, deviceID, pushToken, otherSerialNumber, passTypeIdentifier)
+	assert.NoError(err)
+	res, err := mock.DeleteStaleRegistration(ctx, deviceID, otherSerialNumber, passTypeIdentifier, before)
+	assert.NoError(err)
+	assert.False(res)
+	res, err = mock.DeleteStaleRegistration(ctx, deviceID, serialNumber, passTypeIdentifier, before)
+	assert.NoError(err)
+	assert.True(res)
+	res, err = mock.FindRegistration(ctx, deviceID, otherSerialNumber)
+	assert.NoError(err)
+	assert.True(res)
+}
+
+func TestFindDeviceIDs(t *testing.T) {
+	var (
+		ctx                = context.Background()
+		mock               = memory.New()
+		deviceID           = uuid.NewV4().String()
+		serialNumber       = uuid.NewV4().String()
+		pushToken          = uuid.NewV4().String()
+		passTypeIdentifier = "test.passkit"
+	)
+	assert := assert.New(t)
+	err := mock.InsertRegistration(ctx, deviceID, pushToken, serialNumber, passTypeIdentifier)
+	assert.NoError(err)
+	ids, err := mock.FindDeviceIDs(ctx, pushToken)
+	assert.NoError(err)
+	assert.Equal([]string{deviceID}, ids)
+	ids, err = mock.FindDeviceIDs(ctx, uuid.NewV4().String())
+	assert.NoError(err)
+	assert.Empty(ids)
 }
 
 func TestFindSerialNumbers(t *testing.T) {
@@ -177,6 +326,36 @@ func TestDeleteRegistration(t *testing.T) {
 	assert.True(res)
 }
 
+func TestDeletePass(t *testing.T) {
+	var (
+		ctx                = context.Background()
+		mock               = memory.New()
+		deviceID           = uuid.NewV4().String()
+		serialNumber       = uuid.NewV4().String()
+		authToken          = uuid.NewV4().String()
+		pushToken          = uuid.NewV4().String()
+		passTypeIdentifier = "test.passkit"
+	)
+	assert := assert.New(t)
+	err := mock.InsertPass(ctx, serialNumber, authToken, passTypeIdentifier)
+	assert.NoError(err)
+	err = mock.InsertRegistration(ctx, deviceID, pushToken, serialNumber, passTypeIdentifier)
+	assert.NoError(err)
+	err = mock.InsertPush(ctx, api.NewPush(serialNumber, passTypeIdentifier, pushToken))
+	assert.NoError(err)
+	err = mock.DeletePass(ctx, serialNumber)
+	assert.NoError(err)
+	ok, err := mock.FindPass(ctx, serialNumber, authToken, passTypeIdentifier)
+	assert.NoError(err)
+	assert.False(ok)
+	ok, err = mock.FindRegistration(ctx, deviceID, serialNumber)
+	assert.NoError(err)
+	assert.False(ok)
+	pushes, err := mock.LoadPushes(ctx, serialNumber)
+	assert.NoError(err)
+	assert.Len(pushes, 0)
+}
+
 func TestInsertLog(t *testing.T) {
 	var (
 		ctx        = context.Background()
@@ -2,6 +2,8 @@ package memory
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/danikarik/okpock/pkg/api"
@@ -13,8 +15,19 @@ func (m *Memory) SaveNewPassCard(ctx context.Context, project *api.Project, pass
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.passCards[passcard.ID] = passcard
+	if passcard.ID == 0 {
+		m.passCardSeq++
+		passcard.ID = m.passCardSeq
+	}
+	if passcard.Version == 0 {
+		passcard.Version = 1
+	}
+	passcard.ExpiresAt = passcard.Data.Expiration()
+
+	clone := *passcard
+	m.passCards[passcard.ID] = &clone
 	m.projectPassCards[passcard.ID] = project.ID
+	m.savePassCardVersion(ctx, passcard)
 
 	return nil
 }
@@ -29,7 +42,8 @@ func (m *Memory) LoadPassCard(ctx context.Context, project *api.Project, id int6
 		return nil, store.ErrNotFound
 	}
 
-	return p, nil
+	clone := *p
+	return &clone, nil
 }
 
 // LoadPassCardBySerialNumber ...
@@ -40,7 +54,8 @@ func (m *Memory) LoadPassCardBySerialNumber(ctx context.Context, project *api.Pr
 	for _, p := range m.passCards {
 		if p.Data != nil {
 			if p.Data.SerialNumber == serialNumber {
-				return p, nil
+				clone := *p
+				return &clone, nil
 			}
 		}
 	}
@@ -55,12 +70,14 @@ func (m *Memory) LoadPassCards(ctx context.Context, project *api.Project, opts *
 
 	data := []*api.PassCardInfo{}
 	for passCardID, projectID := range m.projectPassCards {
-		if projectID == project.ID {
-			data = append(data, m.passCards[passCardID])
+		p := m.passCards[passCardID]
+		if projectID == project.ID && !p.IsArchived() {
+			clone := *p
+			data = append(data, &clone)
 		}
 	}
 
-	return &api.PassCardInfoList{Opts: opts, Data: data}, nil
+	return pagePassCards(data, opts), nil
 }
 
 // LoadPassCardsByBarcodeMessage ...
@@ -69,17 +86,70 @@ func (m *Memory) LoadPassCardsByBarcodeMessage(ctx context.Context, project *api
 	defer m.mu.Unlock()
 
 	data := []*api.PassCardInfo{}
-	for _, p := range m.passCards {
-		if p.Data != nil {
+	for passCardID, projectID := range m.projectPassCards {
+		if projectID != project.ID {
+			continue
+		}
+		p := m.passCards[passCardID]
+		if p.Data != nil && !p.IsArchived() {
 			for _, barcode := range p.Data.Barcodes {
 				if barcode.Message == message {
-					data = append(data, p)
+					clone := *p
+					data = append(data, &clone)
+					break
 				}
 			}
 		}
 	}
 
-	return &api.PassCardInfoList{Opts: opts, Data: data}, nil
+	return pagePassCards(data, opts), nil
+}
+
+// LoadPassCardsByGroupingIdentifier ...
+func (m *Memory) LoadPassCardsByGroupingIdentifier(ctx context.Context, project *api.Project, groupingID string, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := []*api.PassCardInfo{}
+	for passCardID, projectID := range m.projectPassCards {
+		if projectID != project.ID {
+			continue
+		}
+		p := m.passCards[passCardID]
+		if p.Data != nil && !p.IsArchived() && p.Data.GroupingIdentifier == groupingID {
+			clone := *p
+			data = append(data, &clone)
+		}
+	}
+
+	return pagePassCards(data, opts), nil
+}
+
+// LoadPassCardsByUserInfo ...
+func (m *Memory) LoadPassCardsByUserInfo(ctx context.Context, project *api.Project, key, value string, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if key == "" {
+		return nil, store.ErrEmptyQueryParam
+	}
+
+	data := []*api.PassCardInfo{}
+	for passCardID, projectID := range m.projectPassCards {
+		if projectID != project.ID {
+			continue
+		}
+		p := m.passCards[passCardID]
+		if p.Data == nil || p.IsArchived() {
+			continue
+		}
+		if v, ok := p.Data.UserInfo[key]; ok && fmt.Sprint(v) == value {
+			clone := *p
+			data = append(data, &clone)
+		}
+	}
+
+	return pagePassCards(data, opts), nil
 }
 
 // UpdatePassCard ...
@@ -87,10 +157,151 @@ func (m *Memory) UpdatePassCard(ctx context.Context, data *api.PassCard, passcar
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	stored, ok := m.passCards[passcard.ID]
+	if !ok || stored.IsArchived() {
+		return store.ErrNotFound
+	}
+	if stored.Version != passcard.Version {
+		return store.ErrVersionMismatch
+	}
+
 	data.CopyFrom(passcard.Data)
 	passcard.Data = data
+	passcard.Version++
+	passcard.UpdatedAt = time.Now()
+	passcard.ExpiresAt = data.Expiration()
+
+	clone := *passcard
+	m.passCards[passcard.ID] = &clone
+	m.savePassCardVersion(ctx, passcard)
+
+	return nil
+}
+
+// SetPassCardLocalizations ...
+func (m *Memory) SetPassCardLocalizations(ctx context.Context, localizations api.Localizations, passcard *api.PassCardInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := localizations.IsValid()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.passCards[passcard.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	passcard.Localizations = localizations
 	passcard.UpdatedAt = time.Now()
-	m.passCards[passcard.ID] = passcard
+	stored.Localizations = passcard.Localizations
+	stored.UpdatedAt = passcard.UpdatedAt
+
+	return nil
+}
+
+// ArchivePassCard ...
+func (m *Memory) ArchivePassCard(ctx context.Context, passcard *api.PassCardInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.passCards[passcard.ID]
+	if !ok || stored.IsArchived() {
+		return store.ErrNotFound
+	}
+	if stored.Version != passcard.Version {
+		return store.ErrVersionMismatch
+	}
+
+	archivedAt := time.Now()
+	passcard.ArchivedAt = &archivedAt
+	stored.ArchivedAt = &archivedAt
 
 	return nil
 }
+
+// LoadArchivedPassCards ...
+func (m *Memory) LoadArchivedPassCards(ctx context.Context, before time.Time, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := []*api.PassCardInfo{}
+	for _, p := range m.passCards {
+		if p.IsArchived() && !p.ArchivedAt.After(before) {
+			clone := *p
+			data = append(data, &clone)
+		}
+	}
+
+	return pagePassCards(data, opts), nil
+}
+
+// LoadExpiredPassCards ...
+func (m *Memory) LoadExpiredPassCards(ctx context.Context, before time.Time, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := []*api.PassCardInfo{}
+	for _, p := range m.passCards {
+		if p.IsExpired(m.projects[m.projectPassCards[p.ID]], before) {
+			clone := *p
+			data = append(data, &clone)
+		}
+	}
+
+	return pagePassCards(data, opts), nil
+}
+
+// LoadPassCardProject ...
+func (m *Memory) LoadPassCardProject(ctx context.Context, passcard *api.PassCardInfo) (*api.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	project, ok := m.projects[m.projectPassCards[passcard.ID]]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return project, nil
+}
+
+// DeletePassCard ...
+func (m *Memory) DeletePassCard(ctx context.Context, passcard *api.PassCardInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.passCards, passcard.ID)
+	delete(m.projectPassCards, passcard.ID)
+	for id, version := range m.passCardVersions {
+		if version.PassCardID == passcard.ID {
+			delete(m.passCardVersions, id)
+		}
+	}
+
+	return nil
+}
+
+// pagePassCards orders cards from newest to oldest and cuts page
+// starting at cursor the same way sequel store does.
+func pagePassCards(data []*api.PassCardInfo, opts *api.PagingOptions) *api.PassCardInfoList {
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	sort.Slice(data, func(i, j int) bool { return data[i].ID > data[j].ID })
+
+	passcards := &api.PassCardInfoList{Opts: opts, Data: []*api.PassCardInfo{}}
+	for _, p := range data {
+		if opts.Cursor > 0 && p.ID > opts.Cursor {
+			continue
+		}
+		if uint64(len(passcards.Data)) == opts.Limit {
+			opts.Next = p.ID
+			break
+		}
+		passcards.Data = append(passcards.Data, p)
+	}
+
+	return passcards
+}
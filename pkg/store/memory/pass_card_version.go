@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// savePassCardVersion must be called with lock held.
+func (m *Memory) savePassCardVersion(ctx context.Context, passcard *api.PassCardInfo) {
+	m.passCardVerSeq++
+	version := api.NewPassCardVersion(passcard, api.AuthorFromContext(ctx))
+	version.ID = m.passCardVerSeq
+	m.passCardVersions[version.ID] = version
+}
+
+// LoadPassCardVersion ...
+func (m *Memory) LoadPassCardVersion(ctx context.Context, passcard *api.PassCardInfo, version int64) (*api.PassCardVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.passCardVersions {
+		if v.PassCardID == passcard.ID && v.Version == version {
+			clone := *v
+			return &clone, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// LoadPassCardVersions ...
+func (m *Memory) LoadPassCardVersions(ctx context.Context, passcard *api.PassCardInfo, opts *api.PagingOptions) (*api.PassCardVersions, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := []*api.PassCardVersion{}
+	for _, v := range m.passCardVersions {
+		if v.PassCardID == passcard.ID {
+			clone := *v
+			data = append(data, &clone)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].Version > data[j].Version })
+	return &api.PassCardVersions{Opts: opts, Data: data}, nil
+}
@@ -178,3 +178,71 @@ func (m *Memory) SetStripImage(ctx context.Context, size api.ImageSize, key stri
 
 	return nil
 }
+
+// SetThumbnailImage ...
+func (m *Memory) SetThumbnailImage(ctx context.Context, size api.ImageSize, key string, project *api.Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch size {
+	case api.ImageSize3x:
+		project.ThumbnailImage3x = key
+	case api.ImageSize2x:
+		project.ThumbnailImage2x = key
+	default:
+		project.ThumbnailImage = key
+	}
+
+	project.UpdatedAt = time.Now()
+	m.projects[project.ID] = project
+
+	return nil
+}
+
+// SetProjectLocalizations ...
+func (m *Memory) SetProjectLocalizations(ctx context.Context, localizations api.Localizations, project *api.Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := localizations.IsValid()
+	if err != nil {
+		return err
+	}
+
+	project.Localizations = localizations
+	project.UpdatedAt = time.Now()
+	m.projects[project.ID] = project
+
+	return nil
+}
+
+// SetProjectTemplate ...
+func (m *Memory) SetProjectTemplate(ctx context.Context, template *api.Template, project *api.Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if template != nil {
+		err := template.IsValid()
+		if err != nil {
+			return err
+		}
+	}
+
+	project.Template = template
+	project.UpdatedAt = time.Now()
+	m.projects[project.ID] = project
+
+	return nil
+}
+
+// SetProjectExpiration ...
+func (m *Memory) SetProjectExpiration(ctx context.Context, expireAfterDays int, project *api.Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	project.ExpireAfterDays = expireAfterDays
+	project.UpdatedAt = time.Now()
+	m.projects[project.ID] = project
+
+	return nil
+}
@@ -341,6 +341,11 @@ func TestSetImage(t *testing.T) {
 				return
 			}
 
+			err = db.SetThumbnailImage(ctx, tc.Size, tc.NewKey, p)
+			if !assert.NoError(err) {
+				return
+			}
+
 			loaded, err := db.LoadProject(ctx, u, p.ID)
 			if !assert.NoError(err) {
 				return
@@ -355,19 +360,186 @@ func TestSetImage(t *testing.T) {
 				assert.Equal(tc.NewKey, loaded.IconImage3x)
 				assert.Equal(tc.NewKey, loaded.LogoImage3x)
 				assert.Equal(tc.NewKey, loaded.StripImage3x)
+				assert.Equal(tc.NewKey, loaded.ThumbnailImage3x)
 			case api.ImageSize2x:
 				assert.Equal(tc.NewKey, loaded.BackgroundImage2x)
 				assert.Equal(tc.NewKey, loaded.FooterImage2x)
 				assert.Equal(tc.NewKey, loaded.IconImage2x)
 				assert.Equal(tc.NewKey, loaded.LogoImage2x)
 				assert.Equal(tc.NewKey, loaded.StripImage2x)
+				assert.Equal(tc.NewKey, loaded.ThumbnailImage2x)
 			default:
 				assert.Equal(tc.NewKey, loaded.BackgroundImage)
 				assert.Equal(tc.NewKey, loaded.FooterImage)
 				assert.Equal(tc.NewKey, loaded.IconImage)
 				assert.Equal(tc.NewKey, loaded.LogoImage)
 				assert.Equal(tc.NewKey, loaded.StripImage)
+				assert.Equal(tc.NewKey, loaded.ThumbnailImage)
+			}
+		})
+	}
+}
+
+func TestSetLocalizations(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Localizations api.Localizations
+		Valid         bool
+	}{
+		{
+			Name: "Valid",
+			Localizations: api.Localizations{
+				"en": {
+					Strings: map[string]string{"offer_label": "Offer"},
+					Images:  map[string]string{"logo.png": fakeString()},
+				},
+				"pt-BR": {
+					Strings: map[string]string{"offer_label": "Oferta"},
+				},
+			},
+			Valid: true,
+		},
+		{
+			Name:  "Empty",
+			Valid: true,
+		},
+		{
+			Name: "InvalidLanguage",
+			Localizations: api.Localizations{
+				"en.lproj/..": {Strings: map[string]string{"offer_label": "Offer"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			db := memory.New()
+			var err error
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+			err = db.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = db.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			passcard := fakePassCard(project)
+			err = db.SaveNewPassCard(ctx, project, passcard)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = db.SetProjectLocalizations(ctx, tc.Localizations, project)
+			if !tc.Valid {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = db.SetPassCardLocalizations(ctx, tc.Localizations, passcard)
+			if !assert.NoError(err) {
+				return
+			}
+
+			loadedProject, err := db.LoadProject(ctx, user, project.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Localizations, loadedProject.Localizations)
+
+			loadedPassCard, err := db.LoadPassCard(ctx, project, passcard.ID)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.Localizations, loadedPassCard.Localizations)
+		})
+	}
+}
+
+func TestSetTemplate(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Template *api.Template
+		Valid    bool
+	}{
+		{
+			Name: "Valid",
+			Template: &api.Template{
+				PrimaryFields: []*api.TemplateField{
+					&api.TemplateField{
+						Field:    api.Field{Key: "balance", Label: "BALANCE", NumberStyle: api.PKNumberStyleDecimal},
+						Required: true,
+					},
+				},
+				BackFields: []*api.TemplateField{
+					&api.TemplateField{
+						Field: api.Field{Key: "terms", Label: "Terms", Value: "No cash value"},
+					},
+				},
+			},
+			Valid: true,
+		},
+		{
+			Name:  "Empty",
+			Valid: true,
+		},
+		{
+			Name: "DuplicatedKey",
+			Template: &api.Template{
+				HeaderFields: []*api.TemplateField{
+					&api.TemplateField{Field: api.Field{Key: "balance"}},
+				},
+				PrimaryFields: []*api.TemplateField{
+					&api.TemplateField{Field: api.Field{Key: "balance"}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			db := memory.New()
+			var err error
+
+			user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+			err = db.SaveNewUser(ctx, user)
+			if !assert.NoError(err) {
+				return
+			}
+
+			project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+			err = db.SaveNewProject(ctx, user, project)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = db.SetProjectTemplate(ctx, tc.Template, project)
+			if !tc.Valid {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			loaded, err := db.LoadProject(ctx, user, project.ID)
+			if !assert.NoError(err) {
+				return
 			}
+			assert.Equal(tc.Template, loaded.Template)
 		})
 	}
 }
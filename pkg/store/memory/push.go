@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// InsertPush ...
+func (m *Memory) InsertPush(ctx context.Context, push *api.Push) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if push == nil {
+		return store.ErrNilStruct
+	}
+	m.pushSeq++
+	push.ID = m.pushSeq
+	clone := *push
+	m.pushes[push.ID] = &clone
+	return nil
+}
+
+// UpdatePassWithPushes ...
+func (m *Memory) UpdatePassWithPushes(ctx context.Context, serialNumber, passTypeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pass, ok := m.passes[serialNumber]
+	if !ok {
+		return store.ErrZeroRowsAffected
+	}
+	pass.updated = time.Now()
+	for _, reg := range m.regs {
+		if reg.serial == serialNumber {
+			m.pushSeq++
+			push := api.NewPush(serialNumber, passTypeID, m.devices[reg.device])
+			push.ID = m.pushSeq
+			m.pushes[push.ID] = push
+		}
+	}
+	return nil
+}
+
+// LoadPushes ...
+func (m *Memory) LoadPushes(ctx context.Context, serialNumber string) ([]*api.Push, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pushes := []*api.Push{}
+	for _, push := range m.pushes {
+		if push.SerialNumber == serialNumber {
+			clone := *push
+			pushes = append(pushes, &clone)
+		}
+	}
+	sort.Slice(pushes, func(i, j int) bool { return pushes[i].ID > pushes[j].ID })
+	return pushes, nil
+}
+
+// LoadPendingPushes ...
+func (m *Memory) LoadPendingPushes(ctx context.Context, limit uint64) ([]*api.Push, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	pushes := []*api.Push{}
+	for _, push := range m.pushes {
+		if push.Status != api.PushPending && push.Status != api.PushProcessing {
+			continue
+		}
+		if push.NextAttemptAt.After(now) {
+			continue
+		}
+		clone := *push
+		pushes = append(pushes, &clone)
+	}
+	sort.Slice(pushes, func(i, j int) bool { return pushes[i].ID < pushes[j].ID })
+	if uint64(len(pushes)) > limit {
+		pushes = pushes[:limit]
+	}
+	return pushes, nil
+}
+
+// ClaimPush ...
+func (m *Memory) ClaimPush(ctx context.Context, lease time.Duration, push *api.Push) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if push == nil {
+		return false, store.ErrNilStruct
+	}
+	stored, ok := m.pushes[push.ID]
+	if !ok || stored.Attempts != push.Attempts {
+		return false, nil
+	}
+	if stored.Status != api.PushPending && stored.Status != api.PushProcessing {
+		return false, nil
+	}
+	now := time.Now()
+	stored.Status = api.PushProcessing
+	stored.Attempts++
+	stored.NextAttemptAt = now.Add(lease)
+	stored.UpdatedAt = now
+	*push = *stored
+	return true, nil
+}
+
+// UpdatePush ...
+func (m *Memory) UpdatePush(ctx context.Context, push *api.Push) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if push == nil {
+		return false, store.ErrNilStruct
+	}
+	stored, ok := m.pushes[push.ID]
+	if !ok || stored.Attempts != push.Attempts || stored.Status != api.PushProcessing {
+		return false, nil
+	}
+	push.UpdatedAt = time.Now()
+	stored.Status = push.Status
+	stored.LastError = push.LastError
+	stored.NextAttemptAt = push.NextAttemptAt
+	stored.UpdatedAt = push.UpdatedAt
+	return true, nil
+}
@@ -0,0 +1,131 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushOutbox(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		mock         = memory.New()
+		serialNumber = fakeString()
+		passTypeID   = "test.passkit"
+	)
+	assert := assert.New(t)
+
+	first := api.NewPush(serialNumber, passTypeID, fakeString())
+	err := mock.InsertPush(ctx, first)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(first.ID > 0)
+
+	second := api.NewPush(serialNumber, passTypeID, fakeString())
+	second.NextAttemptAt = time.Now().Add(time.Hour)
+	err = mock.InsertPush(ctx, second)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pending, err := mock.LoadPendingPushes(ctx, 10)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(pending, 1) {
+		return
+	}
+	assert.Equal(first.ID, pending[0].ID)
+
+	stale := *pending[0]
+	ok, err := mock.ClaimPush(ctx, time.Minute, pending[0])
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(api.PushProcessing, pending[0].Status)
+	assert.Equal(1, pending[0].Attempts)
+
+	ok, err = mock.ClaimPush(ctx, time.Minute, &stale)
+	assert.NoError(err)
+	assert.False(ok)
+
+	expired := *pending[0]
+	ok, err = mock.ClaimPush(ctx, time.Minute, pending[0])
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(2, pending[0].Attempts)
+
+	expired.Status = api.PushDead
+	ok, err = mock.UpdatePush(ctx, &expired)
+	assert.NoError(err)
+	assert.False(ok)
+
+	pending[0].Status = api.PushDelivered
+	ok, err = mock.UpdatePush(ctx, pending[0])
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(ok)
+
+	pushes, err := mock.LoadPushes(ctx, serialNumber)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(pushes, 2) {
+		return
+	}
+	assert.Equal(api.PushPending, pushes[0].Status)
+	assert.Equal(api.PushDelivered, pushes[1].Status)
+
+	pushes, err = mock.LoadPushes(ctx, fakeString())
+	assert.NoError(err)
+	assert.Empty(pushes)
+}
+
+func TestUpdatePassWithPushes(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		mock         = memory.New()
+		serialNumber = fakeString()
+		passTypeID   = "test.passkit"
+	)
+	assert := assert.New(t)
+
+	err := mock.UpdatePassWithPushes(ctx, serialNumber, passTypeID)
+	assert.Error(err)
+
+	err = mock.InsertPass(ctx, serialNumber, fakeString(), passTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	tokens := []string{fakeString(), fakeString()}
+	for _, token := range tokens {
+		err = mock.InsertRegistration(ctx, fakeString(), token, serialNumber, passTypeID)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	err = mock.UpdatePassWithPushes(ctx, serialNumber, passTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pushes, err := mock.LoadPushes(ctx, serialNumber)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(pushes, len(tokens)) {
+		return
+	}
+	for _, push := range pushes {
+		assert.Contains(tokens, push.PushToken)
+		assert.Equal(passTypeID, push.PassTypeID)
+		assert.Equal(api.PushPending, push.Status)
+	}
+}
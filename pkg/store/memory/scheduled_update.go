@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+// SaveNewScheduledUpdate ...
+func (m *Memory) SaveNewScheduledUpdate(ctx context.Context, project *api.Project, update *api.ScheduledUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if update == nil {
+		return store.ErrNilStruct
+	}
+	m.scheduledUpdateSeq++
+	update.ID = m.scheduledUpdateSeq
+	update.ProjectID = project.ID
+	clone := *update
+	m.scheduledUpdates[update.ID] = &clone
+	return nil
+}
+
+// LoadScheduledUpdate ...
+func (m *Memory) LoadScheduledUpdate(ctx context.Context, project *api.Project, id int64) (*api.ScheduledUpdate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	update, ok := m.scheduledUpdates[id]
+	if !ok || update.ProjectID != project.ID {
+		return nil, store.ErrNotFound
+	}
+	clone := *update
+	return &clone, nil
+}
+
+// LoadScheduledUpdates ...
+func (m *Memory) LoadScheduledUpdates(ctx context.Context, project *api.Project, opts *api.PagingOptions) (*api.ScheduledUpdates, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := []*api.ScheduledUpdate{}
+	for _, update := range m.scheduledUpdates {
+		if update.ProjectID == project.ID {
+			clone := *update
+			data = append(data, &clone)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID > data[j].ID })
+	return &api.ScheduledUpdates{Opts: opts, Data: data}, nil
+}
+
+// LoadDueScheduledUpdates ...
+func (m *Memory) LoadDueScheduledUpdates(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]*api.ScheduledUpdate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := []*api.ScheduledUpdate{}
+	for _, update := range m.scheduledUpdates {
+		due := update.Status == api.SchedulePending && !update.EffectiveAt.After(now)
+		expired := update.Status == api.ScheduleRunning && update.ClaimedAt != nil &&
+			!update.ClaimedAt.After(now.Add(-lease))
+		if due || expired {
+			clone := *update
+			data = append(data, &clone)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool {
+		if data[i].EffectiveAt.Equal(data[j].EffectiveAt) {
+			return data[i].ID < data[j].ID
+		}
+		return data[i].EffectiveAt.Before(data[j].EffectiveAt)
+	})
+	if uint64(len(data)) > limit {
+		data = data[:limit]
+	}
+	return data, nil
+}
+
+// ClaimScheduledUpdate ...
+func (m *Memory) ClaimScheduledUpdate(ctx context.Context, update *api.ScheduledUpdate) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if update == nil {
+		return false, store.ErrNilStruct
+	}
+	stored, ok := m.scheduledUpdates[update.ID]
+	if !ok || stored.Status != update.Status {
+		return false, nil
+	}
+	if (stored.ClaimedAt == nil) != (update.ClaimedAt == nil) ||
+		stored.ClaimedAt != nil && !stored.ClaimedAt.Equal(*update.ClaimedAt) {
+		return false, nil
+	}
+	now := time.Now()
+	stored.Status = api.ScheduleRunning
+	stored.ClaimedAt = &now
+	stored.UpdatedAt = now
+	*update = *stored
+	return true, nil
+}
+
+// UpdateScheduledUpdate ...
+func (m *Memory) UpdateScheduledUpdate(ctx context.Context, update *api.ScheduledUpdate, status api.ScheduleStatus) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if update == nil {
+		return false, store.ErrNilStruct
+	}
+	stored, ok := m.scheduledUpdates[update.ID]
+	if !ok || stored.Status != status {
+		return false, nil
+	}
+	update.UpdatedAt = time.Now()
+	clone := *update
+	m.scheduledUpdates[update.ID] = &clone
+	return true, nil
+}
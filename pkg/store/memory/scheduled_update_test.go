@@ -0,0 +1,99 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"github.com/danikarik/okpock/pkg/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledUpdates(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	mock := memory.New()
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	project.ID = fakeID()
+
+	other := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	other.ID = project.ID + 1
+
+	now := time.Now()
+
+	due := api.NewScheduledUpdate(project.ID, now.Add(-time.Minute), api.Author{UserID: 1})
+	due.PassCardID = fakeID()
+	due.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+	err := mock.SaveNewScheduledUpdate(ctx, project, due)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(due.ID > 0)
+
+	later := api.NewScheduledUpdate(project.ID, now.Add(time.Hour), api.Author{UserID: 1})
+	later.Filter = api.RawJSON(`{"all":true}`)
+	later.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+	err = mock.SaveNewScheduledUpdate(ctx, project, later)
+	if !assert.NoError(err) {
+		return
+	}
+
+	updates, err := mock.LoadDueScheduledUpdates(ctx, now, time.Minute, 10)
+	if assert.NoError(err) && assert.Len(updates, 1) {
+		assert.Equal(due.ID, updates[0].ID)
+	}
+
+	stale := *due
+
+	ok, err := mock.ClaimScheduledUpdate(ctx, due)
+	if assert.NoError(err) && assert.True(ok) {
+		assert.Equal(api.ScheduleRunning, due.Status)
+		assert.NotNil(due.ClaimedAt)
+	}
+
+	ok, err = mock.ClaimScheduledUpdate(ctx, &stale)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	ok, err = mock.UpdateScheduledUpdate(ctx, due, api.SchedulePending)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	updates, err = mock.LoadDueScheduledUpdates(ctx, now, time.Minute, 10)
+	if assert.NoError(err) {
+		assert.Len(updates, 0)
+	}
+
+	updates, err = mock.LoadDueScheduledUpdates(ctx, now.Add(2*time.Hour), time.Minute, 10)
+	if assert.NoError(err) && assert.Len(updates, 2) {
+		assert.Equal(due.ID, updates[0].ID)
+		assert.Equal(later.ID, updates[1].ID)
+
+		ok, err = mock.ClaimScheduledUpdate(ctx, updates[0])
+		if assert.NoError(err) {
+			assert.True(ok)
+		}
+	}
+
+	loaded, err := mock.LoadScheduledUpdate(ctx, project, due.ID)
+	if assert.NoError(err) {
+		assert.Equal(api.ScheduleRunning, loaded.Status)
+		assert.Equal(due.PassCardID, loaded.PassCardID)
+		assert.JSONEq(string(due.Patch), string(loaded.Patch))
+	}
+
+	_, err = mock.LoadScheduledUpdate(ctx, other, due.ID)
+	assert.Equal(store.ErrNotFound, err)
+
+	list, err := mock.LoadScheduledUpdates(ctx, project, api.NewPagingOptions(0, 0))
+	if assert.NoError(err) && assert.Len(list.Data, 2) {
+		assert.Equal(later.ID, list.Data[0].ID)
+		assert.Equal(due.ID, list.Data[1].ID)
+	}
+}
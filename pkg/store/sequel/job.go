@@ -0,0 +1,403 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+func checkJob(j *api.Job, opts byte) error {
+	if (opts & checkNilStruct) != 0 {
+		if j == nil {
+			return store.ErrNilStruct
+		}
+	}
+
+	if (opts & checkZeroID) != 0 {
+		if j.ID == 0 {
+			return store.ErrZeroID
+		}
+	}
+
+	return nil
+}
+
+// SaveNewJob ...
+func (m *MySQL) SaveNewJob(ctx context.Context, project *api.Project, job *api.Job) error {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	err = checkJob(job, checkNilStruct)
+	if err != nil {
+		return err
+	}
+
+	job.ProjectID = project.ID
+
+	query := m.builder.Insert("jobs").
+		Columns(
+			"project_id",
+			"kind",
+			"params",
+			"`cursor`",
+			"status",
+			"total",
+			"processed",
+			"failed",
+			"last_error",
+			"attempts",
+			"lease_until",
+			"user_id",
+			"request_id",
+			"created_at",
+			"updated_at",
+		).
+		Values(
+			job.ProjectID,
+			job.Kind,
+			job.Params,
+			job.Cursor,
+			job.Status,
+			job.Total,
+			job.Processed,
+			job.Failed,
+			job.LastError,
+			job.Attempts,
+			job.LeaseUntil,
+			job.UserID,
+			job.RequestID,
+			job.CreatedAt,
+			job.UpdatedAt,
+		)
+
+	id, err := m.insertQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	job.ID = id
+
+	return nil
+}
+
+// LoadJob ...
+func (m *MySQL) LoadJob(ctx context.Context, project *api.Project, id int64) (*api.Job, error) {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == 0 {
+		return nil, store.ErrZeroID
+	}
+
+	query := m.builder.Select("*").
+		From("jobs").
+		Where(sq.Eq{
+			"id":         id,
+			"project_id": project.ID,
+		})
+
+	row, err := m.selectRowQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var job = &api.Job{}
+
+	err = row.StructScan(job)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// LoadJobs ...
+func (m *MySQL) LoadJobs(ctx context.Context, project *api.Project, opts *api.PagingOptions) (*api.Jobs, error) {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var jobs = &api.Jobs{
+		Opts: opts,
+		Data: []*api.Job{},
+	}
+
+	query := m.builder.Select("*").
+		From("jobs").
+		Where(sq.Eq{"project_id": project.ID}).
+		OrderBy("created_at desc", "id desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return jobs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cnt uint64
+	for rows.Next() {
+		var job = &api.Job{}
+
+		err = rows.StructScan(job)
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = job.ID
+		} else {
+			jobs.Data = append(jobs.Data, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// LoadPendingJobs ...
+func (m *MySQL) LoadPendingJobs(ctx context.Context, now time.Time, limit uint64) ([]*api.Job, error) {
+	var jobs = []*api.Job{}
+
+	query := m.builder.Select("*").
+		From("jobs").
+		Where(sq.Or{
+			sq.Eq{"status": api.JobPending},
+			sq.And{
+				sq.Eq{"status": api.JobRunning},
+				sq.LtOrEq{"lease_until": now},
+			},
+		}).
+		OrderBy("id").
+		Limit(limit)
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return jobs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var job = &api.Job{}
+
+		err = rows.StructScan(job)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// ClaimJob ...
+func (m *MySQL) ClaimJob(ctx context.Context, lease time.Duration, job *api.Job) (bool, error) {
+	err := checkJob(job, checkNilStruct|checkZeroID)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		now        = time.Now()
+		leaseUntil = now.Add(lease)
+	)
+
+	query := m.builder.Update("jobs").
+		Set("status", api.JobRunning).
+		Set("attempts", job.Attempts+1).
+		Set("lease_until", leaseUntil).
+		Set("updated_at", now).
+		Where(sq.Eq{
+			"id":       job.ID,
+			"attempts": job.Attempts,
+			"status":   []api.JobStatus{api.JobPending, api.JobRunning},
+		})
+
+	_, err = m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	job.Status = api.JobRunning
+	job.Attempts++
+	job.LeaseUntil = &leaseUntil
+	job.UpdatedAt = now
+
+	return true, nil
+}
+
+// UpdateJob ...
+func (m *MySQL) UpdateJob(ctx context.Context, job *api.Job) error {
+	err := checkJob(job, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := m.builder.Update("jobs").
+		Set("`cursor`", job.Cursor).
+		Set("status", job.Status).
+		Set("total", job.Total).
+		Set("processed", job.Processed).
+		Set("failed", job.Failed).
+		Set("last_error", job.LastError).
+		Set("lease_until", job.LeaseUntil).
+		Set("updated_at", now).
+		Set("finished_at", job.FinishedAt).
+		Where(sq.Eq{
+			"id":       job.ID,
+			"attempts": job.Attempts,
+		})
+
+	_, err = m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		// MySQL reports zero rows when nothing has changed,
+		// so ownership is checked separately.
+		err = m.checkJobAttempts(ctx, job)
+	}
+	if err != nil {
+		return err
+	}
+	job.UpdatedAt = now
+
+	return nil
+}
+
+func (m *MySQL) checkJobAttempts(ctx context.Context, job *api.Job) error {
+	query := m.builder.Select("count(1)").From("jobs").
+		Where(sq.Eq{
+			"id":       job.ID,
+			"attempts": job.Attempts,
+		})
+
+	cnt, err := m.countQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return store.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// SaveJobResult ...
+func (m *MySQL) SaveJobResult(ctx context.Context, job *api.Job, result *api.JobResult) error {
+	err := checkJob(job, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return store.ErrNilStruct
+	}
+
+	result.JobID = job.ID
+
+	query := m.builder.Insert("job_results").
+		Columns(
+			"job_id",
+			"`row`",
+			"pass_card_id",
+			"serial_number",
+			"error",
+			"created_at",
+		).
+		Values(
+			result.JobID,
+			result.Row,
+			result.PassCardID,
+			result.SerialNumber,
+			result.Error,
+			result.CreatedAt,
+		)
+
+	id, err := m.insertQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	result.ID = id
+
+	return nil
+}
+
+// LoadJobResults ...
+func (m *MySQL) LoadJobResults(ctx context.Context, job *api.Job, opts *api.PagingOptions) (*api.JobResults, error) {
+	err := checkJob(job, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var results = &api.JobResults{
+		Opts: opts,
+		Data: []*api.JobResult{},
+	}
+
+	query := m.builder.Select("*").
+		From("job_results").
+		Where(sq.Eq{"job_id": job.ID}).
+		OrderBy("id").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.GtOrEq{"id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cnt uint64
+	for rows.Next() {
+		var result = &api.JobResult{}
+
+		err = rows.StructScan(result)
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = result.ID
+		} else {
+			results.Data = append(results.Data, result)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,230 @@
+package sequel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"github.com/danikarik/okpock/pkg/store/sequel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	other := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, other)
+	if !assert.NoError(err) {
+		return
+	}
+
+	first := api.NewJob(api.PublishJob, project.ID, api.Author{})
+	err = db.SaveNewJob(ctx, project, first)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(first.ID > 0)
+
+	second := api.NewJob(api.PublishJob, project.ID, api.Author{})
+	err = db.SaveNewJob(ctx, project, second)
+	if !assert.NoError(err) {
+		return
+	}
+
+	first.Status = api.JobRunning
+	first.Total = 10
+	first.Processed = 4
+	first.Failed = 1
+	first.LastError = "upload failed"
+	err = db.UpdateJob(ctx, first)
+	if !assert.NoError(err) {
+		return
+	}
+
+	loaded, err := db.LoadJob(ctx, project, first.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(api.JobRunning, loaded.Status)
+	assert.Equal(10, loaded.Total)
+	assert.Equal(4, loaded.Processed)
+	assert.Equal(1, loaded.Failed)
+	assert.Equal("upload failed", loaded.LastError)
+	assert.Nil(loaded.FinishedAt)
+
+	loaded.Finish(api.JobFinished)
+	err = db.UpdateJob(ctx, loaded)
+	if !assert.NoError(err) {
+		return
+	}
+
+	loaded, err = db.LoadJob(ctx, project, first.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(loaded.Done())
+	assert.NotNil(loaded.FinishedAt)
+
+	jobs, err := db.LoadJobs(ctx, project, api.NewPagingOptions(0, 1))
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(jobs.Data, 1) {
+		return
+	}
+	assert.Equal(second.ID, jobs.Data[0].ID)
+	assert.Equal(first.ID, jobs.Opts.Next)
+
+	_, err = db.LoadJob(ctx, other, first.ID)
+	assert.Equal(store.ErrNotFound, err)
+
+	jobs, err = db.LoadJobs(ctx, other, nil)
+	assert.NoError(err)
+	assert.Empty(jobs.Data)
+
+	failed := api.NewJobResult(1)
+	failed.Error = "pass structure: only one style allowed"
+	err = db.SaveJobResult(ctx, first, failed)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(failed.ID > 0)
+
+	succeeded := api.NewJobResult(2)
+	succeeded.PassCardID = 42
+	succeeded.SerialNumber = fakeString()
+	err = db.SaveJobResult(ctx, first, succeeded)
+	if !assert.NoError(err) {
+		return
+	}
+
+	results, err := db.LoadJobResults(ctx, first, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(results.Data, 2) {
+		return
+	}
+	assert.Equal(1, results.Data[0].Row)
+	assert.False(results.Data[0].Succeeded())
+	assert.Equal(2, results.Data[1].Row)
+	assert.True(results.Data[1].Succeeded())
+	assert.Equal(succeeded.SerialNumber, results.Data[1].SerialNumber)
+
+	results, err = db.LoadJobResults(ctx, second, nil)
+	assert.NoError(err)
+	assert.Empty(results.Data)
+}
+
+func TestClaimJob(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	job := api.NewJob(api.PublishJob, project.ID, api.Author{UserID: user.ID})
+	job.Params = api.RawJSON(`{"filter":{"all":true}}`)
+	err = db.SaveNewJob(ctx, project, job)
+	if !assert.NoError(err) {
+		return
+	}
+
+	findJob := func(now time.Time) *api.Job {
+		pending, err := db.LoadPendingJobs(ctx, now, 1000)
+		if !assert.NoError(err) {
+			return nil
+		}
+		for _, pendingJob := range pending {
+			if pendingJob.ID == job.ID {
+				return pendingJob
+			}
+		}
+		return nil
+	}
+
+	claimed := findJob(time.Now())
+	if !assert.NotNil(claimed) {
+		return
+	}
+	assert.Equal(user.ID, claimed.UserID)
+	assert.JSONEq(`{"filter":{"all":true}}`, string(claimed.Params))
+	stale := *claimed
+
+	ok, err := db.ClaimJob(ctx, time.Minute, claimed)
+	if !assert.NoError(err) || !assert.True(ok) {
+		return
+	}
+	assert.Equal(api.JobRunning, claimed.Status)
+	assert.Equal(1, claimed.Attempts)
+
+	ok, err = db.ClaimJob(ctx, time.Minute, &stale)
+	assert.NoError(err)
+	assert.False(ok)
+
+	assert.Nil(findJob(time.Now()))
+
+	reclaimed := findJob(time.Now().Add(2 * time.Minute))
+	if !assert.NotNil(reclaimed) {
+		return
+	}
+
+	ok, err = db.ClaimJob(ctx, time.Minute, reclaimed)
+	if !assert.NoError(err) || !assert.True(ok) {
+		return
+	}
+	assert.Equal(2, reclaimed.Attempts)
+
+	claimed.Processed = 1
+	err = db.UpdateJob(ctx, claimed)
+	assert.Equal(store.ErrVersionMismatch, err)
+
+	reclaimed.Cursor = 42
+	reclaimed.Finish(api.JobFinished)
+	err = db.UpdateJob(ctx, reclaimed)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Nil(findJob(time.Now().Add(2 * time.Minute)))
+}
@@ -95,6 +95,27 @@ func (m *MySQL) finishTx(tx *sqlx.Tx, err error) error {
 	return err
 }
 
+// withTx runs fn in a single transaction, which is rolled back if fn fails.
+func (m *MySQL) withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = m.finishTx(tx, err) }()
+
+	return fn(tx)
+}
+
+func execTx(ctx context.Context, tx *sqlx.Tx, query sq.Sqlizer) (sql.Result, error) {
+	rawsql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.ExecContext(ctx, rawsql, args...)
+}
+
 func (m *MySQL) insertQuery(ctx context.Context, query sq.InsertBuilder) (id int64, err error) {
 	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"testing"
 
+	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/env"
+	"github.com/danikarik/okpock/pkg/secure"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	uuid "github.com/satori/go.uuid"
@@ -20,7 +22,13 @@ var clean = []string{
 	"DELETE FROM `users`",
 	"DELETE FROM `logs`",
 	"DELETE FROM `registrations`",
+	"DELETE FROM `devices`",
 	"DELETE FROM `passes`",
+	"DELETE FROM `pushes`",
+	"DELETE FROM `jobs`",
+	"DELETE FROM `job_results`",
+	"DELETE FROM `pass_card_versions`",
+	"DELETE FROM `scheduled_updates`",
 }
 
 func testConnection(ctx context.Context, t *testing.T) (*sqlx.DB, error) {
@@ -55,3 +63,25 @@ func fakeEmail() string {
 func fakeString() string {
 	return uuid.NewV4().String()
 }
+
+func fakePassCard(project *api.Project) *api.PassCardInfo {
+	return api.NewPassCardInfo(&api.PassCard{
+		Description:      project.Description,
+		FormatVersion:    1,
+		OrganizationName: project.OrganizationName,
+		PassTypeID:       "pass.com.example." + string(project.PassType),
+		SerialNumber:     fakeString(),
+		TeamID:           fakeString(),
+		Coupon: &api.PassStructure{
+			PrimaryFields: []*api.Field{
+				&api.Field{
+					Key:   "offer",
+					Label: "offer_label",
+					Value: "20% off",
+				},
+			},
+		},
+		AuthenticationToken: secure.Token(),
+		WebServiceURL:       "https://okpock.com",
+	})
+}
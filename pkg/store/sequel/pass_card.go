@@ -3,11 +3,13 @@ package sequel
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/store"
+	sqlx "github.com/jmoiron/sqlx"
 )
 
 func checkPassCard(p *api.PassCardInfo, opts byte) error {
@@ -41,34 +43,55 @@ func (m *MySQL) SaveNewPassCard(ctx context.Context, project *api.Project, passc
 		return err
 	}
 
-	query := m.builder.Insert("pass_cards").
-		Columns(
-			"raw_data",
-			"created_at",
-			"updated_at",
-		).
-		Values(
-			passcard.Data,
-			passcard.CreatedAt,
-			passcard.UpdatedAt,
-		)
-
-	id, err := m.insertQuery(ctx, query)
-	if err != nil {
-		return err
+	if passcard.Version == 0 {
+		passcard.Version = 1
 	}
-	passcard.ID = id
+	passcard.ExpiresAt = passcard.Data.Expiration()
+
+	return m.withTx(ctx, func(tx *sqlx.Tx) error {
+		query := m.builder.Insert("pass_cards").
+			Columns(
+				"raw_data",
+				"localizations",
+				"version",
+				"created_at",
+				"updated_at",
+				"expires_at",
+			).
+			Values(
+				passcard.Data,
+				passcard.Localizations,
+				passcard.Version,
+				passcard.CreatedAt,
+				passcard.UpdatedAt,
+				passcard.ExpiresAt,
+			)
+
+		res, err := execTx(ctx, tx, query)
+		if err != nil {
+			return err
+		}
 
-	query = m.builder.Insert("project_pass_cards").
-		Columns("project_id", "pass_card_id").
-		Values(project.ID, passcard.ID)
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if id == 0 {
+			return store.ErrZeroID
+		}
+		passcard.ID = id
 
-	_, err = m.insertQuery(ctx, query)
-	if err != nil {
-		return err
-	}
+		query = m.builder.Insert("project_pass_cards").
+			Columns("project_id", "pass_card_id").
+			Values(project.ID, passcard.ID)
 
-	return nil
+		_, err = execTx(ctx, tx, query)
+		if err != nil {
+			return err
+		}
+
+		return m.savePassCardVersion(ctx, tx, passcard)
+	})
 }
 
 func (m *MySQL) loadPassCard(ctx context.Context, query sq.SelectBuilder) (*api.PassCardInfo, error) {
@@ -143,7 +166,10 @@ func (m *MySQL) LoadPassCards(ctx context.Context, project *api.Project, opts *a
 	query := m.builder.Select("pc.*").
 		From("pass_cards pc").
 		LeftJoin("project_pass_cards ppc on ppc.pass_card_id = pc.id").
-		Where(sq.Eq{"ppc.project_id": project.ID}).
+		Where(sq.Eq{
+			"ppc.project_id": project.ID,
+			"pc.archived_at": nil,
+		}).
 		OrderBy("pc.created_at desc", "pc.id desc").
 		Limit(opts.Limit + 1)
 
@@ -200,8 +226,141 @@ func (m *MySQL) LoadPassCardsByBarcodeMessage(ctx context.Context, project *api.
 	query := m.builder.Select("pc.*").
 		From("pass_cards pc").
 		LeftJoin("project_pass_cards ppc on ppc.pass_card_id = pc.id").
-		Where(sq.Eq{"ppc.project_id": project.ID}).
-		Where("JSON_CONTAINS(pc.raw_data->>'$.barcodes[*].message', JSON_ARRAY('"+message+"'))").
+		Where(sq.Eq{
+			"ppc.project_id": project.ID,
+			"pc.archived_at": nil,
+		}).
+		Where("JSON_CONTAINS(pc.raw_data->>'$.barcodes[*].message', JSON_ARRAY(?))", message).
+		OrderBy("pc.created_at desc", "pc.id desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"pc.id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return passcards, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cnt uint64
+	for rows.Next() {
+		var passcard = &api.PassCardInfo{}
+
+		err = rows.StructScan(passcard)
+		if err == sql.ErrNoRows {
+			return nil, store.ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = passcard.ID
+		} else {
+			passcards.Data = append(passcards.Data, passcard)
+		}
+	}
+
+	return passcards, nil
+}
+
+// LoadPassCardsByGroupingIdentifier ...
+func (m *MySQL) LoadPassCardsByGroupingIdentifier(ctx context.Context, project *api.Project, groupingID string, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupingID == "" {
+		return nil, store.ErrEmptyQueryParam
+	}
+
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var passcards = &api.PassCardInfoList{
+		Opts: opts,
+		Data: []*api.PassCardInfo{},
+	}
+
+	query := m.builder.Select("pc.*").
+		From("pass_cards pc").
+		LeftJoin("project_pass_cards ppc on ppc.pass_card_id = pc.id").
+		Where(sq.Eq{
+			"ppc.project_id":                       project.ID,
+			"pc.raw_data->>'$.groupingIdentifier'": groupingID,
+			"pc.archived_at":                       nil,
+		}).
+		OrderBy("pc.created_at desc", "pc.id desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"pc.id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return passcards, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cnt uint64
+	for rows.Next() {
+		var passcard = &api.PassCardInfo{}
+
+		err = rows.StructScan(passcard)
+		if err == sql.ErrNoRows {
+			return nil, store.ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = passcard.ID
+		} else {
+			passcards.Data = append(passcards.Data, passcard)
+		}
+	}
+
+	return passcards, nil
+}
+
+// LoadPassCardsByUserInfo ...
+func (m *MySQL) LoadPassCardsByUserInfo(ctx context.Context, project *api.Project, key, value string, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		return nil, store.ErrEmptyQueryParam
+	}
+
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var passcards = &api.PassCardInfoList{
+		Opts: opts,
+		Data: []*api.PassCardInfo{},
+	}
+
+	query := m.builder.Select("pc.*").
+		From("pass_cards pc").
+		LeftJoin("project_pass_cards ppc on ppc.pass_card_id = pc.id").
+		Where(sq.Eq{
+			"ppc.project_id": project.ID,
+			"pc.archived_at": nil,
+		}).
+		Where("JSON_UNQUOTE(JSON_EXTRACT(pc.raw_data, ?)) = ?", userInfoPath(key), value).
 		OrderBy("pc.created_at desc", "pc.id desc").
 		Limit(opts.Limit + 1)
 
@@ -239,6 +398,14 @@ func (m *MySQL) LoadPassCardsByBarcodeMessage(ctx context.Context, project *api.
 	return passcards, nil
 }
 
+// userInfoPath returns JSON path of user info key. Key is quoted so it may
+// contain any characters.
+func userInfoPath(key string) string {
+	key = strings.Replace(key, `\`, `\\`, -1)
+	key = strings.Replace(key, `"`, `\"`, -1)
+	return `$.userInfo."` + key + `"`
+}
+
 // UpdatePassCard ...
 func (m *MySQL) UpdatePassCard(ctx context.Context, data *api.PassCard, passcard *api.PassCardInfo) error {
 	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
@@ -247,11 +414,87 @@ func (m *MySQL) UpdatePassCard(ctx context.Context, data *api.PassCard, passcard
 	}
 
 	data.CopyFrom(passcard.Data)
-	passcard.Data = data
+
+	updated := *passcard
+	updated.Data = data
+	updated.Version++
+	updated.UpdatedAt = time.Now()
+	updated.ExpiresAt = data.Expiration()
+
+	err = m.withTx(ctx, func(tx *sqlx.Tx) error {
+		query := m.builder.Update("pass_cards").
+			Set("raw_data", updated.Data).
+			Set("version", updated.Version).
+			Set("updated_at", updated.UpdatedAt).
+			Set("expires_at", updated.ExpiresAt).
+			Where(sq.Eq{
+				"id":          passcard.ID,
+				"version":     passcard.Version,
+				"archived_at": nil,
+			})
+
+		res, err := execTx(ctx, tx, query)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return m.checkPassCardArchived(ctx, passcard)
+		}
+
+		return m.savePassCardVersion(ctx, tx, &updated)
+	})
+	if err != nil {
+		return err
+	}
+
+	*passcard = updated
+
+	return nil
+}
+
+// checkPassCardArchived tells why pass card is not updated. Returns
+// `store.ErrNotFound` if pass card is archived or removed and
+// `store.ErrVersionMismatch` otherwise.
+func (m *MySQL) checkPassCardArchived(ctx context.Context, passcard *api.PassCardInfo) error {
+	query := m.builder.Select("count(1)").From("pass_cards").
+		Where(sq.Eq{
+			"id":          passcard.ID,
+			"archived_at": nil,
+		})
+
+	cnt, err := m.countQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return store.ErrNotFound
+	}
+
+	return store.ErrVersionMismatch
+}
+
+// SetPassCardLocalizations ...
+func (m *MySQL) SetPassCardLocalizations(ctx context.Context, localizations api.Localizations, passcard *api.PassCardInfo) error {
+	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	err = localizations.IsValid()
+	if err != nil {
+		return err
+	}
+
+	passcard.Localizations = localizations
 	passcard.UpdatedAt = time.Now()
 
 	query := m.builder.Update("pass_cards").
-		Set("raw_data", passcard.Data).
+		Set("localizations", passcard.Localizations).
 		Set("updated_at", passcard.UpdatedAt).
 		Where(sq.Eq{"id": passcard.ID})
 
@@ -262,3 +505,195 @@ func (m *MySQL) UpdatePassCard(ctx context.Context, data *api.PassCard, passcard
 
 	return nil
 }
+
+// ArchivePassCard ...
+func (m *MySQL) ArchivePassCard(ctx context.Context, passcard *api.PassCardInfo) error {
+	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	archivedAt := time.Now()
+
+	query := m.builder.Update("pass_cards").
+		Set("archived_at", archivedAt).
+		Where(sq.Eq{
+			"id":          passcard.ID,
+			"version":     passcard.Version,
+			"archived_at": nil,
+		})
+
+	_, err = m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return m.checkPassCardArchived(ctx, passcard)
+	}
+	if err != nil {
+		return err
+	}
+
+	passcard.ArchivedAt = &archivedAt
+
+	return nil
+}
+
+// LoadArchivedPassCards ...
+func (m *MySQL) LoadArchivedPassCards(ctx context.Context, before time.Time, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var passcards = &api.PassCardInfoList{
+		Opts: opts,
+		Data: []*api.PassCardInfo{},
+	}
+
+	query := m.builder.Select("*").
+		From("pass_cards").
+		Where(sq.LtOrEq{"archived_at": before}).
+		OrderBy("id desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return passcards, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cnt uint64
+	for rows.Next() {
+		var passcard = &api.PassCardInfo{}
+
+		err = rows.StructScan(passcard)
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = passcard.ID
+		} else {
+			passcards.Data = append(passcards.Data, passcard)
+		}
+	}
+
+	return passcards, nil
+}
+
+// LoadExpiredPassCards ...
+func (m *MySQL) LoadExpiredPassCards(ctx context.Context, before time.Time, opts *api.PagingOptions) (*api.PassCardInfoList, error) {
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var passcards = &api.PassCardInfoList{
+		Opts: opts,
+		Data: []*api.PassCardInfo{},
+	}
+
+	query := m.builder.Select("pc.*").
+		From("pass_cards pc").
+		LeftJoin("project_pass_cards ppc on ppc.pass_card_id = pc.id").
+		LeftJoin("projects p on p.id = ppc.project_id").
+		Where(sq.Eq{"pc.archived_at": nil}).
+		Where("COALESCE(pc.raw_data->>'$.voided', 'false') <> 'true'").
+		Where(sq.Or{
+			sq.LtOrEq{"pc.expires_at": before},
+			sq.And{
+				sq.Gt{"p.expire_after_days": 0},
+				sq.Expr("pc.created_at <= DATE_SUB(?, INTERVAL p.expire_after_days DAY)", before),
+			},
+		}).
+		OrderBy("pc.id desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"pc.id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return passcards, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cnt uint64
+	for rows.Next() {
+		var passcard = &api.PassCardInfo{}
+
+		err = rows.StructScan(passcard)
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = passcard.ID
+		} else {
+			passcards.Data = append(passcards.Data, passcard)
+		}
+	}
+
+	return passcards, nil
+}
+
+// LoadPassCardProject ...
+func (m *MySQL) LoadPassCardProject(ctx context.Context, passcard *api.PassCardInfo) (*api.Project, error) {
+	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := m.builder.Select("p.*").
+		From("projects p").
+		LeftJoin("project_pass_cards ppc on ppc.project_id = p.id").
+		Where(sq.Eq{"ppc.pass_card_id": passcard.ID})
+
+	row, err := m.selectRowQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var project = &api.Project{}
+
+	err = row.StructScan(project)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// DeletePassCard ...
+func (m *MySQL) DeletePassCard(ctx context.Context, passcard *api.PassCardInfo) error {
+	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	queries := []sq.DeleteBuilder{
+		m.builder.Delete("project_pass_cards").Where(sq.Eq{"pass_card_id": passcard.ID}),
+		m.builder.Delete("pass_card_versions").Where(sq.Eq{"pass_card_id": passcard.ID}),
+		m.builder.Delete("pass_cards").Where(sq.Eq{"id": passcard.ID}),
+	}
+
+	return m.withTx(ctx, func(tx *sqlx.Tx) error {
+		for _, query := range queries {
+			_, err := execTx(ctx, tx, query)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
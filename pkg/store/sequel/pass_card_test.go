@@ -8,6 +8,7 @@ import (
 
 	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/secure"
+	"github.com/danikarik/okpock/pkg/store"
 	"github.com/danikarik/okpock/pkg/store/sequel"
 	"github.com/stretchr/testify/assert"
 )
@@ -130,6 +131,43 @@ func TestSaveNewPassCard(t *testing.T) {
 			},
 			LoadByBarcodeMessage: true,
 		},
+		{
+			Name:     "CouponByQuotedBarcodeMessage",
+			PassType: api.Coupon,
+			PassCard: &api.PassCard{
+				FormatVersion: 1,
+				SerialNumber:  fakeString(),
+				TeamID:        fakeString(),
+				Coupon: &api.PassStructure{
+					AuxiliaryFields: []*api.Field{
+						&api.Field{
+							Key:        "expires",
+							Label:      "EXPIRES",
+							Value:      "2020-04-24T10:00-05:00",
+							IsRelative: true,
+							DateStyle:  api.PKDateStyleShort,
+						},
+					},
+					BackFields: []*api.Field{
+						&api.Field{
+							Key:   "offer",
+							Label: "Any premium dog food",
+							Value: "20% off",
+						},
+					},
+				},
+				Barcodes: []*api.Barcode{
+					&api.Barcode{
+						Message:         fakeString() + `') OR JSON_ARRAY('`,
+						Format:          api.PKBarcodeFormatPDF417,
+						MessageEncoding: "iso-8859-1",
+					},
+				},
+				AuthenticationToken: secure.Token(),
+				WebServiceURL:       "https://okpock.com",
+			},
+			LoadByBarcodeMessage: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -328,6 +366,13 @@ func TestUpdatePassCard(t *testing.T) {
 			assert.Equal(passcard.ID, loaded.ID)
 			assert.Equal(passcard.Data, loaded.Data)
 			assert.Equal(loaded.Data.Locations, tc.Locations)
+			assert.Equal(int64(2), loaded.Version)
+
+			stale := *loaded
+			stale.Version = 1
+			err = db.UpdatePassCard(ctx, loaded.Data, &stale)
+			assert.Equal(store.ErrVersionMismatch, err)
+			assert.Equal(int64(1), stale.Version)
 		})
 	}
 }
@@ -531,3 +576,360 @@ func TestPassCardsPagination(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadPassCardsByGroupingIdentifier(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	groupingID := fakeString()
+	for i := 0; i < 3; i++ {
+		passcard := fakePassCard(project)
+		if i > 0 {
+			passcard.Data.GroupingIdentifier = groupingID
+		}
+		err = db.SaveNewPassCard(ctx, project, passcard)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	passcards, err := db.LoadPassCardsByGroupingIdentifier(ctx, project, groupingID, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(passcards.Data, 2) {
+		for _, passcard := range passcards.Data {
+			assert.Equal(groupingID, passcard.Data.GroupingIdentifier)
+		}
+	}
+
+	passcards, err = db.LoadPassCardsByGroupingIdentifier(ctx, project, fakeString(), api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(passcards.Data, 0)
+}
+
+func TestLoadPassCardsByUserInfo(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	tier := fakeString()
+	for i := 0; i < 3; i++ {
+		passcard := fakePassCard(project)
+		passcard.Data.UserInfo = api.JSONMap{"visits": float64(i)}
+		if i > 0 {
+			passcard.Data.UserInfo["tier"] = tier
+		}
+		err = db.SaveNewPassCard(ctx, project, passcard)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	passcards, err := db.LoadPassCardsByUserInfo(ctx, project, "tier", tier, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(passcards.Data, 2) {
+		for _, passcard := range passcards.Data {
+			assert.Equal(tier, passcard.Data.UserInfo["tier"])
+		}
+	}
+
+	passcards, err = db.LoadPassCardsByUserInfo(ctx, project, "visits", "2", api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(passcards.Data, 1)
+
+	passcards, err = db.LoadPassCardsByUserInfo(ctx, project, "tier", fakeString(), api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(passcards.Data, 0)
+}
+
+func TestLoadPassCardVersions(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	author := api.Author{UserID: user.ID, RequestID: fakeString()}
+	ctx = api.WithAuthor(ctx, author)
+
+	passcard := fakePassCard(project)
+	err = db.SaveNewPassCard(ctx, project, passcard)
+	if !assert.NoError(err) {
+		return
+	}
+
+	newData := *passcard.Data
+	newData.LogoText = fakeString()
+	err = db.UpdatePassCard(ctx, &newData, passcard)
+	if !assert.NoError(err) {
+		return
+	}
+
+	versions, err := db.LoadPassCardVersions(ctx, passcard, api.NewPagingOptions(0, 1))
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(versions.Data, 1) {
+		assert.Equal(int64(2), versions.Data[0].Version)
+		assert.Equal(newData.LogoText, versions.Data[0].Data.LogoText)
+		assert.Equal(author.UserID, versions.Data[0].UserID)
+		assert.Equal(author.RequestID, versions.Data[0].RequestID)
+	}
+	assert.Equal(int64(1), versions.Opts.Next)
+
+	version, err := db.LoadPassCardVersion(ctx, passcard, 1)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(passcard.ID, version.PassCardID)
+	assert.Empty(version.Data.LogoText)
+
+	_, err = db.LoadPassCardVersion(ctx, passcard, 3)
+	assert.Equal(store.ErrNotFound, err)
+}
+
+func TestArchivePassCard(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	passcards := []*api.PassCardInfo{}
+	for i := 0; i < 2; i++ {
+		passcard := fakePassCard(project)
+		err = db.SaveNewPassCard(ctx, project, passcard)
+		if !assert.NoError(err) {
+			return
+		}
+		passcards = append(passcards, passcard)
+	}
+
+	archived := passcards[0]
+	stale := *archived
+	changed := *archived.Data
+	changed.Description = fakeString()
+	err = db.UpdatePassCard(ctx, &changed, archived)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = db.ArchivePassCard(ctx, &stale)
+	assert.Equal(store.ErrVersionMismatch, err)
+
+	err = db.ArchivePassCard(ctx, archived)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(archived.IsArchived())
+
+	err = db.ArchivePassCard(ctx, archived)
+	assert.Equal(store.ErrNotFound, err)
+
+	data := *archived.Data
+	data.Description = fakeString()
+	err = db.UpdatePassCard(ctx, &data, archived)
+	assert.Equal(store.ErrNotFound, err)
+
+	list, err := db.LoadPassCards(ctx, project, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(list.Data, 1) {
+		assert.Equal(passcards[1].ID, list.Data[0].ID)
+	}
+
+	loaded, err := db.LoadPassCard(ctx, project, archived.ID)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(loaded.IsArchived())
+
+	list, err = db.LoadArchivedPassCards(ctx, archived.ArchivedAt.Add(-time.Hour), api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(list.Data, 0)
+
+	list, err = db.LoadArchivedPassCards(ctx, time.Now().Add(time.Hour), api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(list.Data, 1) {
+		assert.Equal(archived.ID, list.Data[0].ID)
+	}
+
+	err = db.DeletePassCard(ctx, archived)
+	if !assert.NoError(err) {
+		return
+	}
+
+	_, err = db.LoadPassCard(ctx, project, archived.ID)
+	assert.Equal(store.ErrNotFound, err)
+
+	versions, err := db.LoadPassCardVersions(ctx, archived, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(versions.Data, 0)
+}
+
+func TestLoadExpiredPassCards(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	now := time.Now().Truncate(time.Second)
+
+	expiring := fakePassCard(project)
+	expiring.Data.ExpirationDate = now.Add(time.Hour).Format(time.RFC3339)
+
+	voided := fakePassCard(project)
+	voided.Data.ExpirationDate = now.Add(-time.Hour).Format(time.RFC3339)
+	voided.Data.Voided = true
+
+	plain := fakePassCard(project)
+
+	for _, passcard := range []*api.PassCardInfo{expiring, voided, plain} {
+		err = db.SaveNewPassCard(ctx, project, passcard)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+	if assert.NotNil(expiring.ExpiresAt) {
+		assert.True(now.Add(time.Hour).Equal(*expiring.ExpiresAt))
+	}
+	assert.Nil(plain.ExpiresAt)
+
+	list, err := db.LoadExpiredPassCards(ctx, now, api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(list.Data, 0)
+
+	list, err = db.LoadExpiredPassCards(ctx, now.Add(2*time.Hour), api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Len(list.Data, 1) {
+		assert.Equal(expiring.ID, list.Data[0].ID)
+	}
+
+	err = db.SetProjectExpiration(ctx, 1, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	list, err = db.LoadExpiredPassCards(ctx, now.Add(48*time.Hour), api.NewPagingOptions(0, 0))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(list.Data, 2)
+
+	loaded, err := db.LoadPassCardProject(ctx, plain)
+	if assert.NoError(err) {
+		assert.Equal(project.ID, loaded.ID)
+		assert.Equal(1, loaded.ExpireAfterDays)
+	}
+}
@@ -0,0 +1,124 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	sqlx "github.com/jmoiron/sqlx"
+)
+
+// savePassCardVersion keeps current state of pass card in history along
+// with author taken from context.
+func (m *MySQL) savePassCardVersion(ctx context.Context, tx *sqlx.Tx, passcard *api.PassCardInfo) error {
+	version := api.NewPassCardVersion(passcard, api.AuthorFromContext(ctx))
+
+	query := m.builder.Insert("pass_card_versions").
+		Columns(
+			"pass_card_id",
+			"version",
+			"raw_data",
+			"user_id",
+			"request_id",
+			"created_at",
+		).
+		Values(
+			version.PassCardID,
+			version.Version,
+			version.Data,
+			version.UserID,
+			version.RequestID,
+			version.CreatedAt,
+		)
+
+	_, err := execTx(ctx, tx, query)
+	return err
+}
+
+// LoadPassCardVersion ...
+func (m *MySQL) LoadPassCardVersion(ctx context.Context, passcard *api.PassCardInfo, version int64) (*api.PassCardVersion, error) {
+	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := m.builder.Select("*").
+		From("pass_card_versions").
+		Where(sq.Eq{
+			"pass_card_id": passcard.ID,
+			"version":      version,
+		})
+
+	row, err := m.selectRowQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var v = &api.PassCardVersion{}
+
+	err = row.StructScan(v)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// LoadPassCardVersions ...
+func (m *MySQL) LoadPassCardVersions(ctx context.Context, passcard *api.PassCardInfo, opts *api.PagingOptions) (*api.PassCardVersions, error) {
+	err := checkPassCard(passcard, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var versions = &api.PassCardVersions{
+		Opts: opts,
+		Data: []*api.PassCardVersion{},
+	}
+
+	query := m.builder.Select("*").
+		From("pass_card_versions").
+		Where(sq.Eq{"pass_card_id": passcard.ID}).
+		OrderBy("version desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"version": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return versions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cnt uint64
+	for rows.Next() {
+		var v = &api.PassCardVersion{}
+
+		err = rows.StructScan(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = v.Version
+		} else {
+			versions.Data = append(versions.Data, v)
+		}
+	}
+
+	return versions, nil
+}
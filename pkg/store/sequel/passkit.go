@@ -7,6 +7,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/danikarik/okpock/pkg/store"
+	sqlx "github.com/jmoiron/sqlx"
 )
 
 // InsertPass ...
@@ -85,28 +86,61 @@ func (m *MySQL) FindRegistrationBySerialNumber(ctx context.Context, serialNumber
 	return cnt > 0, nil
 }
 
-// FindPushToken ...
-func (m *MySQL) FindPushToken(ctx context.Context, serialNumber string) (string, error) {
-	query := m.builder.Select("push_token").From("registrations").
+// FindPushTokens ...
+func (m *MySQL) FindPushTokens(ctx context.Context, serialNumber string) ([]string, error) {
+	var tokens = []string{}
+
+	query := m.builder.Select("d.push_token").From("registrations r").
+		Join("devices d on d.device_id = r.device_id").
 		Where(sq.Eq{
-			"serial_number": serialNumber,
+			"r.serial_number": serialNumber,
 		})
 
-	row, err := m.selectRowQuery(ctx, query)
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return tokens, nil
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var pushToken string
-	err = row.Scan(&pushToken)
-	if err == sql.ErrNoRows {
-		return "", store.ErrNotFound
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// FindDeviceIDs ...
+func (m *MySQL) FindDeviceIDs(ctx context.Context, pushToken string) ([]string, error) {
+	var ids = []string{}
+
+	query := m.builder.Select("device_id").From("devices").
+		Where(sq.Eq{
+			"push_token": pushToken,
+		})
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return ids, nil
 	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
 
-	return pushToken, nil
+	return ids, nil
 }
 
 // FindSerialNumbers ...
@@ -169,16 +203,25 @@ func (m *MySQL) LatestPass(ctx context.Context, serialNumber, authToken, passTyp
 
 // InsertRegistration ...
 func (m *MySQL) InsertRegistration(ctx context.Context, deviceID, pushToken, serialNumber, passTypeID string) error {
-	query := m.builder.Insert("registrations").
-		Columns("device_id", "push_token", "serial_number", "pass_type_id").
-		Values(deviceID, pushToken, serialNumber, passTypeID)
+	return m.withTx(ctx, func(tx *sqlx.Tx) error {
+		query := m.builder.Insert("devices").
+			Columns("device_id", "push_token", "updated_at").
+			Values(deviceID, pushToken, time.Now()).
+			Suffix("ON DUPLICATE KEY UPDATE push_token = VALUES(push_token), updated_at = VALUES(updated_at)")
+
+		_, err := execTx(ctx, tx, query)
+		if err != nil {
+			return err
+		}
 
-	_, err := m.insertQuery(ctx, query)
-	if err != nil {
-		return err
-	}
+		query = m.builder.Insert("registrations").
+			Columns("device_id", "serial_number", "pass_type_id", "updated_at").
+			Values(deviceID, serialNumber, passTypeID, time.Now()).
+			Suffix("ON DUPLICATE KEY UPDATE pass_type_id = VALUES(pass_type_id), updated_at = VALUES(updated_at)")
 
-	return nil
+		_, err = execTx(ctx, tx, query)
+		return err
+	})
 }
 
 // DeleteRegistration ...
@@ -191,6 +234,30 @@ func (m *MySQL) DeleteRegistration(ctx context.Context, deviceID, serialNumber,
 		})
 
 	rows, err := m.deleteQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// DeleteStaleRegistration ...
+func (m *MySQL) DeleteStaleRegistration(ctx context.Context, deviceID, serialNumber, passTypeID string, before time.Time) (bool, error) {
+	query := m.builder.Delete("registrations").
+		Where(sq.Eq{
+			"device_id":     deviceID,
+			"serial_number": serialNumber,
+			"pass_type_id":  passTypeID,
+		}).
+		Where(sq.LtOrEq{"updated_at": before})
+
+	rows, err := m.deleteQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -198,6 +265,25 @@ func (m *MySQL) DeleteRegistration(ctx context.Context, deviceID, serialNumber,
 	return rows > 0, nil
 }
 
+// DeletePass ...
+func (m *MySQL) DeletePass(ctx context.Context, serialNumber string) error {
+	if serialNumber == "" {
+		return store.ErrEmptyQueryParam
+	}
+
+	for _, table := range []string{"registrations", "pushes", "passes"} {
+		query := m.builder.Delete(table).
+			Where(sq.Eq{"serial_number": serialNumber})
+
+		_, err := m.deleteQuery(ctx, query)
+		if err != nil && err != store.ErrZeroRowsAffected {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // InsertLog ...
 func (m *MySQL) InsertLog(ctx context.Context, remoteAddr, requestID, message string) error {
 	query := m.builder.Insert("logs").
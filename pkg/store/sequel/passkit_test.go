@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/danikarik/okpock/pkg/api"
 	"github.com/danikarik/okpock/pkg/store/sequel"
 	_ "github.com/go-sql-driver/mysql"
 	uuid "github.com/satori/go.uuid"
@@ -75,7 +76,7 @@ func TestFindPass(t *testing.T) {
 	ctx := context.Background()
 	assert := assert.New(t)
 
-	testCase := struct {
+	pass := struct {
 		SerialNumber string
 		AuthToken    string
 		PassTypeID   string
@@ -85,6 +86,38 @@ func TestFindPass(t *testing.T) {
 		PassTypeID:   "com.example.pass",
 	}
 
+	testCases := []struct {
+		SerialNumber string
+		AuthToken    string
+		PassTypeID   string
+		Expected     bool
+	}{
+		{
+			SerialNumber: pass.SerialNumber,
+			AuthToken:    pass.AuthToken,
+			PassTypeID:   pass.PassTypeID,
+			Expected:     true,
+		},
+		{
+			SerialNumber: pass.SerialNumber,
+			AuthToken:    "wrong",
+			PassTypeID:   pass.PassTypeID,
+			Expected:     false,
+		},
+		{
+			SerialNumber: pass.SerialNumber,
+			AuthToken:    pass.AuthToken,
+			PassTypeID:   "com.example.another",
+			Expected:     false,
+		},
+		{
+			SerialNumber: "1967bce8-fb9c-4be7-8946-c1a3a7607a88",
+			AuthToken:    pass.AuthToken,
+			PassTypeID:   pass.PassTypeID,
+			Expected:     false,
+		},
+	}
+
 	conn, err := testConnection(ctx, t)
 	if !assert.NoError(err) {
 		return
@@ -93,12 +126,14 @@ func TestFindPass(t *testing.T) {
 
 	db := sequel.New(conn)
 
-	err = db.InsertPass(ctx, testCase.SerialNumber, testCase.AuthToken, testCase.PassTypeID)
+	err = db.InsertPass(ctx, pass.SerialNumber, pass.AuthToken, pass.PassTypeID)
 	assert.NoError(err)
 
-	ok, err := db.FindPass(ctx, testCase.SerialNumber, testCase.AuthToken, testCase.PassTypeID)
-	assert.NoError(err)
-	assert.True(ok)
+	for _, c := range testCases {
+		ok, err := db.FindPass(ctx, c.SerialNumber, c.AuthToken, c.PassTypeID)
+		assert.NoError(err)
+		assert.Equal(c.Expected, ok)
+	}
 }
 
 func TestFindRegistration(t *testing.T) {
@@ -189,11 +224,92 @@ func TestFindRegistrationBySerialNumber(t *testing.T) {
 		assert.Equal(c.Expected, ok)
 
 		if ok {
-			token, err := db.FindPushToken(ctx, c.SerialNumber)
+			tokens, err := db.FindPushTokens(ctx, c.SerialNumber)
 			assert.NoError(err)
-			assert.Equal(c.PushToken, token)
+			assert.Equal([]string{c.PushToken}, tokens)
+		}
+	}
+}
+
+func TestFindPushTokens(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	testCase := struct {
+		SerialNumber string
+		PassTypeID   string
+		Devices      map[string]string
+	}{
+		SerialNumber: uuid.NewV4().String(),
+		PassTypeID:   "com.example.pass",
+		Devices: map[string]string{
+			uuid.NewV4().String(): uuid.NewV4().String(),
+			uuid.NewV4().String(): uuid.NewV4().String(),
+		},
+	}
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	expected := []string{}
+	for deviceID, pushToken := range testCase.Devices {
+		err = db.InsertRegistration(ctx, deviceID, pushToken, testCase.SerialNumber, testCase.PassTypeID)
+		if !assert.NoError(err) {
+			return
 		}
+		expected = append(expected, pushToken)
 	}
+
+	tokens, err := db.FindPushTokens(ctx, testCase.SerialNumber)
+	assert.NoError(err)
+	assert.ElementsMatch(expected, tokens)
+
+	tokens, err = db.FindPushTokens(ctx, uuid.NewV4().String())
+	assert.NoError(err)
+	assert.Empty(tokens)
+}
+
+func TestFindDeviceIDs(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	testCase := struct {
+		DeviceID     string
+		PushToken    string
+		SerialNumber string
+		PassTypeID   string
+	}{
+		DeviceID:     uuid.NewV4().String(),
+		PushToken:    uuid.NewV4().String(),
+		SerialNumber: uuid.NewV4().String(),
+		PassTypeID:   "com.example.pass",
+	}
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	err = db.InsertRegistration(ctx, testCase.DeviceID, testCase.PushToken, testCase.SerialNumber, testCase.PassTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	ids, err := db.FindDeviceIDs(ctx, testCase.PushToken)
+	assert.NoError(err)
+	assert.Equal([]string{testCase.DeviceID}, ids)
+
+	ids, err = db.FindDeviceIDs(ctx, uuid.NewV4().String())
+	assert.NoError(err)
+	assert.Empty(ids)
 }
 
 func TestFindSerialNumbers(t *testing.T) {
@@ -308,7 +424,21 @@ func TestInsertRegistration(t *testing.T) {
 	db := sequel.New(conn)
 
 	err = db.InsertRegistration(ctx, testCase.DeviceID, testCase.PushToken, testCase.SerialNumber, testCase.PassTypeID)
-	assert.NoError(err)
+	if !assert.NoError(err) {
+		return
+	}
+
+	rotated := uuid.NewV4().String()
+	err = db.InsertRegistration(ctx, testCase.DeviceID, rotated, testCase.SerialNumber, testCase.PassTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pushTokens, err := db.FindPushTokens(ctx, testCase.SerialNumber)
+	if assert.NoError(err) {
+		assert.Contains(pushTokens, rotated)
+		assert.NotContains(pushTokens, testCase.PushToken)
+	}
 }
 
 func TestDeleteRegistration(t *testing.T) {
@@ -347,6 +477,116 @@ func TestDeleteRegistration(t *testing.T) {
 	ok, err := db.DeleteRegistration(ctx, testCase.DeviceID, testCase.SerialNumber, testCase.PassTypeID)
 	assert.NoError(err)
 	assert.True(ok)
+
+	ok, err = db.DeleteRegistration(ctx, testCase.DeviceID, testCase.SerialNumber, testCase.PassTypeID)
+	assert.NoError(err)
+	assert.False(ok)
+}
+
+func TestDeleteStaleRegistration(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	testCase := struct {
+		DeviceID     string
+		PushToken    string
+		SerialNumber string
+		PassTypeID   string
+	}{
+		DeviceID:     uuid.NewV4().String(),
+		PushToken:    uuid.NewV4().String(),
+		SerialNumber: "1967bce8-fb9c-4be7-8946-c1a3a7607a88",
+		PassTypeID:   "com.example.pass",
+	}
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	err = db.InsertRegistration(ctx, testCase.DeviceID, testCase.PushToken, testCase.SerialNumber, testCase.PassTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	ok, err := db.DeleteStaleRegistration(ctx, testCase.DeviceID, testCase.SerialNumber, testCase.PassTypeID, time.Now().Add(-time.Hour))
+	assert.NoError(err)
+	assert.False(ok)
+
+	ok, err = db.DeleteStaleRegistration(ctx, testCase.DeviceID, testCase.SerialNumber, testCase.PassTypeID, time.Now().Add(time.Hour))
+	assert.NoError(err)
+	assert.True(ok)
+}
+
+func TestDeletePass(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	testCase := struct {
+		DeviceID     string
+		AuthToken    string
+		PushToken    string
+		SerialNumber string
+		PassTypeID   string
+	}{
+		DeviceID:     uuid.NewV4().String(),
+		AuthToken:    uuid.NewV4().String(),
+		PushToken:    uuid.NewV4().String(),
+		SerialNumber: uuid.NewV4().String(),
+		PassTypeID:   "com.example.pass",
+	}
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	err = db.InsertPass(ctx, testCase.SerialNumber, testCase.AuthToken, testCase.PassTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = db.InsertRegistration(
+		ctx,
+		testCase.DeviceID,
+		testCase.PushToken,
+		testCase.SerialNumber,
+		testCase.PassTypeID,
+	)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = db.InsertPush(ctx, api.NewPush(testCase.SerialNumber, testCase.PassTypeID, testCase.PushToken))
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = db.DeletePass(ctx, testCase.SerialNumber)
+	if !assert.NoError(err) {
+		return
+	}
+
+	ok, err := db.FindPass(ctx, testCase.SerialNumber, testCase.AuthToken, testCase.PassTypeID)
+	assert.NoError(err)
+	assert.False(ok)
+
+	ok, err = db.FindRegistration(ctx, testCase.DeviceID, testCase.SerialNumber)
+	assert.NoError(err)
+	assert.False(ok)
+
+	pushes, err := db.LoadPushes(ctx, testCase.SerialNumber)
+	assert.NoError(err)
+	assert.Len(pushes, 0)
+
+	err = db.DeletePass(ctx, testCase.SerialNumber)
+	assert.NoError(err)
 }
 
 func TestInsertLog(t *testing.T) {
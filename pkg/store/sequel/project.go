@@ -373,3 +373,117 @@ func (m *MySQL) SetStripImage(ctx context.Context, size api.ImageSize, key strin
 
 	return nil
 }
+
+// SetThumbnailImage ...
+func (m *MySQL) SetThumbnailImage(ctx context.Context, size api.ImageSize, key string, project *api.Project) error {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	project.UpdatedAt = time.Now()
+
+	query := m.builder.Update("projects").
+		Set("updated_at", project.UpdatedAt).
+		Where(sq.Eq{"id": project.ID})
+
+	switch size {
+	case api.ImageSize3x:
+		project.ThumbnailImage3x = key
+		query = query.Set("thumbnail_image_3x", project.ThumbnailImage3x)
+	case api.ImageSize2x:
+		project.ThumbnailImage2x = key
+		query = query.Set("thumbnail_image_2x", project.ThumbnailImage2x)
+	default:
+		project.ThumbnailImage = key
+		query = query.Set("thumbnail_image", project.ThumbnailImage)
+	}
+
+	_, err = m.updateQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetProjectLocalizations ...
+func (m *MySQL) SetProjectLocalizations(ctx context.Context, localizations api.Localizations, project *api.Project) error {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	err = localizations.IsValid()
+	if err != nil {
+		return err
+	}
+
+	project.Localizations = localizations
+	project.UpdatedAt = time.Now()
+
+	query := m.builder.Update("projects").
+		Set("localizations", project.Localizations).
+		Set("updated_at", project.UpdatedAt).
+		Where(sq.Eq{"id": project.ID})
+
+	_, err = m.updateQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetProjectTemplate ...
+func (m *MySQL) SetProjectTemplate(ctx context.Context, template *api.Template, project *api.Project) error {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	if template != nil {
+		err = template.IsValid()
+		if err != nil {
+			return err
+		}
+	}
+
+	project.Template = template
+	project.UpdatedAt = time.Now()
+
+	query := m.builder.Update("projects").
+		Set("template", project.Template).
+		Set("updated_at", project.UpdatedAt).
+		Where(sq.Eq{"id": project.ID})
+
+	_, err = m.updateQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetProjectExpiration ...
+func (m *MySQL) SetProjectExpiration(ctx context.Context, expireAfterDays int, project *api.Project) error {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	project.ExpireAfterDays = expireAfterDays
+	project.UpdatedAt = time.Now()
+
+	query := m.builder.Update("projects").
+		Set("expire_after_days", project.ExpireAfterDays).
+		Set("updated_at", project.UpdatedAt).
+		Where(sq.Eq{"id": project.ID})
+
+	_, err = m.updateQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package sequel
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	sqlx "github.com/jmoiron/sqlx"
+)
+
+func (m *MySQL) loadPushes(ctx context.Context, query sq.SelectBuilder) ([]*api.Push, error) {
+	var pushes = []*api.Push{}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return pushes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		push := &api.Push{}
+		err = rows.StructScan(push)
+		if err != nil {
+			return nil, err
+		}
+		pushes = append(pushes, push)
+	}
+
+	return pushes, nil
+}
+
+// InsertPush ...
+func (m *MySQL) InsertPush(ctx context.Context, push *api.Push) error {
+	if push == nil {
+		return store.ErrNilStruct
+	}
+
+	query := m.builder.Insert("pushes").
+		Columns(
+			"serial_number",
+			"pass_type_id",
+			"push_token",
+			"status",
+			"attempts",
+			"last_error",
+			"next_attempt_at",
+			"created_at",
+			"updated_at",
+		).
+		Values(
+			push.SerialNumber,
+			push.PassTypeID,
+			push.PushToken,
+			push.Status,
+			push.Attempts,
+			push.LastError,
+			push.NextAttemptAt,
+			push.CreatedAt,
+			push.UpdatedAt,
+		)
+
+	id, err := m.insertQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	push.ID = id
+
+	return nil
+}
+
+// UpdatePassWithPushes ...
+func (m *MySQL) UpdatePassWithPushes(ctx context.Context, serialNumber, passTypeID string) error {
+	now := time.Now()
+
+	return m.withTx(ctx, func(tx *sqlx.Tx) error {
+		query := m.builder.Update("passes").
+			Set("updated_at", now).
+			Where(sq.Eq{"serial_number": serialNumber})
+
+		res, err := execTx(ctx, tx, query)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return store.ErrZeroRowsAffected
+		}
+
+		tokens := m.builder.Select().
+			Column("?", serialNumber).
+			Column("?", passTypeID).
+			Column("d.push_token").
+			Column("?", api.PushPending).
+			Column("0").
+			Column("''").
+			Column("?", now).
+			Column("?", now).
+			Column("?", now).
+			From("registrations r").
+			Join("devices d on d.device_id = r.device_id").
+			Where(sq.Eq{"r.serial_number": serialNumber})
+
+		insert := m.builder.Insert("pushes").
+			Columns(
+				"serial_number",
+				"pass_type_id",
+				"push_token",
+				"status",
+				"attempts",
+				"last_error",
+				"next_attempt_at",
+				"created_at",
+				"updated_at",
+			).
+			Select(tokens)
+
+		_, err = execTx(ctx, tx, insert)
+		return err
+	})
+}
+
+// LoadPushes ...
+func (m *MySQL) LoadPushes(ctx context.Context, serialNumber string) ([]*api.Push, error) {
+	query := m.builder.Select("*").From("pushes").
+		Where(sq.Eq{"serial_number": serialNumber}).
+		OrderBy("created_at desc", "id desc")
+
+	return m.loadPushes(ctx, query)
+}
+
+// LoadPendingPushes ...
+func (m *MySQL) LoadPendingPushes(ctx context.Context, limit uint64) ([]*api.Push, error) {
+	query := m.builder.Select("*").From("pushes").
+		Where(sq.Eq{"status": []api.PushStatus{api.PushPending, api.PushProcessing}}).
+		Where(sq.LtOrEq{"next_attempt_at": time.Now()}).
+		OrderBy("next_attempt_at", "id").
+		Limit(limit)
+
+	return m.loadPushes(ctx, query)
+}
+
+// ClaimPush ...
+func (m *MySQL) ClaimPush(ctx context.Context, lease time.Duration, push *api.Push) (bool, error) {
+	if push == nil {
+		return false, store.ErrNilStruct
+	}
+
+	now := time.Now()
+	query := m.builder.Update("pushes").
+		Set("status", api.PushProcessing).
+		Set("attempts", push.Attempts+1).
+		Set("next_attempt_at", now.Add(lease)).
+		Set("updated_at", now).
+		Where(sq.Eq{
+			"id":       push.ID,
+			"attempts": push.Attempts,
+			"status":   []api.PushStatus{api.PushPending, api.PushProcessing},
+		})
+
+	_, err := m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	push.Status = api.PushProcessing
+	push.Attempts++
+	push.NextAttemptAt = now.Add(lease)
+	push.UpdatedAt = now
+
+	return true, nil
+}
+
+// UpdatePush ...
+func (m *MySQL) UpdatePush(ctx context.Context, push *api.Push) (bool, error) {
+	if push == nil {
+		return false, store.ErrNilStruct
+	}
+
+	now := time.Now()
+	query := m.builder.Update("pushes").
+		Set("status", push.Status).
+		Set("last_error", push.LastError).
+		Set("next_attempt_at", push.NextAttemptAt).
+		Set("updated_at", now).
+		Where(sq.Eq{
+			"id":       push.ID,
+			"attempts": push.Attempts,
+			"status":   api.PushProcessing,
+		})
+
+	_, err := m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	push.UpdatedAt = now
+
+	return true, nil
+}
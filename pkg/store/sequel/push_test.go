@@ -0,0 +1,166 @@
+package sequel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store/sequel"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushOutbox(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	testCase := struct {
+		SerialNumber string
+		PassTypeID   string
+	}{
+		SerialNumber: uuid.NewV4().String(),
+		PassTypeID:   "com.example.pass",
+	}
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	first := api.NewPush(testCase.SerialNumber, testCase.PassTypeID, uuid.NewV4().String())
+	first.NextAttemptAt = time.Now().Add(-time.Minute)
+	err = db.InsertPush(ctx, first)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(first.ID > 0)
+
+	second := api.NewPush(testCase.SerialNumber, testCase.PassTypeID, uuid.NewV4().String())
+	second.NextAttemptAt = time.Now().Add(time.Hour)
+	err = db.InsertPush(ctx, second)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pending, err := db.LoadPendingPushes(ctx, 10)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var claimed *api.Push
+	for _, push := range pending {
+		assert.NotEqual(second.ID, push.ID)
+		if push.ID == first.ID {
+			claimed = push
+		}
+	}
+	if !assert.NotNil(claimed) {
+		return
+	}
+
+	stale := *claimed
+	ok, err := db.ClaimPush(ctx, time.Minute, claimed)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(api.PushProcessing, claimed.Status)
+	assert.Equal(1, claimed.Attempts)
+
+	ok, err = db.ClaimPush(ctx, time.Minute, &stale)
+	assert.NoError(err)
+	assert.False(ok)
+
+	expired := *claimed
+	ok, err = db.ClaimPush(ctx, time.Minute, claimed)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(2, claimed.Attempts)
+
+	expired.Status = api.PushDead
+	ok, err = db.UpdatePush(ctx, &expired)
+	assert.NoError(err)
+	assert.False(ok)
+
+	claimed.Status = api.PushDelivered
+	ok, err = db.UpdatePush(ctx, claimed)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(ok)
+
+	pushes, err := db.LoadPushes(ctx, testCase.SerialNumber)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(pushes, 2) {
+		return
+	}
+
+	statuses := map[int64]api.PushStatus{}
+	for _, push := range pushes {
+		statuses[push.ID] = push.Status
+	}
+	assert.Equal(api.PushDelivered, statuses[first.ID])
+	assert.Equal(api.PushPending, statuses[second.ID])
+
+	pushes, err = db.LoadPushes(ctx, uuid.NewV4().String())
+	assert.NoError(err)
+	assert.Empty(pushes)
+}
+
+func TestUpdatePassWithPushes(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	testCase := struct {
+		SerialNumber string
+		AuthToken    string
+		PassTypeID   string
+	}{
+		SerialNumber: uuid.NewV4().String(),
+		AuthToken:    uuid.NewV4().String(),
+		PassTypeID:   "com.example.pass",
+	}
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	err = db.InsertPass(ctx, testCase.SerialNumber, testCase.AuthToken, testCase.PassTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	tokens := []string{uuid.NewV4().String(), uuid.NewV4().String()}
+	for _, token := range tokens {
+		err = db.InsertRegistration(ctx, uuid.NewV4().String(), token, testCase.SerialNumber, testCase.PassTypeID)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	err = db.UpdatePassWithPushes(ctx, testCase.SerialNumber, testCase.PassTypeID)
+	if !assert.NoError(err) {
+		return
+	}
+
+	pushes, err := db.LoadPushes(ctx, testCase.SerialNumber)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(pushes, len(tokens)) {
+		return
+	}
+	for _, push := range pushes {
+		assert.Contains(tokens, push.PushToken)
+		assert.Equal(testCase.PassTypeID, push.PassTypeID)
+		assert.Equal(api.PushPending, push.Status)
+	}
+}
@@ -0,0 +1,279 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+)
+
+func checkScheduledUpdate(u *api.ScheduledUpdate, opts byte) error {
+	if (opts & checkNilStruct) != 0 {
+		if u == nil {
+			return store.ErrNilStruct
+		}
+	}
+
+	if (opts & checkZeroID) != 0 {
+		if u.ID == 0 {
+			return store.ErrZeroID
+		}
+	}
+
+	return nil
+}
+
+// SaveNewScheduledUpdate ...
+func (m *MySQL) SaveNewScheduledUpdate(ctx context.Context, project *api.Project, update *api.ScheduledUpdate) error {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return err
+	}
+
+	err = checkScheduledUpdate(update, checkNilStruct)
+	if err != nil {
+		return err
+	}
+
+	update.ProjectID = project.ID
+
+	query := m.builder.Insert("scheduled_updates").
+		Columns(
+			"project_id",
+			"pass_card_id",
+			"filter",
+			"patch",
+			"status",
+			"effective_at",
+			"job_id",
+			"last_error",
+			"claimed_at",
+			"user_id",
+			"request_id",
+			"created_at",
+			"updated_at",
+		).
+		Values(
+			update.ProjectID,
+			update.PassCardID,
+			update.Filter,
+			update.Patch,
+			update.Status,
+			update.EffectiveAt,
+			update.JobID,
+			update.LastError,
+			update.ClaimedAt,
+			update.UserID,
+			update.RequestID,
+			update.CreatedAt,
+			update.UpdatedAt,
+		)
+
+	id, err := m.insertQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	update.ID = id
+
+	return nil
+}
+
+// LoadScheduledUpdate ...
+func (m *MySQL) LoadScheduledUpdate(ctx context.Context, project *api.Project, id int64) (*api.ScheduledUpdate, error) {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == 0 {
+		return nil, store.ErrZeroID
+	}
+
+	query := m.builder.Select("*").
+		From("scheduled_updates").
+		Where(sq.Eq{
+			"id":         id,
+			"project_id": project.ID,
+		})
+
+	row, err := m.selectRowQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var update = &api.ScheduledUpdate{}
+
+	err = row.StructScan(update)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+// LoadScheduledUpdates ...
+func (m *MySQL) LoadScheduledUpdates(ctx context.Context, project *api.Project, opts *api.PagingOptions) (*api.ScheduledUpdates, error) {
+	err := checkProject(project, checkNilStruct|checkZeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = api.NewPagingOptions(0, 0)
+	}
+
+	var updates = &api.ScheduledUpdates{
+		Opts: opts,
+		Data: []*api.ScheduledUpdate{},
+	}
+
+	query := m.builder.Select("*").
+		From("scheduled_updates").
+		Where(sq.Eq{"project_id": project.ID}).
+		OrderBy("id desc").
+		Limit(opts.Limit + 1)
+
+	if opts.Cursor > 0 {
+		query = query.Where(sq.LtOrEq{"id": opts.Cursor})
+	}
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return updates, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cnt uint64
+	for rows.Next() {
+		var update = &api.ScheduledUpdate{}
+
+		err = rows.StructScan(update)
+		if err != nil {
+			return nil, err
+		}
+
+		if cnt++; cnt > opts.Limit {
+			opts.Next = update.ID
+		} else {
+			updates.Data = append(updates.Data, update)
+		}
+	}
+
+	return updates, nil
+}
+
+// LoadDueScheduledUpdates ...
+func (m *MySQL) LoadDueScheduledUpdates(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]*api.ScheduledUpdate, error) {
+	var updates = []*api.ScheduledUpdate{}
+
+	query := m.builder.Select("*").
+		From("scheduled_updates").
+		Where(sq.Or{
+			sq.And{
+				sq.Eq{"status": api.SchedulePending},
+				sq.LtOrEq{"effective_at": now},
+			},
+			sq.And{
+				sq.Eq{"status": api.ScheduleRunning},
+				sq.LtOrEq{"claimed_at": now.Add(-lease)},
+			},
+		}).
+		OrderBy("effective_at", "id").
+		Limit(limit)
+
+	rows, err := m.selectQuery(ctx, query)
+	if err == store.ErrNotFound {
+		return updates, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		update := &api.ScheduledUpdate{}
+		err = rows.StructScan(update)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+
+	return updates, nil
+}
+
+// ClaimScheduledUpdate ...
+func (m *MySQL) ClaimScheduledUpdate(ctx context.Context, update *api.ScheduledUpdate) (bool, error) {
+	err := checkScheduledUpdate(update, checkNilStruct|checkZeroID)
+	if err != nil {
+		return false, err
+	}
+
+	// Claim time is compared on next claim, so it is kept in precision of
+	// column.
+	now := time.Now().Truncate(time.Second)
+	query := m.builder.Update("scheduled_updates").
+		Set("status", api.ScheduleRunning).
+		Set("claimed_at", now).
+		Set("updated_at", now).
+		Where(sq.Eq{
+			"id":         update.ID,
+			"status":     update.Status,
+			"claimed_at": update.ClaimedAt,
+		})
+
+	_, err = m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	update.Status = api.ScheduleRunning
+	update.ClaimedAt = &now
+	update.UpdatedAt = now
+
+	return true, nil
+}
+
+// UpdateScheduledUpdate ...
+func (m *MySQL) UpdateScheduledUpdate(ctx context.Context, update *api.ScheduledUpdate, status api.ScheduleStatus) (bool, error) {
+	err := checkScheduledUpdate(update, checkNilStruct|checkZeroID)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	query := m.builder.Update("scheduled_updates").
+		Set("status", update.Status).
+		Set("effective_at", update.EffectiveAt).
+		Set("job_id", update.JobID).
+		Set("last_error", update.LastError).
+		Set("claimed_at", update.ClaimedAt).
+		Set("updated_at", now).
+		Where(sq.Eq{
+			"id":     update.ID,
+			"status": status,
+		})
+
+	_, err = m.updateQuery(ctx, query)
+	if err == store.ErrZeroRowsAffected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	update.UpdatedAt = now
+
+	return true, nil
+}
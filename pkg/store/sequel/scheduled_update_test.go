@@ -0,0 +1,123 @@
+package sequel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danikarik/okpock/pkg/api"
+	"github.com/danikarik/okpock/pkg/store"
+	"github.com/danikarik/okpock/pkg/store/sequel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledUpdates(t *testing.T) {
+	ctx := context.Background()
+	assert := assert.New(t)
+
+	conn, err := testConnection(ctx, t)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	db := sequel.New(conn)
+
+	user := api.NewUser(fakeUsername(), fakeEmail(), fakeString(), nil)
+	err = db.SaveNewUser(ctx, user)
+	if !assert.NoError(err) {
+		return
+	}
+
+	project := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, project)
+	if !assert.NoError(err) {
+		return
+	}
+
+	other := api.NewProject(fakeString(), fakeString(), fakeString(), api.Coupon)
+	err = db.SaveNewProject(ctx, user, other)
+	if !assert.NoError(err) {
+		return
+	}
+
+	now := time.Now().Truncate(time.Second)
+
+	passcard := fakePassCard(project)
+	err = db.SaveNewPassCard(ctx, project, passcard)
+	if !assert.NoError(err) {
+		return
+	}
+
+	due := api.NewScheduledUpdate(project.ID, now.Add(-time.Minute), api.Author{UserID: user.ID})
+	due.PassCardID = passcard.ID
+	due.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+	err = db.SaveNewScheduledUpdate(ctx, project, due)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(due.ID > 0)
+
+	later := api.NewScheduledUpdate(project.ID, now.Add(time.Hour), api.Author{UserID: user.ID})
+	later.Filter = api.RawJSON(`{"all":true}`)
+	later.Patch = api.RawJSON(`{"data":{"logoText":"Sale"}}`)
+	err = db.SaveNewScheduledUpdate(ctx, project, later)
+	if !assert.NoError(err) {
+		return
+	}
+
+	updates, err := db.LoadDueScheduledUpdates(ctx, now, time.Minute, 10)
+	if assert.NoError(err) && assert.Len(updates, 1) {
+		assert.Equal(due.ID, updates[0].ID)
+	}
+
+	stale := *due
+
+	ok, err := db.ClaimScheduledUpdate(ctx, due)
+	if assert.NoError(err) && assert.True(ok) {
+		assert.Equal(api.ScheduleRunning, due.Status)
+		assert.NotNil(due.ClaimedAt)
+	}
+
+	ok, err = db.ClaimScheduledUpdate(ctx, &stale)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	ok, err = db.UpdateScheduledUpdate(ctx, due, api.SchedulePending)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	updates, err = db.LoadDueScheduledUpdates(ctx, now, time.Minute, 10)
+	if assert.NoError(err) {
+		assert.Len(updates, 0)
+	}
+
+	updates, err = db.LoadDueScheduledUpdates(ctx, now.Add(2*time.Hour), time.Minute, 10)
+	if assert.NoError(err) && assert.Len(updates, 2) {
+		assert.Equal(due.ID, updates[0].ID)
+		assert.Equal(later.ID, updates[1].ID)
+
+		ok, err = db.ClaimScheduledUpdate(ctx, updates[0])
+		if assert.NoError(err) {
+			assert.True(ok)
+		}
+	}
+
+	loaded, err := db.LoadScheduledUpdate(ctx, project, due.ID)
+	if assert.NoError(err) {
+		assert.Equal(api.ScheduleRunning, loaded.Status)
+		assert.Equal(due.PassCardID, loaded.PassCardID)
+		assert.JSONEq(string(due.Patch), string(loaded.Patch))
+	}
+
+	_, err = db.LoadScheduledUpdate(ctx, other, due.ID)
+	assert.Equal(store.ErrNotFound, err)
+
+	list, err := db.LoadScheduledUpdates(ctx, project, api.NewPagingOptions(0, 0))
+	if assert.NoError(err) && assert.Len(list.Data, 2) {
+		assert.Equal(later.ID, list.Data[0].ID)
+		assert.Equal(due.ID, list.Data[1].ID)
+	}
+}